@@ -0,0 +1,137 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"sort"
+	"strings"
+)
+
+// SessionAdmin is implemented by tokens that can enumerate active sessions for admin consoles | 支持为管理后台枚举有效会话的 Token 实现该接口
+// Sessions are kicked with Destroy or DestroySession | 使用 Destroy 或 DestroySession 踢出会话
+type SessionAdmin interface {
+	SearchSessions(ctx context.Context, query SessionQuery) (*SessionPage, error) // Page of sessions matching query | 符合查询条件的会话分页
+	CountSessions(ctx context.Context, query SessionQuery) (int, error)           // Number of sessions matching query, ignoring paging | 符合查询条件的会话数，忽略分页参数
+}
+
+// SessionQuery filters and pages sessions, zero values match everything | 会话的过滤与分页条件，零值表示不限制
+type SessionQuery struct {
+	UserKey       string              // Sessions of this user only, read from its index without scanning | 仅查询该用户的会话，直接读取索引无需扫描
+	UserKeyPrefix string              // Users whose key starts with it | 用户标识以其开头的用户
+	CreatedAfter  int64               // Sessions created at or after this time (ms) | 在该时间及之后创建的会话（毫秒）
+	CreatedBefore int64               // Sessions created before this time (ms) | 在该时间之前创建的会话（毫秒）
+	Filter        func(*Session) bool // Custom filter, e.g. on DeviceId or Roles | 自定义过滤，如按设备标识或角色过滤
+	Offset        int                 // Matching sessions skipped | 跳过的匹配会话数
+	Limit         int                 // Page size (<= 0 uses DefaultSessionPageSize, capped at MaxSessionPageSize) | 分页大小（<= 0 时使用 DefaultSessionPageSize，最大为 MaxSessionPageSize）
+}
+
+// SessionPage is one page of SearchSessions | SearchSessions 返回的一页会话
+type SessionPage struct {
+	Sessions []*Session `json:"sessions"` // Sessions ordered by creation time, Token is left empty | 按创建时间排序的会话，Token 字段为空
+	Total    int        `json:"total"`    // Number of matching sessions | 匹配的会话总数
+	Offset   int        `json:"offset"`   // Offset of the page | 本页偏移量
+	Limit    int        `json:"limit"`    // Applied page size | 实际使用的分页大小
+}
+
+// match reports whether session passes the filters of query | 判断会话是否满足查询的过滤条件
+func (q SessionQuery) match(session *Session) bool {
+	if q.CreatedAfter > 0 && session.CreateTime < q.CreatedAfter {
+		return false
+	}
+	if q.CreatedBefore > 0 && session.CreateTime >= q.CreatedBefore {
+		return false
+	}
+	return q.Filter == nil || q.Filter(session)
+}
+
+// SearchSessions implements SessionAdmin | 实现 SessionAdmin 接口
+// Without UserKey every user index is scanned, which needs a Cache implementing Scanner; tenant instances only see their own namespace
+// 未指定 UserKey 时会扫描所有用户索引，要求 Cache 实现 Scanner；租户实例仅能看到自身命名空间
+func (m *GTokenV2) SearchSessions(ctx context.Context, query SessionQuery) (*SessionPage, error) {
+	var sessions []*Session
+	if err := m.scanSessions(ctx, query, func(session *Session) {
+		session.Token = "" // Admin pages never carry credentials | 管理分页不携带凭证
+		sessions = append(sessions, session)
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if a.CreateTime != b.CreateTime {
+			return a.CreateTime < b.CreateTime
+		}
+		if a.UserKey != b.UserKey {
+			return a.UserKey < b.UserKey
+		}
+		return a.DeviceId < b.DeviceId
+	})
+
+	page := &SessionPage{Total: len(sessions), Offset: max(query.Offset, 0), Limit: query.Limit}
+	if page.Limit <= 0 {
+		page.Limit = DefaultSessionPageSize
+	}
+	page.Limit = min(page.Limit, MaxSessionPageSize)
+	start := min(page.Offset, len(sessions))
+	page.Sessions = sessions[start:min(start+page.Limit, len(sessions))]
+	return page, nil
+}
+
+// CountSessions implements SessionAdmin | 实现 SessionAdmin 接口
+func (m *GTokenV2) CountSessions(ctx context.Context, query SessionQuery) (int, error) {
+	count := 0
+	err := m.scanSessions(ctx, query, func(*Session) { count++ })
+	return count, err
+}
+
+// scanSessions calls fn with every live session matching query | 对每个符合查询条件的有效会话调用 fn
+func (m *GTokenV2) scanSessions(ctx context.Context, query SessionQuery, fn func(*Session)) error {
+	visit := func(userKey string) error {
+		sessions, err := m.ListSessions(ctx, userKey)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			if query.match(session) {
+				fn(session)
+			}
+		}
+		return nil
+	}
+	if query.UserKey != "" {
+		if !strings.HasPrefix(query.UserKey, query.UserKeyPrefix) {
+			return nil
+		}
+		return visit(query.UserKey)
+	}
+
+	scanner, ok := m.Cache.(Scanner)
+	if !ok {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrScanOff)
+	}
+	var (
+		visitErr error
+		seen     = make(map[string]struct{})
+	)
+	err := scanner.Scan(ctx, SessionIndexPreKey+query.UserKeyPrefix, func(cacheKey string) bool {
+		userKey := strings.TrimPrefix(cacheKey, SessionIndexPreKey)
+		if _, ok := seen[userKey]; ok || userKey == "" {
+			return true // Redis SCAN may repeat keys | Redis SCAN 可能重复返回 key
+		}
+		seen[userKey] = struct{}{}
+		if visitErr = visit(userKey); visitErr != nil {
+			return false
+		}
+		return ctx.Err() == nil
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil && gerror.Code(err) == gcode.CodeNil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSearchSessions(t *testing.T) {
+	ctx := context.Background()
+	var now int64 = 1000
+	token := newTestToken(t, Options{MultiLogin: true})
+	token.Clock = ClockFunc(func() time.Time { return time.UnixMilli(atomic.LoadInt64(&now)) })
+	token.useClock()
+
+	for _, login := range []struct{ userKey, deviceId string }{
+		{"alice", "web"}, {"alice", "app"}, {"bob", "web"}, {"admin:root", ""},
+	} {
+		atomic.AddInt64(&now, 1000)
+		if _, err := token.GenerateWithDevice(ctx, login.userKey, login.deviceId, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := token.SearchSessions(ctx, SessionQuery{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || len(page.Sessions) != 3 || page.Sessions[0].UserKey != "alice" || page.Sessions[0].DeviceId != "web" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	first := page.Sessions[0]
+	if first.Token != "" || first.CreateTime != 2000 || first.ExpireTime != 2000+token.Options.Timeout {
+		t.Fatalf("unexpected session metadata %+v", first)
+	}
+	if page, _ = token.SearchSessions(ctx, SessionQuery{Offset: 3, Limit: 3}); len(page.Sessions) != 1 || page.Sessions[0].UserKey != "admin:root" {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+
+	cases := []struct {
+		name  string
+		query SessionQuery
+		count int
+	}{
+		{"all", SessionQuery{}, 4},
+		{"user", SessionQuery{UserKey: "alice"}, 2},
+		{"prefix", SessionQuery{UserKeyPrefix: "a"}, 3},
+		{"user outside prefix", SessionQuery{UserKey: "bob", UserKeyPrefix: "a"}, 0},
+		{"created range", SessionQuery{CreatedAfter: 3000, CreatedBefore: 5000}, 2},
+		{"filter", SessionQuery{Filter: func(s *Session) bool { return s.DeviceId == "web" }}, 2},
+		{"missing user", SessionQuery{UserKey: "carol"}, 0},
+	}
+	for _, c := range cases {
+		if count, err := token.CountSessions(ctx, c.query); err != nil || count != c.count {
+			t.Fatalf("%s: expected %d, got %d %v", c.name, c.count, count, err)
+		}
+	}
+
+	// Kicked and expired sessions disappear | 被踢出与已过期的会话不再出现
+	if err = token.Destroy(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := token.CountSessions(ctx, SessionQuery{}); count != 2 {
+		t.Fatalf("expected 2 sessions after kick, got %d", count)
+	}
+	atomic.AddInt64(&now, token.Options.Timeout)
+	if count, _ := token.CountSessions(ctx, SessionQuery{}); count != 0 {
+		t.Fatalf("expected no sessions after expiry, got %d", count)
+	}
+}
+
+func TestSearchSessions_Tenant(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	acme, err := token.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = acme.Generate(ctx, "bob", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Generate(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each namespace only sees its own sessions | 各命名空间仅能看到自身的会话
+	page, err := acme.(SessionAdmin).SearchSessions(ctx, SessionQuery{})
+	if err != nil || page.Total != 1 || page.Sessions[0].UserKey != "bob" || page.Sessions[0].TenantId != "acme" {
+		t.Fatalf("unexpected acme sessions %+v %v", page, err)
+	}
+	if page, err = token.SearchSessions(ctx, SessionQuery{}); err != nil || page.Total != 1 || page.Sessions[0].UserKey != "alice" {
+		t.Fatalf("unexpected default sessions %+v %v", page, err)
+	}
+
+	// Scanning needs a Scanner cache | 扫描需要实现 Scanner 的缓存
+	token.Cache = struct{ Cache }{token.Cache}
+	if _, err = token.SearchSessions(ctx, SessionQuery{}); gerror.Code(err) != gcode.CodeNotSupported {
+		t.Fatalf("expected CodeNotSupported, got %v", err)
+	}
+	if count, err := token.CountSessions(ctx, SessionQuery{UserKey: "alice"}); err != nil || count != 1 {
+		t.Fatalf("UserKey query should not scan: %d %v", count, err)
+	}
+}
+
+func TestSearchSessions_Telemetry(t *testing.T) {
+	ctx := context.Background()
+	token, err := New(WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":", Telemetry: true}), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+	if _, err = token.Generate(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Traced caches keep Scanner of the decorated cache | 装饰后的缓存保留被装饰缓存的 Scanner
+	page, err := token.(SessionAdmin).SearchSessions(ctx, SessionQuery{})
+	if err != nil || page.Total != 1 || page.Sessions[0].UserKey != "alice" {
+		t.Fatalf("unexpected sessions %+v %v", page, err)
+	}
+	if count, err := token.(SessionAdmin).CountSessions(ctx, SessionQuery{UserKeyPrefix: "a"}); err != nil || count != 1 {
+		t.Fatalf("unexpected count %d %v", count, err)
+	}
+}
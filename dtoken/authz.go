@@ -0,0 +1,264 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+	"strings"
+	"sync"
+)
+
+// Grants holds roles and permissions of a session | 会话拥有的角色与权限
+type Grants struct {
+	Roles       []string `json:"roles"`       // Role names | 角色名称
+	Permissions []string `json:"permissions"` // Permissions, "order:*" and "*" are wildcards | 权限，"order:*" 与 "*" 为通配权限
+}
+
+// AuthRule declares what a route requires | 声明路由所需的权限
+type AuthRule struct {
+	Path        string   `json:"path"`        // Path pattern, see PathRules for the syntax, a method list narrows Method | 路径模式，语法见 PathRules，其中的方法列表会进一步限定 Method
+	Method      string   `json:"method"`      // HTTP method ("" for all) | HTTP 方法（空表示全部）
+	Permissions []string `json:"permissions"` // All of them are required | 需要全部拥有
+	Roles       []string `json:"roles"`       // Any of them is required | 拥有其一即可
+}
+
+// PermissionProvider resolves grants of a user, e.g. from database | 解析用户的角色与权限，例如从数据库加载
+type PermissionProvider interface {
+	Grants(ctx context.Context, userKey string) (*Grants, error)
+}
+
+// PermissionProviderFunc adapts a function to PermissionProvider | 将函数适配为 PermissionProvider
+type PermissionProviderFunc func(ctx context.Context, userKey string) (*Grants, error)
+
+// Grants implements PermissionProvider | 实现 PermissionProvider 接口
+func (f PermissionProviderFunc) Grants(ctx context.Context, userKey string) (*Grants, error) {
+	return f(ctx, userKey)
+}
+
+// Authorizer checks session grants against route rules, safe for concurrent use | 根据路由规则校验会话权限，并发安全
+type Authorizer struct {
+	mu              sync.RWMutex
+	rules           []AuthRule
+	paths           []*pathRuleSet
+	rolePermissions map[string][]string
+	provider        PermissionProvider
+}
+
+// NewAuthorizer creates a new Authorizer instance, failing on invalid rule paths | 创建一个新的 Authorizer 实例，规则路径非法时返回错误
+func NewAuthorizer(rules ...AuthRule) (*Authorizer, error) {
+	a := &Authorizer{rolePermissions: make(map[string][]string)}
+	if err := a.AddRule(rules...); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewAuthorizerByOptions creates an Authorizer from AuthRules and RolePermissions | 根据 AuthRules 与 RolePermissions 创建 Authorizer
+func NewAuthorizerByOptions(options Options) (*Authorizer, error) {
+	a, err := NewAuthorizer(options.AuthRules...)
+	if err != nil {
+		return nil, err
+	}
+	for role, permissions := range options.RolePermissions {
+		a.SetRolePermissions(role, permissions...)
+	}
+	return a, nil
+}
+
+// mustAuthorizerByOptions creates an Authorizer, panicking on invalid config | 创建 Authorizer，配置错误时 panic
+func mustAuthorizerByOptions(options Options) *Authorizer {
+	a, err := NewAuthorizerByOptions(options)
+	if err != nil {
+		panic("invalid config: " + err.Error() + " | AuthRules 配置错误")
+	}
+	return a
+}
+
+// AddRule appends route rules, adding none if any path is invalid | 追加路由规则，任一路径非法时不追加任何规则
+func (a *Authorizer) AddRule(rules ...AuthRule) error {
+	paths := make([]*pathRuleSet, len(rules))
+	for i, rule := range rules {
+		set, err := newPathRuleSet([]string{rule.Path})
+		if err != nil {
+			return err
+		}
+		paths[i] = set
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, rule := range rules {
+		rule.Method = strings.ToUpper(rule.Method)
+		a.rules = append(a.rules, rule)
+		a.paths = append(a.paths, paths[i])
+	}
+	return nil
+}
+
+// SetRolePermissions sets the permissions granted by a role | 设置角色拥有的权限
+func (a *Authorizer) SetRolePermissions(role string, permissions ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rolePermissions[role] = append([]string(nil), permissions...)
+}
+
+// SetProvider sets the provider consulted in addition to session grants | 设置在会话权限之外额外查询的权限提供者
+func (a *Authorizer) SetProvider(provider PermissionProvider) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.provider = provider
+}
+
+// Match returns the rules matching method and path | 返回匹配方法与路径的规则
+func (a *Authorizer) Match(method, path string) []AuthRule {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var matched []AuthRule
+	segments := splitPath(path)
+	for i, rule := range a.rules {
+		if (rule.Method == "" || rule.Method == method) && a.paths[i].match(method, path, segments) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// Resolve merges session grants, provider grants and role permissions | 合并会话权限、提供者权限与角色权限
+func (a *Authorizer) Resolve(ctx context.Context, session *Session) (*Grants, error) {
+	a.mu.RLock()
+	provider := a.provider
+	a.mu.RUnlock()
+
+	grants := &Grants{
+		Roles:       append([]string(nil), session.Roles...),
+		Permissions: append([]string(nil), session.Permissions...),
+	}
+	if provider != nil {
+		provided, err := provider.Grants(ctx, session.UserKey)
+		if err != nil {
+			return nil, err
+		}
+		if provided != nil {
+			grants.Roles = append(grants.Roles, provided.Roles...)
+			grants.Permissions = append(grants.Permissions, provided.Permissions...)
+		}
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, role := range grants.Roles {
+		grants.Permissions = append(grants.Permissions, a.rolePermissions[role]...)
+	}
+	return grants, nil
+}
+
+// Authorize checks session against the rules of the route | 根据路由规则校验会话权限
+// Routes without rules only require authentication | 未配置规则的路由只需通过认证
+func (a *Authorizer) Authorize(ctx context.Context, session *Session, method, path string) (*Grants, error) {
+	grants, err := a.Resolve(ctx, session)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	for _, rule := range a.Match(strings.ToUpper(method), path) {
+		if len(rule.Roles) > 0 && !hasAnyRole(grants.Roles, rule.Roles) {
+			return grants, gerror.NewCode(CodeForbidden, MsgErrForbidden)
+		}
+		for _, permission := range rule.Permissions {
+			if !HasPermission(grants.Permissions, permission) {
+				return grants, gerror.NewCode(CodeForbidden, MsgErrForbidden)
+			}
+		}
+	}
+	return grants, nil
+}
+
+// HasPermission reports whether granted permissions cover required | 判断已有权限是否覆盖所需权限
+// "order:*" covers "order:read" and "order:item:read", "*" covers everything | "order:*" 覆盖 "order:read" 与 "order:item:read"，"*" 覆盖全部
+func HasPermission(granted []string, required string) bool {
+	for _, permission := range granted {
+		if permission == required || permission == PermissionWildcard {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(permission, PermissionDelimiter+PermissionWildcard); ok &&
+			strings.HasPrefix(required, prefix+PermissionDelimiter) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyRole reports whether any required role is granted | 判断是否拥有任一所需角色
+func hasAnyRole(granted []string, required []string) bool {
+	for _, role := range required {
+		if gstr.InArray(granted, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateWithGrants creates a device session carrying roles and permissions | 生成携带角色与权限的设备会话
+func (m *GTokenV2) GenerateWithGrants(ctx context.Context, userKey, deviceId string, data any, grants *Grants) (token string, err error) {
+	ctx, span := m.Telemetry.start(ctx, SpanGenerate)
+	defer func() { m.Telemetry.end(span, err) }()
+	return m.generate(ctx, userKey, deviceId, data, grants, m.options().MultiLogin)
+}
+
+// SetGrants replaces roles and permissions of a live session and its refresh family | 替换有效会话及其刷新令牌族的角色与权限
+func (m *GTokenV2) SetGrants(ctx context.Context, userKey, deviceId string, grants *Grants) error {
+	if err := checkSessionKey(userKey, deviceId); err != nil {
+		return err
+	}
+	cacheKey := sessionKey(userKey, deviceId)
+	userCache, err := m.Cache.Get(ctx, cacheKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if userCache == nil {
+		return gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
+	}
+	setGrants(userCache, grants)
+	if err = m.Cache.Set(ctx, cacheKey, userCache); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	// Keep grants across refresh | 刷新后保留权限
+	if m.RefreshCache == nil {
+		return nil
+	}
+	refreshCache, err := m.RefreshCache.Get(ctx, cacheKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if refreshCache == nil {
+		return nil
+	}
+	setGrants(refreshCache, grants)
+	if err = m.RefreshCache.Set(ctx, cacheKey, refreshCache); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return nil
+}
+
+// setGrants writes grants into a cache map, nil removes them | 将权限写入缓存 map，为 nil 时移除
+func setGrants(cacheValue g.Map, grants *Grants) {
+	if grants == nil {
+		delete(cacheValue, KeyRoles)
+		delete(cacheValue, KeyPermissions)
+		return
+	}
+	cacheValue[KeyRoles] = g.SliceStr(grants.Roles)
+	cacheValue[KeyPermissions] = g.SliceStr(grants.Permissions)
+}
+
+// grantsFromCache reads grants from a cache map, nil when absent | 从缓存 map 读取权限，不存在时返回 nil
+func grantsFromCache(cacheValue g.Map) *Grants {
+	roles, hasRoles := cacheValue[KeyRoles]
+	permissions, hasPermissions := cacheValue[KeyPermissions]
+	if !hasRoles && !hasPermissions {
+		return nil
+	}
+	return &Grants{Roles: gconv.Strings(roles), Permissions: gconv.Strings(permissions)}
+}
@@ -0,0 +1,203 @@
+package dtoken
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthz_HasPermission(t *testing.T) {
+	cases := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{"order:read"}, "order:read", true},
+		{[]string{"order:read"}, "order:write", false},
+		{[]string{"order:*"}, "order:write", true},
+		{[]string{"order:*"}, "order:item:read", true},
+		{[]string{"order:*"}, "orders:read", false},
+		{[]string{"order:*"}, "order", false},
+		{[]string{"*"}, "user:delete", true},
+		{nil, "order:read", false},
+	}
+	for _, c := range cases {
+		if got := HasPermission(c.granted, c.required); got != c.want {
+			t.Fatalf("HasPermission(%v, %q) = %t, want %t", c.granted, c.required, got, c.want)
+		}
+	}
+}
+
+func TestAuthz_Authorize(t *testing.T) {
+	ctx := context.Background()
+	authorizer, err := NewAuthorizer(
+		AuthRule{Path: "/order/*", Method: "post", Permissions: []string{"order:write"}},
+		AuthRule{Path: "/admin/*", Roles: []string{"admin", "root"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizer.SetRolePermissions("editor", "order:*")
+
+	session := &Session{UserKey: "alice", Roles: []string{"viewer"}, Permissions: []string{"order:read"}}
+	if _, err := authorizer.Authorize(ctx, session, "GET", "/order/1"); err != nil {
+		t.Fatalf("expected GET allowed: %v", err)
+	}
+	if _, err := authorizer.Authorize(ctx, session, "POST", "/order/1"); err == nil {
+		t.Fatal("expected POST forbidden")
+	}
+	if _, err := authorizer.Authorize(ctx, session, "GET", "/admin/users"); err == nil {
+		t.Fatal("expected admin forbidden")
+	}
+
+	// Roles resolved by provider expand to permissions
+	authorizer.SetProvider(PermissionProviderFunc(func(ctx context.Context, userKey string) (*Grants, error) {
+		return &Grants{Roles: []string{"editor", "admin"}}, nil
+	}))
+	grants, err := authorizer.Authorize(ctx, session, "POST", "/order/1")
+	if err != nil {
+		t.Fatalf("expected provider roles to allow: %v", err)
+	}
+	if !HasPermission(grants.Permissions, "order:delete") {
+		t.Fatalf("expected role permissions in grants: %v", grants.Permissions)
+	}
+	if _, err = authorizer.Authorize(ctx, session, "GET", "/admin/users"); err != nil {
+		t.Fatalf("expected admin allowed: %v", err)
+	}
+}
+
+func TestAuthz_InvalidRule(t *testing.T) {
+	rules := []AuthRule{
+		{Path: "/admin/*", Roles: []string{"admin"}},
+		{Path: "~/admin/(", Roles: []string{"admin"}},
+	}
+	if _, err := NewAuthorizer(rules...); !gerror.HasCode(err, gcode.CodeInvalidConfiguration) {
+		t.Fatalf("expected invalid rule rejected, got %v", err)
+	}
+	if _, err := NewAuthorizerByOptions(Options{AuthRules: rules}); err == nil {
+		t.Fatal("expected invalid options rejected")
+	}
+
+	// A failed AddRule adds none of the rules
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = authorizer.AddRule(rules...); err == nil {
+		t.Fatal("expected AddRule to fail")
+	}
+	if matched := authorizer.Match("GET", "/admin/users"); len(matched) != 0 {
+		t.Fatalf("expected no rules added, got %v", matched)
+	}
+}
+
+func TestAuthz_MethodPrefixedRule(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{AuthRules: []AuthRule{{Path: "POST,PUT /orders/*", Roles: []string{"admin"}}}})
+	handler := NewHTTPMiddleware(token).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The method list of the path applies like AuthRule.Method
+	for _, c := range []struct {
+		method string
+		status int
+	}{
+		{http.MethodPost, http.StatusForbidden},
+		{http.MethodPut, http.StatusForbidden},
+		{http.MethodGet, http.StatusOK},
+	} {
+		req := httptest.NewRequest(c.method, "/orders/1", nil)
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.method, c.status, rec.Code)
+		}
+	}
+}
+
+func TestAuthz_SessionGrants(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	pair, err := token.GeneratePair(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = token.SetGrants(ctx, "alice", "web", &Grants{Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := token.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := token.ValidateSession(ctx, rotated.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Roles) != 1 || session.Roles[0] != "admin" || session.Data != "w" {
+		t.Fatalf("expected grants kept across refresh: %+v", session)
+	}
+}
+
+func TestAuthz_Middleware(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{
+		AuthRules:       []AuthRule{{Path: "/admin/*", Permissions: []string{"admin:read"}}},
+		RolePermissions: map[string][]string{"admin": {"admin:*"}},
+	})
+	middleware := NewDefaultMiddleware(token)
+
+	s := g.Server(t.Name())
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(middleware.Auth)
+		group.ALL("/admin/stats", func(r *ghttp.Request) {
+			r.Response.Write("ok")
+		})
+	})
+	s.SetDumpRouterMap(false)
+	s.SetPort(0)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Shutdown() }()
+
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := token.GenerateWithGrants(ctx, "bob", "", nil, &Grants{Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		token  string
+		status int
+	}{
+		{userToken, http.StatusForbidden},
+		{adminToken, http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/admin/stats", s.GetListenedPort()), nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("expected status %d, got %d", c.status, resp.StatusCode)
+		}
+	}
+}
@@ -3,13 +3,18 @@ package dtoken
 import (
 	"context"
 	"errors"
+	"github.com/gogf/gf/v2/database/gredis"
 	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gcache"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/gfile"
 	"github.com/gogf/gf/v2/text/gstr"
 	"github.com/gogf/gf/v2/util/gconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,16 +28,63 @@ type Cache interface {
 	Remove(ctx context.Context, cacheKey string) error
 }
 
+// Renewer is implemented by caches that renew a session atomically | 支持原子续期的缓存实现该接口
+type Renewer interface {
+	// Renew increments refreshNum, sets keyLastRenewTime and extends TTL if token still matches | Token 仍一致时递增续期次数、更新续期时间并延长有效期
+	Renew(ctx context.Context, cacheKey string, token string, renewTime int64) (renewed bool, err error)
+}
+
+// Swapper is implemented by caches that replace an entry atomically | 支持原子替换缓存项的缓存实现该接口
+type Swapper interface {
+	// Swap overwrites the entry with cacheValue only if its keyToken still equals token | 仅当缓存项的 Token 仍一致时以 cacheValue 覆盖写入
+	Swap(ctx context.Context, cacheKey string, token string, cacheValue g.Map) (swapped bool, err error)
+}
+
+// TimeoutSetter is implemented by caches whose entry lifetime can change at runtime | 可在运行时调整条目有效期的缓存实现该接口
+type TimeoutSetter interface {
+	// SetTimeout sets the lifetime (ms) of entries written afterwards | 设置此后写入条目的有效期（毫秒）
+	SetTimeout(timeout int64)
+}
+
+// Scanner is implemented by caches that can iterate their keys | 支持遍历缓存 key 的缓存实现该接口
+type Scanner interface {
+	// Scan calls fn with every key (without PreKey) starting with prefix until fn returns false, in no particular order
+	// Keys may expire before fn reads them and Redis may report a key more than once
+	// 按任意顺序对每个以 prefix 开头的 key（不含 PreKey）调用 fn，fn 返回 false 时停止；key 可能在读取前过期，Redis 可能重复返回同一 key
+	Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error
+}
+
 // DefaultCache implements the default cache | 默认缓存实现
 type DefaultCache struct {
 	Cache   *gcache.Cache // Cache instance | 缓存实例
 	Mode    int8          // Cache mode: 1 for gcache, 2 for gredis, 3 for gfile | 缓存模式：1为gcache，2为gredis，3为gfile
 	PreKey  string        // Cache key prefix | 缓存key前缀
 	Timeout int64         // Timeout in milliseconds | 超时时间，单位毫秒
+	Clock   Clock         // Time source of expiry in gcache mode (nil uses gcache timers) | gcache 模式下判断过期的时间来源（为 nil 时使用 gcache 定时器）
+	Redis   *gredis.Redis // Redis client in gredis mode, required by Scan | gredis 模式下的 Redis 客户端，Scan 依赖该字段
+}
+
+// clockEntry is a gcache value expiring by Clock | 按 Clock 判断过期的 gcache 缓存值
+type clockEntry struct {
+	value    string // JSON encoded cache value | JSON 编码的缓存值
+	expireAt int64  // Expire time (ms) of Clock | 按 Clock 计算的过期时间（毫秒）
+}
+
+// NewCacheByOptions creates the cache selected by options.CacheMode | 根据 CacheMode 创建缓存实例
+func NewCacheByOptions(options Options, preKey string, timeout int64) (Cache, error) {
+	switch options.CacheMode {
+	case CacheModeRedisHash:
+		return NewRedisCache(options.RedisGroup, preKey, timeout), nil
+	case CacheModeFile:
+		return NewFileCache(options.CacheFileDir, preKey, timeout, options.CacheFileSync)
+	default:
+		return NewDefaultCache(options.CacheMode, preKey, timeout, options.RedisGroup), nil
+	}
 }
 
 // NewDefaultCache creates a new DefaultCache instance | 创建新的默认缓存实例
-func NewDefaultCache(mode int8, preKey string, timeout int64) *DefaultCache {
+// redisGroup optionally selects the redis group in redis mode | redisGroup 可选，用于在 Redis 模式下指定分组
+func NewDefaultCache(mode int8, preKey string, timeout int64, redisGroup ...string) *DefaultCache {
 	c := &DefaultCache{
 		Cache:   gcache.New(),
 		Mode:    mode,
@@ -44,12 +96,27 @@ func NewDefaultCache(mode int8, preKey string, timeout int64) *DefaultCache {
 	if c.Mode == CacheModeFile {
 		c.initFileCache(gctx.New()) // Initialize file cache | 初始化文件缓存
 	} else if c.Mode == CacheModeRedis {
-		c.Cache.SetAdapter(gcache.NewAdapterRedis(g.Redis())) // Initialize Redis cache | 初始化 Redis 缓存
+		redis := g.Redis()
+		if len(redisGroup) > 0 && redisGroup[0] != "" {
+			redis = g.Redis(redisGroup[0])
+		}
+		c.Cache.SetAdapter(gcache.NewAdapterRedis(redis)) // Initialize Redis cache | 初始化 Redis 缓存
+		c.Redis = redis
 	}
 
 	return c
 }
 
+// SetTimeout implements TimeoutSetter | 实现 TimeoutSetter 接口
+func (c *DefaultCache) SetTimeout(timeout int64) {
+	atomic.StoreInt64(&c.Timeout, timeout)
+}
+
+// timeout returns the current entry lifetime (ms) | 返回当前条目有效期（毫秒）
+func (c *DefaultCache) timeout() int64 {
+	return atomic.LoadInt64(&c.Timeout)
+}
+
 // Set sets a cache value | 设置缓存值
 func (c *DefaultCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
 	if cacheValue == nil {
@@ -59,7 +126,12 @@ func (c *DefaultCache) Set(ctx context.Context, cacheKey string, cacheValue g.Ma
 	if err != nil {
 		return err
 	}
-	err = c.Cache.Set(ctx, c.PreKey+cacheKey, string(value), gconv.Duration(c.Timeout)*time.Millisecond) // Set cache with timeout | 设置缓存并设置超时
+	if c.usesClock() {
+		// gcache timer only bounds memory, Get checks expiry against Clock | gcache 定时器仅用于回收内存，Get 按 Clock 判断过期
+		entry := &clockEntry{value: string(value), expireAt: c.Clock.Now().UnixMilli() + c.timeout()}
+		return c.Cache.Set(ctx, c.PreKey+cacheKey, entry, gconv.Duration(c.timeout())*time.Millisecond)
+	}
+	err = c.Cache.Set(ctx, c.PreKey+cacheKey, string(value), gconv.Duration(c.timeout())*time.Millisecond) // Set cache with timeout | 设置缓存并设置超时
 	if err != nil {
 		return err
 	}
@@ -78,6 +150,13 @@ func (c *DefaultCache) Get(ctx context.Context, cacheKey string) (g.Map, error)
 	if dataVar.IsNil() {
 		return nil, nil // Return nil if cache value is empty | 如果缓存值为空，则返回 nil
 	}
+	if entry, ok := dataVar.Val().(*clockEntry); ok {
+		if entry.expireAt <= c.Clock.Now().UnixMilli() {
+			_, err = c.Cache.Remove(ctx, c.PreKey+cacheKey) // Expired by Clock | 按 Clock 已过期
+			return nil, err
+		}
+		return gconv.Map(entry.value), nil
+	}
 	return dataVar.Map(), nil
 }
 
@@ -90,6 +169,35 @@ func (c *DefaultCache) Remove(ctx context.Context, cacheKey string) error {
 	return err
 }
 
+// Scan implements Scanner, using SCAN in gredis mode | 实现 Scanner 接口，gredis 模式下使用 SCAN
+func (c *DefaultCache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	if c.Mode == CacheModeRedis {
+		if c.Redis == nil {
+			return gerror.NewCode(gcode.CodeNotSupported, MsgErrScanOff) // Never fall back to KEYS | 不回退到 KEYS 命令
+		}
+		return redisScan(ctx, c.Redis, c.PreKey, prefix, fn)
+	}
+	keys, err := c.Cache.Keys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		cacheKey, ok := key.(string)
+		if !ok || !strings.HasPrefix(cacheKey, c.PreKey+prefix) {
+			continue
+		}
+		if !fn(cacheKey[len(c.PreKey):]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// usesClock reports whether expiry is checked against Clock | 判断是否按 Clock 判断过期
+func (c *DefaultCache) usesClock() bool {
+	return c.Clock != nil && c.Mode == CacheModeCache
+}
+
 // writeFileCache writes the cache data to a file | 将缓存数据写入文件
 func (c *DefaultCache) writeFileCache(ctx context.Context) {
 	fileName := gstr.Replace(c.PreKey, ":", "_") + CacheModeFileDat // Generate file name | 生成文件名
@@ -119,6 +227,6 @@ func (c *DefaultCache) initFileCache(ctx context.Context) {
 	}
 	// Load the cache data from file | 从文件加载缓存数据
 	for k, v := range maps {
-		_ = c.Cache.Set(ctx, k, v, gconv.Duration(c.Timeout)*time.Millisecond)
+		_ = c.Cache.Set(ctx, k, v, gconv.Duration(c.timeout())*time.Millisecond)
 	}
 }
@@ -0,0 +1,424 @@
+package dtoken
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File cache fsync policies | 文件缓存刷盘策略
+const (
+	FileSyncAlways      = 1 // fsync after every write | 每次写入后刷盘
+	FileSyncEverySecond = 2 // fsync at most once per second | 每秒最多刷盘一次
+	FileSyncNone        = 3 // leave flushing to the OS | 由操作系统决定刷盘时机
+
+	DefaultFileCompactInterval  = time.Minute // Interval of compaction checks | 压缩检查间隔
+	DefaultFileCompactThreshold = 1000        // Minimum log records before compaction | 触发压缩的最少日志记录数
+
+	fileCacheLogSuffix  = ".log"  // Append-only log file suffix | 追加日志文件后缀
+	fileCacheLockSuffix = ".lock" // Lock file suffix | 锁文件后缀
+	fileCacheTmpSuffix  = ".tmp"  // Compaction temporary file suffix | 压缩临时文件后缀
+)
+
+const (
+	MsgErrFileCacheLocked = "file cache is locked by another process" // Error message when the lock is held | 文件缓存已被其他进程锁定
+	MsgErrFileCacheClosed = "file cache closed"                       // Error message when using a closed cache | 文件缓存已关闭
+)
+
+// fileCacheRecord is one line of the append-only log | 追加日志中的一行记录
+type fileCacheRecord struct {
+	Key      string `json:"k"`           // Cache key without prefix | 不含前缀的缓存 key
+	Value    g.Map  `json:"v,omitempty"` // Value, nil means removal | 缓存值，为空表示删除
+	ExpireAt int64  `json:"e,omitempty"` // Absolute expiry (ms) | 绝对过期时间（毫秒）
+}
+
+// fileCacheEntry is an in-memory cache entry | 内存中的缓存项
+type fileCacheEntry struct {
+	value    g.Map
+	expireAt int64
+}
+
+// FileCache is a durable file-backed Cache | 持久化的文件缓存实现
+// Writes are appended to a log that is compacted periodically, and each entry keeps its absolute expiry across restarts |
+// 写入追加到日志并定期压缩，每个缓存项在重启后保留其绝对过期时间
+type FileCache struct {
+	Dir              string // Storage directory | 存储目录
+	PreKey           string // Cache key prefix, also used as file name | 缓存 key 前缀，同时用作文件名
+	Timeout          int64  // Timeout in milliseconds | 超时时间，单位毫秒
+	SyncMode         int8   // fsync policy: 1-always 2-every second 3-none | 刷盘策略：1 每次 2 每秒 3 不主动
+	CompactThreshold int    // Minimum log records before compaction | 触发压缩的最少日志记录数
+
+	mu       sync.Mutex
+	entries  map[string]fileCacheEntry
+	log      *os.File
+	lock     *os.File
+	records  int  // Records in current log | 当前日志中的记录数
+	dirty    bool // Unsynced writes exist | 存在未刷盘的写入
+	closed   bool
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFileCache opens (or creates) a file cache in dir | 在 dir 中打开或创建文件缓存
+func NewFileCache(dir string, preKey string, timeout int64, syncMode int8) (*FileCache, error) {
+	if dir == "" {
+		dir = gfile.Temp()
+	}
+	if syncMode == 0 {
+		syncMode = FileSyncEverySecond
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &FileCache{
+		Dir:              dir,
+		PreKey:           preKey,
+		Timeout:          timeout,
+		SyncMode:         syncMode,
+		CompactThreshold: DefaultFileCompactThreshold,
+		entries:          make(map[string]fileCacheEntry),
+		stopCh:           make(chan struct{}),
+	}
+
+	// Acquire process lock | 获取进程锁
+	lock, err := os.OpenFile(c.path(fileCacheLockSuffix), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(lock); err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	c.lock = lock
+
+	// Replay log then rewrite it compacted | 重放日志后压缩重写
+	if err = c.load(); err != nil {
+		c.release()
+		return nil, err
+	}
+	if err = c.compact(); err != nil {
+		c.release()
+		return nil, err
+	}
+
+	go c.background()
+	return c, nil
+}
+
+// SetTimeout implements TimeoutSetter | 实现 TimeoutSetter 接口
+func (c *FileCache) SetTimeout(timeout int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Timeout = timeout
+}
+
+// Set sets a cache value with absolute expiry | 设置缓存值及其绝对过期时间
+func (c *FileCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	if cacheValue == nil {
+		return errors.New(MsgErrDataEmpty)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	line, err := json.Marshal(fileCacheRecord{Key: cacheKey, Value: cacheValue, ExpireAt: gtime.Now().TimestampMilli() + c.Timeout})
+	if err != nil {
+		return err
+	}
+	// Keep the decoded form in memory so values look the same before and after restart | 内存中保存解码后的值，保证重启前后一致
+	record, err := decodeFileCacheRecord(line)
+	if err != nil {
+		return err
+	}
+	if err = c.append(line); err != nil {
+		return err
+	}
+	c.entries[cacheKey] = fileCacheEntry{value: record.Value, expireAt: record.ExpireAt}
+	return nil
+}
+
+// Get retrieves a cache value, nil if absent or expired | 获取缓存值，不存在或已过期时返回 nil
+func (c *FileCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errors.New(MsgErrFileCacheClosed)
+	}
+	entry, ok := c.entries[cacheKey]
+	if !ok {
+		return nil, nil
+	}
+	if entry.expireAt <= gtime.Now().TimestampMilli() {
+		delete(c.entries, cacheKey) // Dropped from log at next compaction | 下次压缩时从日志中清除
+		return nil, nil
+	}
+	return copyMap(entry.value), nil
+}
+
+// Remove removes a cache value | 删除缓存值
+func (c *FileCache) Remove(ctx context.Context, cacheKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[cacheKey]; !ok {
+		return nil
+	}
+	line, err := json.Marshal(fileCacheRecord{Key: cacheKey})
+	if err != nil {
+		return err
+	}
+	if err = c.append(line); err != nil {
+		return err
+	}
+	delete(c.entries, cacheKey)
+	return nil
+}
+
+// Scan implements Scanner over live entries, fn runs without holding the cache lock | 基于有效缓存项实现 Scanner 接口，调用 fn 时不持有缓存锁
+func (c *FileCache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errors.New(MsgErrFileCacheClosed)
+	}
+	now := gtime.Now().TimestampMilli()
+	keys := make([]string, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if entry.expireAt > now && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		if !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the log with live entries only | 仅保留有效缓存项重写日志
+func (c *FileCache) Compact() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New(MsgErrFileCacheClosed)
+	}
+	return c.compact()
+}
+
+// Close flushes the log and releases the file lock | 刷盘并释放文件锁
+func (c *FileCache) Close() error {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	var err error
+	if c.log != nil {
+		err = c.log.Sync()
+	}
+	c.release()
+	return err
+}
+
+// path returns a file path of this cache | 返回缓存相关文件路径
+func (c *FileCache) path(suffix string) string {
+	return filepath.Join(c.Dir, gstr.Replace(c.PreKey, ":", "_")+CacheModeFileDat+suffix)
+}
+
+// load replays the log into memory, ignoring a torn last line | 将日志重放到内存，忽略末尾未写完整的行
+// Corrupt lines before the end are skipped with a warning so later records survive | 末尾之前的损坏行会被跳过并告警，保留其后的记录
+func (c *FileCache) load() error {
+	file, err := os.Open(c.path(fileCacheLogSuffix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	now := gtime.Now().TimestampMilli()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	corrupt := 0 // Undecodable line, a torn write if nothing follows | 无法解码的行，其后无内容时为崩溃导致的不完整写入
+	for line := 1; scanner.Scan(); line++ {
+		if corrupt > 0 {
+			g.Log().Warningf(context.Background(), "[GToken]file cache skipped corrupt log line %d of %s", corrupt, file.Name())
+			corrupt = 0
+		}
+		record, err := decodeFileCacheRecord(scanner.Bytes())
+		if err != nil {
+			corrupt = line
+			continue
+		}
+		if record.Value == nil || record.ExpireAt <= now {
+			delete(c.entries, record.Key)
+			continue
+		}
+		c.entries[record.Key] = fileCacheEntry{value: record.Value, expireAt: record.ExpireAt}
+	}
+	return scanner.Err()
+}
+
+// append writes one encoded record to the log, caller must hold the lock | 追加一条日志记录，调用方需持有锁
+func (c *FileCache) append(line []byte) error {
+	if c.closed {
+		return errors.New(MsgErrFileCacheClosed)
+	}
+	if c.log == nil {
+		// Retry the log a failed compaction could not reopen | 重试打开压缩失败后未能重新打开的日志
+		if err := c.openLog(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.log.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	c.records++
+	if c.SyncMode == FileSyncAlways {
+		return c.log.Sync()
+	}
+	c.dirty = true
+	return nil
+}
+
+// compact writes live entries to a temporary file and atomically renames it over the log | 将有效缓存项写入临时文件后原子替换日志
+// Caller must hold the lock | 调用方需持有锁
+func (c *FileCache) compact() error {
+	tmpPath := c.path(fileCacheTmpSuffix)
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	now := gtime.Now().TimestampMilli()
+	records := 0
+	for key, entry := range c.entries {
+		if entry.expireAt <= now {
+			delete(c.entries, key)
+			continue
+		}
+		line, err := json.Marshal(fileCacheRecord{Key: key, Value: entry.value, ExpireAt: entry.expireAt})
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+		_, _ = writer.Write(append(line, '\n'))
+		records++
+	}
+	if err = writer.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Replace the log and reopen it for appending, Windows cannot rename over an open file | 替换日志并重新以追加方式打开，Windows 无法覆盖已打开的文件
+	if c.log != nil {
+		_ = c.log.Close()
+		c.log = nil
+	}
+	if err = os.Rename(tmpPath, c.path(fileCacheLogSuffix)); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Join(err, c.openLog()) // Keep appending to the original log | 继续追加到原日志
+	}
+	syncDir(c.Dir)
+	if err = c.openLog(); err != nil {
+		return err
+	}
+	c.records, c.dirty = records, false
+	return nil
+}
+
+// openLog opens the log for appending, caller must hold the lock | 以追加方式打开日志，调用方需持有锁
+func (c *FileCache) openLog() (err error) {
+	c.log, err = os.OpenFile(c.path(fileCacheLogSuffix), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+// background flushes and compacts the log periodically | 定期刷盘与压缩日志
+func (c *FileCache) background() {
+	syncTicker := time.NewTicker(time.Second)
+	defer syncTicker.Stop()
+	compactTicker := time.NewTicker(DefaultFileCompactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-syncTicker.C:
+			c.mu.Lock()
+			if !c.closed && c.log != nil && c.dirty && c.SyncMode == FileSyncEverySecond {
+				if err := c.log.Sync(); err != nil {
+					g.Log().Error(context.Background(), "[GToken]file cache sync error", err)
+				}
+				c.dirty = false
+			}
+			c.mu.Unlock()
+		case <-compactTicker.C:
+			c.mu.Lock()
+			if !c.closed && c.records >= c.CompactThreshold && c.records > 2*len(c.entries) {
+				if err := c.compact(); err != nil {
+					g.Log().Error(context.Background(), "[GToken]file cache compact error", err)
+				}
+			}
+			c.mu.Unlock()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// release closes files and the lock, caller must hold the lock | 关闭文件并释放锁，调用方需持有锁
+func (c *FileCache) release() {
+	if c.log != nil {
+		_ = c.log.Close()
+		c.log = nil
+	}
+	if c.lock != nil {
+		_ = unlockFile(c.lock)
+		_ = c.lock.Close()
+		c.lock = nil
+	}
+	c.closed = true
+}
+
+// syncDir fsyncs a directory so that a rename is durable | 对目录刷盘以保证重命名持久化
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
+
+// decodeFileCacheRecord decodes a log line keeping numbers as json.Number | 解码日志行，数值保留为 json.Number
+func decodeFileCacheRecord(line []byte) (fileCacheRecord, error) {
+	var record fileCacheRecord
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	err := decoder.Decode(&record)
+	return record, err
+}
+
+// copyMap returns a shallow copy so callers cannot mutate cached values | 返回浅拷贝，防止调用方修改缓存值
+func copyMap(m g.Map) g.Map {
+	cp := make(g.Map, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,182 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestFileCache_Persistence(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.Set(ctx, "alice", g.Map{KeyToken: "t1", KeyRefreshNum: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.Set(ctx, "bob", g.Map{KeyToken: "t2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.Remove(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second process must not open the same store
+	if _, err = NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways); err == nil {
+		t.Fatal("expected lock conflict")
+	}
+
+	// Simulate a torn write before restart
+	expireAt := cache.entries["alice"].expireAt
+	logFile, _ := os.OpenFile(cache.path(fileCacheLogSuffix), os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = logFile.WriteString(`{"k":"carol","v":{"tok`)
+	_ = logFile.Close()
+	if err = cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	value, err := reopened.Get(ctx, "alice")
+	if err != nil || value[KeyToken] != "t1" || gconv.Int(value[KeyRefreshNum]) != 2 {
+		t.Fatalf("unexpected value after restart: %v %v", value, err)
+	}
+	if reopened.entries["alice"].expireAt != expireAt {
+		t.Fatal("expected original expiry to be preserved")
+	}
+	if value, _ = reopened.Get(ctx, "bob"); value != nil {
+		t.Fatal("expected removed entry to stay removed")
+	}
+	if value, _ = reopened.Get(ctx, "carol"); value != nil {
+		t.Fatal("expected torn record to be ignored")
+	}
+	if reopened.records != 1 {
+		t.Fatalf("expected compacted log with 1 record, got %d", reopened.records)
+	}
+}
+
+func TestFileCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir, "GToken:", 20, FileSyncNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = cache.Set(ctx, "alice", g.Map{KeyToken: "t1"})
+	_ = cache.Close()
+	time.Sleep(40 * time.Millisecond)
+
+	// Expired entries must not be resurrected by a restart
+	reopened, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if value, _ := reopened.Get(ctx, "alice"); value != nil {
+		t.Fatalf("expected expired entry, got %v", value)
+	}
+}
+
+func TestFileCache_Scan(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewFileCache(t.TempDir(), "GToken:", 60*1000, FileSyncNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	for _, key := range []string{"sessions:alice", "sessions:bob", "alice"} {
+		_ = cache.Set(ctx, key, g.Map{KeyToken: key})
+	}
+
+	// fn may use the cache while scanning | 扫描期间 fn 可以访问缓存
+	var keys []string
+	if err = cache.Scan(ctx, "sessions:", func(cacheKey string) bool {
+		if value, _ := cache.Get(ctx, cacheKey); value == nil {
+			t.Fatalf("scanned key %q not readable", cacheKey)
+		}
+		keys = append(keys, cacheKey)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"sessions:alice", "sessions:bob"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestFileCache_CorruptLine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = cache.Set(ctx, "alice", g.Map{KeyToken: "t1"})
+	logFile, _ := os.OpenFile(cache.path(fileCacheLogSuffix), os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = logFile.WriteString("garbage\n")
+	_ = logFile.Close()
+	_ = cache.Set(ctx, "bob", g.Map{KeyToken: "t2"})
+	_ = cache.Close()
+
+	// Records after a corrupt line in the middle survive the restart
+	reopened, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	for key, token := range map[string]string{"alice": "t1", "bob": "t2"} {
+		if value, _ := reopened.Get(ctx, key); value[KeyToken] != token {
+			t.Fatalf("expected %s to survive a corrupt line, got %v", key, value)
+		}
+	}
+}
+
+func TestFileCache_CompactFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir, "GToken:", 60*1000, FileSyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	_ = cache.Set(ctx, "alice", g.Map{KeyToken: "t1"})
+
+	// A directory in place of the log makes both the rename and the reopen fail
+	logPath := cache.path(fileCacheLogSuffix)
+	_ = os.Remove(logPath)
+	_ = os.MkdirAll(filepath.Join(logPath, "blocker"), 0o755)
+	if err = cache.Compact(); err == nil {
+		t.Fatal("expected compaction to fail")
+	}
+	if err = cache.Set(ctx, "bob", g.Map{KeyToken: "t2"}); err == nil {
+		t.Fatal("expected append to fail while the log is blocked")
+	}
+
+	// Appends recover once the log can be opened again
+	_ = os.RemoveAll(logPath)
+	if err = cache.Set(ctx, "carol", g.Map{KeyToken: "t3"}); err != nil {
+		t.Fatalf("expected append to reopen the log, got %v", err)
+	}
+	if err = cache.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.Set(ctx, "dave", g.Map{KeyToken: "t4"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+package dtoken
+
+import (
+	"context"
+	"errors"
+	"github.com/gogf/gf/v2/database/gredis"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Lua scripts executed atomically by Redis | 由 Redis 原子执行的 Lua 脚本
+const (
+	// KEYS[1]=key ARGV[1]=ttl(ms) ARGV[2..]=field,value pairs | 覆盖写入哈希并设置过期时间
+	redisSetScript = `
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], unpack(ARGV, 2))
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return 1`
+
+	// KEYS[1]=key ARGV[1]=token ARGV[2]=renewTime ARGV[3]=ttl(ms) | 校验 Token 后续期
+	redisRenewScript = `
+if redis.call('HGET', KEYS[1], '` + KeyToken + `') ~= ARGV[1] then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], '` + KeyRefreshNum + `', 1)
+redis.call('HSET', KEYS[1], '` + KeyLastRenewTime + `', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1`
+
+	// KEYS[1]=key ARGV[1]=ttl(ms) ARGV[2]=token ARGV[3..]=field,value pairs | 校验 Token 后覆盖写入哈希
+	redisSwapScript = `
+if redis.call('HGET', KEYS[1], '` + KeyToken + `') ~= ARGV[2] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return 1`
+)
+
+// RedisCache stores each cache entry as a Redis hash | 以 Redis 哈希存储每个缓存项
+// Field values are JSON encoded so numbers stay usable by HINCRBY | 字段值以 JSON 编码，数值字段可直接被 HINCRBY 使用
+type RedisCache struct {
+	Redis   *gredis.Redis // Redis client | Redis 客户端
+	PreKey  string        // Cache key prefix | 缓存 key 前缀
+	Timeout int64         // Timeout in milliseconds | 超时时间，单位毫秒
+}
+
+// NewRedisCache creates a RedisCache on the given redis group ("" for default) | 使用指定 Redis 分组创建 RedisCache（空为默认分组）
+func NewRedisCache(group string, preKey string, timeout int64) *RedisCache {
+	redis := g.Redis()
+	if group != "" {
+		redis = g.Redis(group)
+	}
+	return &RedisCache{
+		Redis:   redis,
+		PreKey:  preKey,
+		Timeout: timeout,
+	}
+}
+
+// SetTimeout implements TimeoutSetter | 实现 TimeoutSetter 接口
+func (c *RedisCache) SetTimeout(timeout int64) {
+	atomic.StoreInt64(&c.Timeout, timeout)
+}
+
+// Set overwrites the hash and resets its TTL | 覆盖写入哈希并重置有效期
+func (c *RedisCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	if cacheValue == nil {
+		return errors.New(MsgErrDataEmpty)
+	}
+	if len(cacheValue) == 0 {
+		return c.Remove(ctx, cacheKey) // Empty map is stored as absent | 空 map 视为删除
+	}
+	args := make([]any, 0, 4+len(cacheValue)*2)
+	args = append(args, redisSetScript, 1, c.PreKey+cacheKey, atomic.LoadInt64(&c.Timeout))
+	for field, value := range cacheValue {
+		encoded, err := gjson.Encode(value)
+		if err != nil {
+			return err
+		}
+		args = append(args, field, string(encoded))
+	}
+	_, err := c.Redis.Do(ctx, "EVAL", args...)
+	return err
+}
+
+// Get reads all hash fields | 读取哈希全部字段
+func (c *RedisCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	result, err := c.Redis.Do(ctx, "HGETALL", c.PreKey+cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	fields := result.MapStrStr()
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	cacheValue := make(g.Map, len(fields))
+	for field, value := range fields {
+		decoded, err := gjson.Decode(value)
+		if err != nil {
+			return nil, err
+		}
+		cacheValue[field] = decoded
+	}
+	return cacheValue, nil
+}
+
+// Remove deletes the hash | 删除哈希
+func (c *RedisCache) Remove(ctx context.Context, cacheKey string) error {
+	_, err := c.Redis.Do(ctx, "DEL", c.PreKey+cacheKey)
+	return err
+}
+
+// Scan implements Scanner using SCAN | 使用 SCAN 实现 Scanner 接口
+func (c *RedisCache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	return redisScan(ctx, c.Redis, c.PreKey, prefix, fn)
+}
+
+// Renew atomically renews a session via Lua script | 通过 Lua 脚本原子续期会话
+func (c *RedisCache) Renew(ctx context.Context, cacheKey string, token string, renewTime int64) (bool, error) {
+	encodedToken, err := gjson.Encode(token)
+	if err != nil {
+		return false, err
+	}
+	result, err := c.Redis.Do(ctx, "EVAL", redisRenewScript, 1, c.PreKey+cacheKey, string(encodedToken), renewTime, atomic.LoadInt64(&c.Timeout))
+	if err != nil {
+		return false, err
+	}
+	return gconv.Int(result.Val()) == 1, nil
+}
+
+// Swap atomically replaces a hash via Lua script | 通过 Lua 脚本原子替换哈希
+func (c *RedisCache) Swap(ctx context.Context, cacheKey string, token string, cacheValue g.Map) (bool, error) {
+	if len(cacheValue) == 0 {
+		return false, errors.New(MsgErrDataEmpty)
+	}
+	encodedToken, err := gjson.Encode(token)
+	if err != nil {
+		return false, err
+	}
+	args := make([]any, 0, 5+len(cacheValue)*2)
+	args = append(args, redisSwapScript, 1, c.PreKey+cacheKey, atomic.LoadInt64(&c.Timeout), string(encodedToken))
+	for field, value := range cacheValue {
+		encoded, err := gjson.Encode(value)
+		if err != nil {
+			return false, err
+		}
+		args = append(args, field, string(encoded))
+	}
+	result, err := c.Redis.Do(ctx, "EVAL", args...)
+	if err != nil {
+		return false, err
+	}
+	return gconv.Int(result.Val()) == 1, nil
+}
+
+// redisScan iterates keys starting with preKey+prefix without blocking Redis like KEYS does | 遍历以 preKey+prefix 开头的 key，不会像 KEYS 一样阻塞 Redis
+func redisScan(ctx context.Context, redis *gredis.Redis, preKey, prefix string, fn func(cacheKey string) bool) error {
+	pattern := redisGlobEscaper.Replace(preKey+prefix) + "*"
+	cursor := "0"
+	for {
+		result, err := redis.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", DefaultScanCount)
+		if err != nil {
+			return err
+		}
+		reply := result.Vars()
+		if len(reply) != 2 {
+			return errors.New("unexpected SCAN reply")
+		}
+		for _, key := range reply[1].Strings() {
+			if strings.HasPrefix(key, preKey+prefix) && !fn(key[len(preKey):]) {
+				return nil
+			}
+		}
+		if cursor = reply[0].String(); cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// redisGlobEscaper escapes glob characters of SCAN MATCH patterns | 转义 SCAN MATCH 模式中的通配字符
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
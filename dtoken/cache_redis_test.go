@@ -0,0 +1,178 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/gogf/gf/contrib/nosql/redis/v2"
+	"github.com/gogf/gf/v2/database/gredis"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/util/gconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestRedisCache(t *testing.T, preKey string, timeout int64) (*RedisCache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	redis, err := gredis.New(&gredis.Config{Address: server.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = redis.Close(context.Background()) })
+	return &RedisCache{Redis: redis, PreKey: preKey, Timeout: timeout}, server
+}
+
+func TestRedisCache_SetGetRemove(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t, "GToken:", 60*1000)
+
+	value := g.Map{
+		KeyUserKey:    "alice",
+		KeyToken:      "t1",
+		KeyData:       g.Map{"name": "Alice"},
+		KeyRefreshNum: 0,
+	}
+	if err := cache.Set(ctx, "alice", value); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("GToken:alice") || server.TTL("GToken:alice") != time.Minute {
+		t.Fatalf("expected hash with ttl, got ttl %v", server.TTL("GToken:alice"))
+	}
+	got, err := cache.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[KeyToken] != "t1" || gconv.Map(got[KeyData])["name"] != "Alice" || gconv.Int(got[KeyRefreshNum]) != 0 {
+		t.Fatalf("unexpected value: %v", got)
+	}
+
+	if err = cache.Remove(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = cache.Get(ctx, "alice"); err != nil || got != nil {
+		t.Fatalf("expected nil after remove, got %v %v", got, err)
+	}
+}
+
+func TestRedisCache_AtomicRenew(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t, "GToken:", 60*1000)
+	if err := cache.Set(ctx, "alice", g.Map{KeyToken: "t1", KeyRefreshNum: 0, KeyLastRenewTime: 0}); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(30 * time.Second)
+
+	// Concurrent renewals from several instances must not lose increments
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := cache.Renew(ctx, "alice", "t1", 1234); err != nil || !ok {
+				t.Error("renew failed", ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := cache.Get(ctx, "alice")
+	if gconv.Int(got[KeyRefreshNum]) != 20 || gconv.Int64(got[KeyLastRenewTime]) != 1234 {
+		t.Fatalf("unexpected renewed value: %v", got)
+	}
+	if server.TTL("GToken:alice") != time.Minute {
+		t.Fatalf("expected ttl to be extended, got %v", server.TTL("GToken:alice"))
+	}
+	if ok, _ := cache.Renew(ctx, "alice", "other", 1); ok {
+		t.Fatal("renew with a replaced token must be refused")
+	}
+}
+
+func TestRedisCache_Swap(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t, "GToken:", 60*1000)
+
+	if err := cache.Set(ctx, "alice", g.Map{KeyToken: "t1", KeyRotateNum: 0}); err != nil {
+		t.Fatal(err)
+	}
+	swapped, err := cache.Swap(ctx, "alice", "t0", g.Map{KeyToken: "t2", KeyRotateNum: 1})
+	if err != nil || swapped {
+		t.Fatalf("expected swap with stale token to fail, got %v %v", swapped, err)
+	}
+	server.SetTTL("GToken:alice", time.Second)
+	swapped, err = cache.Swap(ctx, "alice", "t1", g.Map{KeyToken: "t2", KeyRotateNum: 1})
+	if err != nil || !swapped {
+		t.Fatalf("expected swap to succeed, got %v %v", swapped, err)
+	}
+	got, _ := cache.Get(ctx, "alice")
+	if got[KeyToken] != "t2" || gconv.Int(got[KeyRotateNum]) != 1 || server.TTL("GToken:alice") != time.Minute {
+		t.Fatalf("unexpected value after swap: %v ttl %v", got, server.TTL("GToken:alice"))
+	}
+	if swapped, _ = cache.Swap(ctx, "bob", "", g.Map{KeyToken: "t1"}); swapped {
+		t.Fatal("expected swap of a missing key to fail")
+	}
+}
+
+func TestRedisCache_Token(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	token.Cache, _ = newTestRedisCache(t, "GToken:", token.Options.Timeout)
+
+	accessToken, err := token.GenerateWithDevice(ctx, "alice", "web", g.Map{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := token.Validate(ctx, accessToken)
+	if err != nil || gconv.Int(gconv.Map(data)["id"]) != 1 {
+		t.Fatalf("unexpected validate result %v %v", data, err)
+	}
+	if sessions, err := token.ListSessions(ctx, "alice"); err != nil || len(sessions) != 1 {
+		t.Fatalf("unexpected sessions %v %v", sessions, err)
+	}
+}
+
+func TestRedisCache_Scan(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t, "GToken:", 60*1000)
+	for i := 0; i < 250; i++ {
+		if err := cache.Set(ctx, "sessions:user"+gconv.String(i), g.Map{"web": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = cache.Set(ctx, "sessions:a*b", g.Map{"web": 1})
+	_ = server.Set("GToken:refresh:sessions:x", "other")
+	_ = server.Set("Other:sessions:x", "other")
+
+	seen := make(map[string]struct{})
+	if err := cache.Scan(ctx, "sessions:", func(cacheKey string) bool {
+		seen[cacheKey] = struct{}{}
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := seen["sessions:user249"]; len(seen) != 251 || !ok {
+		t.Fatalf("expected 251 keys without prefix, got %d", len(seen))
+	}
+
+	// Glob characters of prefix match literally | prefix 中的通配字符按字面匹配
+	var keys []string
+	_ = cache.Scan(ctx, "sessions:a*", func(cacheKey string) bool {
+		keys = append(keys, cacheKey)
+		return true
+	})
+	if len(keys) != 1 || keys[0] != "sessions:a*b" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	// gredis mode of DefaultCache scans the same way | DefaultCache 的 gredis 模式同样使用 SCAN
+	defaultCache := &DefaultCache{Cache: gcache.New(), Mode: CacheModeRedis, PreKey: "Default:", Timeout: 60 * 1000, Redis: cache.Redis}
+	defaultCache.Cache.SetAdapter(gcache.NewAdapterRedis(cache.Redis))
+	_ = defaultCache.Set(ctx, "sessions:bob", g.Map{"web": 1})
+	keys = nil
+	if err := defaultCache.Scan(ctx, "sessions:", func(cacheKey string) bool {
+		keys = append(keys, cacheKey)
+		return false
+	}); err != nil || len(keys) != 1 || keys[0] != "sessions:bob" {
+		t.Fatalf("unexpected keys %v %v", keys, err)
+	}
+}
@@ -0,0 +1,215 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"net/http"
+)
+
+// Carrier is a transport-neutral view of an incoming request | 与传输框架无关的请求抽象
+type Carrier interface {
+	Method() string            // Request method | 请求方法
+	Path() string              // Request URL path | 请求路径
+	Host() string              // Request host, may include port | 请求主机，可能包含端口
+	Header(key string) string  // Request header | 请求头
+	Param(key string) string   // Request parameter | 请求参数
+	Cookie(name string) string // Request cookie | 请求 Cookie
+	Context() context.Context  // Request context | 请求上下文
+}
+
+// GHttpCarrier adapts *ghttp.Request to Carrier | 将 *ghttp.Request 适配为 Carrier
+type GHttpCarrier struct {
+	Request *ghttp.Request
+}
+
+// NewGHttpCarrier creates a new GHttpCarrier instance | 创建一个新的 GHttpCarrier 实例
+func NewGHttpCarrier(r *ghttp.Request) *GHttpCarrier {
+	return &GHttpCarrier{Request: r}
+}
+
+// Method implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Method() string {
+	return c.Request.Method
+}
+
+// Path implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Path() string {
+	return c.Request.URL.Path
+}
+
+// Host implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Host() string {
+	return c.Request.Host
+}
+
+// Header implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Header(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+// Param implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Param(key string) string {
+	return c.Request.Get(key).String()
+}
+
+// Cookie implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Cookie(name string) string {
+	return c.Request.Cookie.Get(name).String()
+}
+
+// Context implements Carrier | 实现 Carrier 接口
+func (c *GHttpCarrier) Context() context.Context {
+	return c.Request.Context()
+}
+
+// HTTPCarrier adapts *http.Request to Carrier, params are read from the query string | 将 *http.Request 适配为 Carrier，参数从查询串读取
+type HTTPCarrier struct {
+	Request *http.Request
+}
+
+// NewHTTPCarrier creates a new HTTPCarrier instance | 创建一个新的 HTTPCarrier 实例
+func NewHTTPCarrier(r *http.Request) *HTTPCarrier {
+	return &HTTPCarrier{Request: r}
+}
+
+// Method implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Method() string {
+	return c.Request.Method
+}
+
+// Path implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Path() string {
+	return c.Request.URL.Path
+}
+
+// Host implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Host() string {
+	return c.Request.Host
+}
+
+// Header implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Header(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+// Param implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Param(key string) string {
+	return c.Request.URL.Query().Get(key)
+}
+
+// Cookie implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Cookie(name string) string {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// Context implements Carrier | 实现 Carrier 接口
+func (c *HTTPCarrier) Context() context.Context {
+	return c.Request.Context()
+}
+
+// GetCarrierToken extracts token from a request using DefaultTokenLookup | 使用 DefaultTokenLookup 从请求中提取 Token
+// Supported methods: Header("Authorization: Bearer <token>") or param "token" | 支持 Header 方式和参数方式
+func GetCarrierToken(c Carrier) (string, error) {
+	return defaultExtractor.Extract(c)
+}
+
+// IsExcludePath reports whether urlPath bypasses authentication | 判断路径是否跳过认证
+// Rules follow PathRules syntax without method lists and are compiled per call, prefer PathRules | 规则遵循 PathRules 语法（不含方法列表）且每次调用时编译，建议使用 PathRules
+func IsExcludePath(excludePaths []string, urlPath string) bool {
+	// No exclusion rules configured | 未配置排除路径
+	if len(excludePaths) == 0 {
+		return false
+	}
+
+	// Iterate through exclude paths | 遍历排除路径规则
+	for _, excludePath := range excludePaths {
+		if matchPath(excludePath, urlPath) {
+			return true
+		}
+	}
+
+	// No exclusion match -> require authentication | 未匹配排除规则则需认证
+	return false
+}
+
+// Authenticate extracts and validates the request token and checks authorization | 提取并校验请求 Token，并进行授权检查
+// A nil extractor uses DefaultTokenLookup, ErrorStatus of the error tells 401, 403 and 500 apart | extractor 为 nil 时使用 DefaultTokenLookup，可通过 ErrorStatus 区分 401、403 与 500
+func Authenticate(token Token, extractor TokenExtractor, authorizer *Authorizer, c Carrier) (*Session, *Grants, error) {
+	if extractor == nil {
+		extractor = defaultExtractor
+	}
+	tokenStr, err := extractor.Extract(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check CSRF first so forged requests never renew the session | 先校验 CSRF，避免伪造请求续期会话
+	if err = checkCsrf(token.GetOptions(), c, tokenStr); err != nil {
+		return nil, nil, err
+	}
+	session, err := token.ValidateSession(c.Context(), tokenStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if authorizer == nil {
+		return session, nil, nil
+	}
+	grants, err := authorizer.Authorize(c.Context(), session, c.Method(), c.Path())
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, grants, nil
+}
+
+// authContextKey is the context key of authenticated request info | 已认证请求信息的上下文 key
+type authContextKey struct{}
+
+// authContext holds authenticated request info | 已认证请求的信息
+type authContext struct {
+	session *Session
+	grants  *Grants
+}
+
+// WithSession stores the authenticated session and grants in ctx | 将已认证的会话与权限存入上下文
+func WithSession(ctx context.Context, session *Session, grants *Grants) context.Context {
+	return context.WithValue(ctx, authContextKey{}, &authContext{session: session, grants: grants})
+}
+
+// SessionFromContext returns the authenticated session | 返回已认证的会话
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	auth, ok := ctx.Value(authContextKey{}).(*authContext)
+	if !ok || auth.session == nil {
+		return nil, false
+	}
+	return auth.session, true
+}
+
+// UserKeyFromContext returns the authenticated userKey | 返回已认证的用户标识
+func UserKeyFromContext(ctx context.Context) (string, bool) {
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return session.UserKey, true
+}
+
+// DataFromContext returns the custom data of the authenticated session | 返回已认证会话的自定义数据
+func DataFromContext(ctx context.Context) (any, bool) {
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return session.Data, true
+}
+
+// GrantsFromContext returns the resolved grants, nil when authorization is disabled | 返回已解析的权限，未启用授权时为 nil
+func GrantsFromContext(ctx context.Context) (*Grants, bool) {
+	auth, ok := ctx.Value(authContextKey{}).(*authContext)
+	if !ok || auth.grants == nil {
+		return nil, false
+	}
+	return auth.grants, true
+}
@@ -0,0 +1,37 @@
+package dtoken
+
+import (
+	"time"
+)
+
+// Clock provides the current time to token logic | 为 Token 逻辑提供当前时间
+type Clock interface {
+	Now() time.Time // Current time | 当前时间
+}
+
+// ClockFunc adapts a function to Clock | 将函数适配为 Clock
+type ClockFunc func() time.Time
+
+// Now implements Clock | 实现 Clock 接口
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock reads the wall clock | 读取系统时间
+var SystemClock Clock = ClockFunc(time.Now)
+
+// now returns the current time in milliseconds | 返回当前毫秒时间戳
+func (m *GTokenV2) now() int64 {
+	if m.Clock == nil {
+		return SystemClock.Now().UnixMilli()
+	}
+	return m.Clock.Now().UnixMilli()
+}
+
+// tokenNow returns the current time of token in milliseconds, other tokens use SystemClock | 返回 Token 的当前毫秒时间戳，其他 Token 使用 SystemClock
+func tokenNow(token Token) int64 {
+	if m, ok := token.(*GTokenV2); ok {
+		return m.now()
+	}
+	return SystemClock.Now().UnixMilli()
+}
@@ -77,10 +77,10 @@ func (c *DefaultCodec) Decrypt(ctx context.Context, token string) (userKey strin
 	if err != nil {
 		return "", err
 	}
-	// Split the decrypted string to extract userKey | 分割解密后的字符串，提取用户标识
-	decryptArray := gstr.Split(string(decryptStr), c.Delimiter)
-	if len(decryptArray) < 2 {
+	// Split on the last delimiter, the random part never contains it | 按最后一个分隔符拆分，随机串中不包含分隔符
+	pos := gstr.PosR(string(decryptStr), c.Delimiter)
+	if pos <= 0 {
 		return "", errors.New(MsgErrTokenLen) // Error when the token length is invalid | Token 长度无效时返回错误
 	}
-	return decryptArray[0], nil
+	return string(decryptStr[:pos]), nil
 }
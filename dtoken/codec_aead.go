@@ -0,0 +1,159 @@
+package dtoken
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// AEAD token layout versions | AEAD Token 布局版本
+const (
+	AEADVersion1 byte = 0x01 // version | nonce(12) | AES-GCM(uvarint len | userKey | random(16)) | 版本 | 随机数 | 密文
+	AEADVersion2 byte = 0x02 // version | idLen(1) | keyId | nonce(12) | AES-GCM(...) | 版本 | 密钥 ID 长度 | 密钥 ID | 随机数 | 密文
+
+	aeadRandomSize = 16 // Random bytes appended to userKey | 附加在用户标识后的随机字节数
+)
+
+var aeadEncoding = base64.RawURLEncoding
+
+// AEADCodec implements Codec with AES-GCM authenticated encryption | 基于 AES-GCM 认证加密的编解码实现
+// Tokens are URL-safe base64 of a versioned binary layout | Token 为带版本号二进制布局的 URL 安全 Base64 编码
+type AEADCodec struct {
+	// EncryptKey AES key (16, 24 or 32 bytes) for untagged tokens | 无密钥 ID 的 Token 使用的 AES 密钥（16、24 或 32 字节）
+	EncryptKey []byte
+	// KeyRing when set, tokens are encrypted with its active key and tagged with the key id | 设置后使用其激活密钥加密并在 Token 中写入密钥 ID
+	KeyRing *KeyRing
+	// Legacy decoder accepted during migration (nil rejects legacy tokens) | 迁移期间兼容的旧版解码器（为 nil 时拒绝旧版 Token）
+	Legacy Decoder
+}
+
+// NewAEADCodec creates a new AEADCodec instance | 创建一个新的 AEADCodec 实例
+func NewAEADCodec(encryptKey []byte, legacy ...Decoder) *AEADCodec {
+	c := &AEADCodec{EncryptKey: encryptKey}
+	if len(legacy) > 0 {
+		c.Legacy = legacy[0]
+	}
+	return c
+}
+
+// Encode seals userKey and a random suffix into a token | 将用户标识与随机串加密封装为 Token
+func (c *AEADCodec) Encode(ctx context.Context, userKey string) (token string, err error) {
+	if userKey == "" {
+		return "", errors.New(MsgErrUserKeyEmpty)
+	}
+	// Length-prefixed userKey followed by random bytes | 长度前缀的用户标识加随机字节
+	plain := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(userKey)+aeadRandomSize), uint64(len(userKey)))
+	plain = append(plain, userKey...)
+	random := make([]byte, aeadRandomSize)
+	if _, err = rand.Read(random); err != nil {
+		return "", err
+	}
+	plain = append(plain, random...)
+
+	// Header: version, plus key id when a key ring is used | 头部：版本号，使用密钥环时附加密钥 ID
+	key, header := c.EncryptKey, []byte{AEADVersion1}
+	if c.KeyRing != nil {
+		active := c.KeyRing.Active()
+		key = active.Key
+		header = append([]byte{AEADVersion2, byte(len(active.Id))}, active.Id...)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	out := make([]byte, len(header)+gcm.NonceSize(), len(header)+gcm.NonceSize()+len(plain)+gcm.Overhead())
+	copy(out, header)
+	nonce := out[len(header):]
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// Header is authenticated as additional data | 头部作为附加认证数据
+	out = gcm.Seal(out, nonce, plain, out[:len(header)])
+	return aeadEncoding.EncodeToString(out), nil
+}
+
+// Decrypt opens the token and returns userKey, falling back to Legacy if set | 解密 Token 返回用户标识，配置了 Legacy 时兼容旧版 Token
+func (c *AEADCodec) Decrypt(ctx context.Context, token string) (userKey string, err error) {
+	if token == "" {
+		return "", errors.New(MsgErrTokenEmpty)
+	}
+	if userKey, err = c.open(token); err == nil {
+		return userKey, nil
+	}
+	if c.Legacy != nil {
+		if userKey, err = c.Legacy.Decrypt(ctx, token); err == nil {
+			return userKey, nil
+		}
+	}
+	return "", errors.New(MsgErrTokenInvalid)
+}
+
+// open decodes a versioned AEAD token | 解析带版本号的 AEAD Token
+func (c *AEADCodec) open(token string) (string, error) {
+	raw, err := aeadEncoding.DecodeString(token)
+	if err != nil || len(raw) == 0 {
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+
+	// Resolve key and header length by version | 根据版本确定密钥与头部长度
+	var (
+		key       []byte
+		headerLen int
+	)
+	switch raw[0] {
+	case AEADVersion1:
+		key, headerLen = c.EncryptKey, 1
+	case AEADVersion2:
+		if c.KeyRing == nil || len(raw) < 2 || len(raw) < 2+int(raw[1]) {
+			return "", errors.New(MsgErrTokenInvalid)
+		}
+		headerLen = 2 + int(raw[1])
+		var ok bool
+		if key, ok = c.KeyRing.Lookup(string(raw[2:headerLen])); !ok {
+			return "", errors.New(MsgErrTokenInvalid)
+		}
+	default:
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+	if len(key) == 0 {
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(raw) < headerLen+nonceSize+gcm.Overhead() {
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+	// gcm.Open verifies the tag in constant time | gcm.Open 以常量时间校验认证标签
+	plain, err := gcm.Open(nil, raw[headerLen:headerLen+nonceSize], raw[headerLen+nonceSize:], raw[:headerLen])
+	if err != nil {
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+	return decodeSealedUserKey(plain)
+}
+
+// decodeSealedUserKey extracts the length-prefixed userKey | 提取长度前缀的用户标识
+func decodeSealedUserKey(plain []byte) (string, error) {
+	size, n := binary.Uvarint(plain)
+	if n <= 0 || size == 0 || uint64(len(plain)-n) < size+aeadRandomSize {
+		return "", errors.New(MsgErrTokenInvalid)
+	}
+	return string(plain[n : n+int(size)]), nil
+}
+
+// newGCM creates AES-GCM with the given key | 使用给定密钥创建 AES-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,51 @@
+package dtoken
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAEADCodec_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	codec := NewAEADCodec([]byte(DefaultEncryptKey))
+
+	token, err := codec.Encode(ctx, "tenant_a#web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, err := codec.Decrypt(ctx, token)
+	if err != nil || userKey != "tenant_a#web" {
+		t.Fatalf("unexpected decrypt result %q %v", userKey, err)
+	}
+
+	// Flip one bit in every position, each variant must be rejected
+	raw, _ := aeadEncoding.DecodeString(token)
+	for i := range raw {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+		if _, err = codec.Decrypt(ctx, aeadEncoding.EncodeToString(tampered)); err == nil {
+			t.Fatalf("expected tampered byte %d to be rejected", i)
+		}
+	}
+}
+
+func TestAEADCodec_Legacy(t *testing.T) {
+	ctx := context.Background()
+	legacy := NewDefaultCodec(DefaultTokenDelimiter, []byte(DefaultEncryptKey))
+	legacyToken, err := legacy.Encode(ctx, "user_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userKey, err := legacy.Decrypt(ctx, legacyToken); err != nil || userKey != "user_1" {
+		t.Fatalf("unexpected legacy decrypt result %q %v", userKey, err)
+	}
+
+	strict := NewAEADCodec([]byte(DefaultEncryptKey))
+	if _, err = strict.Decrypt(ctx, legacyToken); err == nil {
+		t.Fatal("expected legacy token to be rejected")
+	}
+	migrating := NewAEADCodec([]byte(DefaultEncryptKey), legacy)
+	if userKey, err := migrating.Decrypt(ctx, legacyToken); err != nil || userKey != "user_1" {
+		t.Fatalf("unexpected migrating decrypt result %q %v", userKey, err)
+	}
+}
@@ -0,0 +1,383 @@
+package dtoken
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/grand"
+	"math/big"
+	"strings"
+)
+
+// Supported JWT signing algorithms | 支持的 JWT 签名算法
+const (
+	JwtAlgHS256 = "HS256" // HMAC using SHA-256 | HMAC-SHA256
+	JwtAlgRS256 = "RS256" // RSASSA-PKCS1-v1_5 using SHA-256 | RSA-SHA256
+	JwtAlgES256 = "ES256" // ECDSA using P-256 and SHA-256 | ECDSA P-256
+	JwtAlgEdDSA = "EdDSA" // Ed25519 signature | Ed25519 签名
+
+	JwtClaimSub    = "sub" // Subject: userKey | 主体：用户标识
+	JwtClaimDevice = "did" // Device identifier | 设备标识
+	JwtClaimIss    = "iss" // Issuer | 签发者
+	JwtClaimIat    = "iat" // Issued at (seconds) | 签发时间（秒）
+	JwtClaimExp    = "exp" // Expiration time (seconds) | 过期时间（秒）
+	JwtClaimJti    = "jti" // Token identifier | Token 唯一标识
+	// JwtClaimUse is "access" or "refresh", external verifiers must only accept "access" tokens | 取值为 access 或 refresh，外部校验方只能接受 access Token
+	JwtClaimUse = "token_use"
+
+	JwtUseAccess  = "access"  // Value of JwtClaimUse on access tokens | 访问令牌的 token_use 取值
+	JwtUseRefresh = "refresh" // Value of JwtClaimUse on refresh tokens | 刷新令牌的 token_use 取值
+)
+
+const (
+	MsgErrJwtFormat    = "jwt format error"            // Error message when JWT is not three segments | JWT 格式错误
+	MsgErrJwtAlg       = "jwt algorithm mismatch"      // Error message when header alg differs from codec | JWT 算法不匹配
+	MsgErrJwtSignature = "jwt signature invalid"       // Error message when signature verification fails | JWT 签名校验失败
+	MsgErrJwtExpired   = "jwt expired"                 // Error message when exp has passed | JWT 已过期
+	MsgErrJwtKey       = "jwt key invalid for alg"     // Error message when key does not fit the algorithm | 密钥与算法不匹配
+	MsgErrJwtUnsupport = "jwt algorithm not supported" // Error message for unknown algorithm | 不支持的 JWT 算法
+	MsgErrJwtUse       = "jwt token_use mismatch"      // Error message when a refresh JWT is used as access token or vice versa | 刷新令牌被当作访问令牌使用或相反
+)
+
+var jwtEncoding = base64.RawURLEncoding
+
+// JWTCodec implements Codec producing standard signed JWTs | 生成标准签名 JWT 的编解码实现
+// The session key is split into "sub" (userKey) and "did" (deviceId) claims | 会话 key 拆分为 sub（用户标识）与 did（设备标识）声明
+// "token_use" tells access and refresh tokens apart, see JwtClaimUse | token_use 区分访问令牌与刷新令牌，参见 JwtClaimUse
+type JWTCodec struct {
+	Alg        string           // Signing algorithm | 签名算法
+	Secret     []byte           // HMAC secret for HS256 | HS256 使用的密钥
+	PrivateKey crypto.Signer    // Signing key for RS256/ES256/EdDSA | RS256/ES256/EdDSA 签名私钥
+	PublicKey  crypto.PublicKey // Verification key for RS256/ES256/EdDSA | RS256/ES256/EdDSA 验签公钥
+	Issuer     string           // Optional "iss" claim | 可选的签发者声明
+	Expire     int64            // Token lifetime used for "exp" (ms) | 用于 exp 的有效期（毫秒）
+	// RefreshExpire is the refresh token lifetime used for "exp" (ms, 0 uses Expire) | 刷新令牌用于 exp 的有效期（毫秒，为 0 时使用 Expire）
+	RefreshExpire int64
+	// Clock is the time source of "iat" and "exp" (nil uses SystemClock) | iat 与 exp 的时间来源（为 nil 时使用 SystemClock）
+	Clock Clock
+	// Claims returns optional custom claims embedded at Encode time | 编码时附加的自定义声明
+	Claims func(ctx context.Context, userKey string) g.Map
+}
+
+// NewJWTCodec creates a JWTCodec; key is the HMAC secret ([]byte) or a crypto.Signer | 创建 JWTCodec，key 为 HMAC 密钥（[]byte）或 crypto.Signer
+func NewJWTCodec(alg string, key any, expire int64) (*JWTCodec, error) {
+	c := &JWTCodec{Alg: alg, Expire: expire}
+	switch k := key.(type) {
+	case []byte:
+		c.Secret = k
+	case crypto.Signer:
+		c.PrivateKey = k
+		c.PublicKey = k.Public()
+	case crypto.PublicKey:
+		c.PublicKey = k
+	}
+	if err := c.checkKey(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewJWTCodecByOptions creates a JWTCodec from Options | 根据配置创建 JWTCodec
+func NewJWTCodecByOptions(options Options) (*JWTCodec, error) {
+	c := &JWTCodec{Alg: options.JwtAlg, Issuer: options.JwtIssuer, Expire: options.jwtExpire(), RefreshExpire: options.RefreshTimeout}
+	if c.Alg == "" {
+		c.Alg = JwtAlgHS256
+	}
+	if c.Alg == JwtAlgHS256 {
+		c.Secret = options.JwtSecret
+	} else {
+		if options.JwtPrivateKey != "" {
+			signer, err := ParsePrivateKeyPEM([]byte(options.JwtPrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			c.PrivateKey, c.PublicKey = signer, signer.Public()
+		}
+		if options.JwtPublicKey != "" {
+			publicKey, err := ParsePublicKeyPEM([]byte(options.JwtPublicKey))
+			if err != nil {
+				return nil, err
+			}
+			c.PublicKey = publicKey
+		}
+	}
+	if err := c.checkKey(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Encode signs a JWT for the session key, refresh tokens expire after RefreshExpire | 为会话 key 签发 JWT，刷新令牌在 RefreshExpire 后过期
+func (c *JWTCodec) Encode(ctx context.Context, userKey string) (token string, err error) {
+	if userKey == "" {
+		return "", errors.New(MsgErrUserKeyEmpty)
+	}
+	if c.PrivateKey == nil && c.Alg != JwtAlgHS256 {
+		return "", errors.New(MsgErrJwtKey)
+	}
+
+	claims := g.Map{}
+	if c.Claims != nil {
+		for k, v := range c.Claims(ctx, userKey) {
+			claims[k] = v
+		}
+	}
+	sub, deviceId, _ := strings.Cut(userKey, DefaultDeviceDelimiter)
+	now, expire, use := c.now(), c.Expire, JwtUseAccess
+	if IsRefreshToken(ctx) {
+		use = JwtUseRefresh
+		if c.RefreshExpire > 0 {
+			expire = c.RefreshExpire
+		}
+	}
+	claims[JwtClaimSub] = sub
+	if deviceId != "" {
+		claims[JwtClaimDevice] = deviceId
+	}
+	claims[JwtClaimIat] = now
+	claims[JwtClaimExp] = now + expire/1000
+	claims[JwtClaimJti] = grand.S(32)
+	claims[JwtClaimUse] = use
+	if c.Issuer != "" {
+		claims[JwtClaimIss] = c.Issuer
+	}
+
+	header, err := gjson.Encode(g.Map{"alg": c.Alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := gjson.Encode(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload)
+	signature, err := c.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + jwtEncoding.EncodeToString(signature), nil
+}
+
+// Decrypt verifies JWT signature and expiry and returns the session key | 校验 JWT 签名与有效期并返回会话 key
+// Refresh tokens are only accepted when ctx decodes a refresh token, see IsRefreshToken | 仅在上下文解码刷新令牌时接受刷新令牌，参见 IsRefreshToken
+func (c *JWTCodec) Decrypt(ctx context.Context, token string) (userKey string, err error) {
+	claims, err := c.ParseClaims(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if refresh := gconv.String(claims[JwtClaimUse]) == JwtUseRefresh; refresh != IsRefreshToken(ctx) {
+		return "", errors.New(MsgErrJwtUse)
+	}
+	recordTokenId(ctx, token, gconv.String(claims[JwtClaimJti]))
+	userKey = gconv.String(claims[JwtClaimSub])
+	if userKey == "" {
+		return "", errors.New(MsgErrUserKeyEmpty)
+	}
+	if deviceId := gconv.String(claims[JwtClaimDevice]); deviceId != "" {
+		userKey = sessionKey(userKey, deviceId)
+	}
+	return userKey, nil
+}
+
+// ParseClaims verifies the JWT and returns all of its claims | 校验 JWT 并返回全部声明
+func (c *JWTCodec) ParseClaims(ctx context.Context, token string) (g.Map, error) {
+	if token == "" {
+		return nil, errors.New(MsgErrTokenEmpty)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+
+	// Check header algorithm to prevent algorithm confusion | 校验头部算法，防止算法混淆攻击
+	headerBytes, err := jwtEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+	header, err := gjson.DecodeToJson(headerBytes)
+	if err != nil {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+	if header.Get("alg").String() != c.Alg {
+		return nil, errors.New(MsgErrJwtAlg)
+	}
+
+	// Verify signature | 校验签名
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+	if err = c.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	// Decode claims and check expiry | 解析声明并校验过期时间
+	payload, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+	claimsJson, err := gjson.DecodeToJson(payload)
+	if err != nil {
+		return nil, errors.New(MsgErrJwtFormat)
+	}
+	claims := claimsJson.Map()
+	if exp, ok := claims[JwtClaimExp]; !ok || gconv.Int64(exp) < c.now() {
+		return nil, errors.New(MsgErrJwtExpired)
+	}
+	return claims, nil
+}
+
+// now returns the current time of Clock in seconds | 返回 Clock 的当前秒级时间戳
+func (c *JWTCodec) now() int64 {
+	if c.Clock == nil {
+		return SystemClock.Now().Unix()
+	}
+	return c.Clock.Now().Unix()
+}
+
+// checkKey ensures the configured key fits the algorithm | 校验密钥与算法匹配
+func (c *JWTCodec) checkKey() error {
+	switch c.Alg {
+	case JwtAlgHS256:
+		if len(c.Secret) == 0 {
+			return errors.New(MsgErrJwtKey)
+		}
+		return nil
+	case JwtAlgRS256:
+		if _, ok := c.PublicKey.(*rsa.PublicKey); ok {
+			return nil
+		}
+	case JwtAlgES256:
+		if k, ok := c.PublicKey.(*ecdsa.PublicKey); ok && k.Curve == elliptic.P256() {
+			return nil
+		}
+	case JwtAlgEdDSA:
+		if _, ok := c.PublicKey.(ed25519.PublicKey); ok {
+			return nil
+		}
+	default:
+		return errors.New(MsgErrJwtUnsupport)
+	}
+	return errors.New(MsgErrJwtKey)
+}
+
+// sign creates the JWS signature of signing input | 生成签名
+func (c *JWTCodec) sign(input []byte) ([]byte, error) {
+	switch c.Alg {
+	case JwtAlgHS256:
+		mac := hmac.New(sha256.New, c.Secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case JwtAlgRS256:
+		key, ok := c.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New(MsgErrJwtKey)
+		}
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case JwtAlgES256:
+		key, ok := c.PrivateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New(MsgErrJwtKey)
+		}
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses fixed-size big-endian R||S | JWS 要求定长大端 R||S
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	case JwtAlgEdDSA:
+		key, ok := c.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New(MsgErrJwtKey)
+		}
+		return ed25519.Sign(key, input), nil
+	}
+	return nil, errors.New(MsgErrJwtUnsupport)
+}
+
+// verify checks the JWS signature of signing input | 校验签名
+func (c *JWTCodec) verify(input, signature []byte) error {
+	valid := false
+	switch c.Alg {
+	case JwtAlgHS256:
+		mac := hmac.New(sha256.New, c.Secret)
+		mac.Write(input)
+		valid = hmac.Equal(signature, mac.Sum(nil))
+	case JwtAlgRS256:
+		if key, ok := c.PublicKey.(*rsa.PublicKey); ok {
+			digest := sha256.Sum256(input)
+			valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+		}
+	case JwtAlgES256:
+		if key, ok := c.PublicKey.(*ecdsa.PublicKey); ok && len(signature) == 64 {
+			digest := sha256.Sum256(input)
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			valid = ecdsa.Verify(key, digest[:], r, s)
+		}
+	case JwtAlgEdDSA:
+		if key, ok := c.PublicKey.(ed25519.PublicKey); ok {
+			valid = ed25519.Verify(key, input, signature)
+		}
+	default:
+		return errors.New(MsgErrJwtUnsupport)
+	}
+	if !valid {
+		return errors.New(MsgErrJwtSignature)
+	}
+	return nil
+}
+
+// ParsePrivateKeyPEM parses a PKCS#8, PKCS#1 or SEC1 private key | 解析 PKCS#8、PKCS#1 或 SEC1 格式私钥
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(MsgErrJwtKey)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errors.New(MsgErrJwtKey)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New(MsgErrJwtKey)
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 public key | 解析 PKIX 或 PKCS#1 格式公钥
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(MsgErrJwtKey)
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New(MsgErrJwtKey)
+}
@@ -0,0 +1,217 @@
+package dtoken
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWTCodec_Algorithms(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	keys := map[string]any{
+		JwtAlgHS256: []byte("secret"),
+		JwtAlgRS256: rsaKey,
+		JwtAlgES256: ecKey,
+		JwtAlgEdDSA: edKey,
+	}
+	for alg, key := range keys {
+		codec, err := NewJWTCodec(alg, key, 60*1000)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		codec.Claims = func(ctx context.Context, userKey string) g.Map {
+			return g.Map{"role": "admin"}
+		}
+
+		token, err := codec.Encode(ctx, sessionKey("alice", "web"))
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		cacheKey, err := codec.Decrypt(ctx, token)
+		if err != nil || cacheKey != sessionKey("alice", "web") {
+			t.Fatalf("%s: unexpected decrypt result %q %v", alg, cacheKey, err)
+		}
+		claims, err := codec.ParseClaims(ctx, token)
+		if err != nil || claims[JwtClaimSub] != "alice" || claims["role"] != "admin" || claims[JwtClaimJti] == "" {
+			t.Fatalf("%s: unexpected claims %v %v", alg, claims, err)
+		}
+
+		// Tampered payload must be rejected
+		parts := strings.Split(token, ".")
+		forged := parts[0] + "." + jwtEncoding.EncodeToString([]byte(`{"sub":"bob","exp":9999999999}`)) + "." + parts[2]
+		if _, err = codec.Decrypt(ctx, forged); err == nil {
+			t.Fatalf("%s: expected tampered token to be rejected", alg)
+		}
+	}
+}
+
+func TestJWTCodec_Expired(t *testing.T) {
+	ctx := context.Background()
+	codec, err := NewJWTCodec(JwtAlgHS256, []byte("secret"), -2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := codec.Encode(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = codec.Decrypt(ctx, token); err == nil || err.Error() != MsgErrJwtExpired {
+		t.Fatalf("expected expired error, got %v", err)
+	}
+}
+
+func TestJWTCodec_RefreshExpire(t *testing.T) {
+	ctx := context.Background()
+	var now atomic.Int64
+	now.Store(time.Now().UnixMilli())
+	clock := ClockFunc(func() time.Time { return time.UnixMilli(now.Load()) })
+
+	codec, err := NewJWTCodec(JwtAlgHS256, []byte("secret"), 60*1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec.RefreshExpire, codec.Clock = 3600*1000, clock
+	accessToken, err := codec.Encode(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken, err := codec.Encode(withRefreshToken(ctx), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now.Add(2 * 60 * 1000)
+	if _, err = codec.Decrypt(ctx, accessToken); err == nil || err.Error() != MsgErrJwtExpired {
+		t.Fatalf("expected access token to expire by the clock, got %v", err)
+	}
+	if _, err = codec.Decrypt(withRefreshToken(ctx), refreshToken); err != nil {
+		t.Fatalf("expected refresh token to outlive the access timeout, got %v", err)
+	}
+}
+
+func TestJWTCodec_TokenUse(t *testing.T) {
+	ctx := context.Background()
+	codec, err := NewJWTCodec(JwtAlgHS256, []byte("secret"), 60*1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec.Claims = func(ctx context.Context, userKey string) g.Map {
+		return g.Map{JwtClaimUse: JwtUseAccess}
+	}
+	accessToken, err := codec.Encode(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken, err := codec.Encode(withRefreshToken(ctx), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// External verifiers tell the tokens apart by token_use, custom claims cannot override it
+	for token, use := range map[string]string{accessToken: JwtUseAccess, refreshToken: JwtUseRefresh} {
+		claims, err := codec.ParseClaims(ctx, token)
+		if err != nil || claims[JwtClaimUse] != use {
+			t.Fatalf("expected token_use %q, got %v %v", use, claims[JwtClaimUse], err)
+		}
+	}
+	if _, err = codec.Decrypt(ctx, refreshToken); err == nil || err.Error() != MsgErrJwtUse {
+		t.Fatalf("expected refresh token rejected as access token, got %v", err)
+	}
+	if _, err = codec.Decrypt(withRefreshToken(ctx), accessToken); err == nil || err.Error() != MsgErrJwtUse {
+		t.Fatalf("expected access token rejected as refresh token, got %v", err)
+	}
+}
+
+func TestJWTCodec_Refresh(t *testing.T) {
+	ctx := context.Background()
+	var now atomic.Int64
+	now.Store(time.Now().UnixMilli())
+	clock := ClockFunc(func() time.Time { return time.UnixMilli(now.Load()) })
+
+	token, err := New(
+		WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":", CodecMode: CodecModeJWT, JwtSecret: []byte("secret"), Timeout: 60 * 1000, MaxRefreshTimes: 2}),
+		WithClock(clock),
+		WithBannerDisabled(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(ctx)
+	pair, err := token.GeneratePair(ctx, "alice", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each token only works for its own use
+	if _, err = token.Validate(ctx, pair.RefreshToken); !gerror.HasCode(err, CodeTokenInvalid) {
+		t.Fatalf("expected refresh JWT rejected as access token, got %v", err)
+	}
+	if _, err = token.Refresh(ctx, pair.AccessToken); !gerror.HasCode(err, CodeRefreshInvalid) {
+		t.Fatalf("expected access JWT rejected as refresh token, got %v", err)
+	}
+
+	// Sliding renewal keeps the access JWT usable past Timeout
+	renewed := make(chan struct{}, 1)
+	token.Subscribe(func(event *Event) { renewed <- struct{}{} }, EventRenewed)
+	now.Add(40 * 1000)
+	if _, err = token.Validate(ctx, pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-renewed:
+	case <-time.After(time.Second):
+		t.Fatal("expected session to be renewed")
+	}
+	now.Add(40 * 1000)
+	if _, err = token.Validate(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("expected renewed JWT to stay valid, got %v", err)
+	}
+
+	// The refresh JWT lives for RefreshTimeout
+	now.Add(10 * 60 * 1000)
+	if _, err = token.Refresh(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("expected refresh JWT to outlive the access timeout, got %v", err)
+	}
+}
+
+func TestJWTCodec_ByOptions(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	der, _ = x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	signer, err := NewJWTCodecByOptions(Options{JwtAlg: JwtAlgES256, JwtPrivateKey: string(privatePEM), Timeout: 60 * 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewJWTCodecByOptions(Options{JwtAlg: JwtAlgES256, JwtPublicKey: string(publicPEM), Timeout: 60 * 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signer.Encode(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userKey, err := verifier.Decrypt(context.Background(), token); err != nil || userKey != "alice" {
+		t.Fatalf("unexpected decrypt result %q %v", userKey, err)
+	}
+	if _, err = verifier.Encode(context.Background(), "alice"); err == nil {
+		t.Fatal("verify-only codec must not sign")
+	}
+}
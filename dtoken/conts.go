@@ -1,17 +1,28 @@
 package dtoken
 
 const (
-	GTokenCfgName = "gToken" // Global configuration node name for gToken | 全局配置文件中 gToken 节点名称
+	GTokenCfgName = "gToken"       // Global configuration node name for gToken | 全局配置文件中 gToken 节点名称
+	DefaultRealm  = "default"      // Realm of options set directly under the gToken node | 直接配置在 gToken 节点下的 Token 域名称
+	RealmCacheKey = "GTokenRealm:" // Cache key prefix of named realms without CachePreKey | 未配置 CachePreKey 的命名域缓存 key 前缀
 
-	CacheModeCache   = 1            // Cache mode using in-memory cache | 使用内存缓存的缓存模式
-	CacheModeRedis   = 2            // Cache mode using Redis | 使用 Redis 的缓存模式
-	CacheModeFile    = 3            // Cache mode using file | 使用文件存储的缓存模式
-	CacheModeFileDat = "gtoken.dat" // Default file storage for cache | 缓存文件的默认存储名称
+	CacheModeCache     = 1            // Cache mode using in-memory cache | 使用内存缓存的缓存模式
+	CacheModeRedis     = 2            // Cache mode using Redis | 使用 Redis 的缓存模式
+	CacheModeFile      = 3            // Cache mode using file | 使用文件存储的缓存模式
+	CacheModeRedisHash = 4            // Cache mode using Redis hashes with atomic renew | 使用 Redis 哈希并原子续期的缓存模式
+	CacheModeFileDat   = "gtoken.dat" // Default file storage for cache | 缓存文件的默认存储名称
+
+	CodecModeDefault = 1 // AES encrypted opaque token | AES 加密的不透明 Token
+	CodecModeJWT     = 2 // Signed JWT | 签名 JWT
+	CodecModeAEAD    = 3 // AES-GCM authenticated opaque token | AES-GCM 认证加密的不透明 Token
 
 	DefaultTimeout        = 10 * 24 * 60 * 60 * 1000           // Default timeout (10 days in milliseconds) | 默认超时时间（10天，单位毫秒）
 	DefaultCacheKey       = "GToken:"                          // Default prefix for cache keys | 默认缓存 key 前缀
 	DefaultTokenDelimiter = "_"                                // Default delimiter for tokens | Token 的默认分隔符
 	DefaultEncryptKey     = "12345678912345678912345678912345" // Default encryption key for token | 默认 Token 加密密钥
+	DefaultRefreshTimeout = 30 * 24 * 60 * 60 * 1000           // Default refresh token lifetime (30 days in milliseconds) | 默认刷新令牌有效期（30天，单位毫秒）
+	DefaultRefreshHistory = 64                                 // Rotated refresh tokens remembered for reuse detection | 用于重放检测的已轮换刷新令牌记录数
+	RefreshPreKey         = "refresh:"                         // Cache key prefix of refresh tokens | 刷新令牌的缓存 key 前缀
+	RevokePreKey          = "revoke:"                          // Cache key prefix of revocation list | 吊销列表的缓存 key 前缀
 
 	// Cache key fields | 缓存 key 字段定义
 	KeyUserKey       = "userKey"          // User identifier | 用户标识
@@ -20,12 +31,69 @@ const (
 	KeyLastRenewTime = "keyLastRenewTime" // Last token renewal time | 上次续期时间
 	KeyData          = "data"             // Custom data stored in cache | 缓存中的自定义数据
 	KeyToken         = "token"            // The actual token value | 实际的 token 值
+	KeyDeviceId      = "deviceId"         // Device identifier of the session | 会话所属设备标识
+	KeyRotateNum     = "rotateNum"        // Refresh token rotation count | 刷新令牌轮换次数
+	KeyUsedTokens    = "usedTokens"       // Hashes of rotated refresh tokens | 已轮换刷新令牌的摘要
+	KeyNotBefore     = "notBefore"        // Tokens issued before this time are revoked | 早于该时间签发的 Token 均被吊销
+	KeyRoles         = "roles"            // Roles granted to the session | 会话拥有的角色
+	KeyPermissions   = "permissions"      // Permissions granted to the session | 会话拥有的权限
+	KeyGrants        = "grants"           // Resolved grants stored in request context | 存入请求上下文的已解析权限
+
+	DefaultDeviceDelimiter = "#"         // Delimiter between userKey and deviceId in session keys | 会话 key 中用户标识与设备标识的分隔符
+	SessionIndexPreKey     = "sessions:" // Cache key prefix of per-user session index | 用户会话索引的缓存 key 前缀
+
+	DefaultTenantDelimiter = "|"        // Delimiter between tenantId and session key inside tokens | Token 内租户标识与会话 key 的分隔符
+	TenantPreKey           = "tenant:"  // Cache key prefix of tenant namespaces | 租户命名空间的缓存 key 前缀
+	KeyTenantId            = "tenantId" // Tenant of the session | 会话所属租户
+
+	SessionEvictOldest = 1 // Evict the oldest session when the limit is reached | 达到上限时踢出最早的会话
+	SessionEvictReject = 2 // Reject new logins when the limit is reached | 达到上限时拒绝新的登录
+
+	DefaultSessionPageSize = 20   // Default page size of SearchSessions | SearchSessions 的默认分页大小
+	MaxSessionPageSize     = 1000 // Maximum page size of SearchSessions | SearchSessions 的最大分页大小
+	DefaultScanCount       = 100  // Keys requested per Redis SCAN call | 每次 Redis SCAN 请求的 key 数量
+
+	RevokeTokenPreKey = "token:" // Revocation key prefix of single tokens | 单个 Token 吊销记录的 key 前缀
+	RevokeUserPreKey  = "user:"  // Revocation key prefix of per-user epochs | 用户吊销时间点的 key 前缀
+	RevokeGlobalKey   = "global" // Revocation key of the global epoch | 全局吊销时间点的 key
+	RevokeTokensKey   = "tokens" // Revocation key marking that single tokens are revoked | 标记存在单个 Token 吊销记录的 key
+
+	PermissionWildcard  = "*" // Wildcard matching any permission segment | 匹配任意权限段的通配符
+	PermissionDelimiter = ":" // Delimiter between permission segments | 权限段之间的分隔符
+
+	DefaultCookiePath     = "/"            // Default path of session cookies | 会话 Cookie 的默认路径
+	DefaultCsrfCookieName = "csrf_token"   // Default name of the CSRF cookie | CSRF Cookie 的默认名称
+	DefaultCsrfHeaderName = "X-CSRF-Token" // Default header echoing the CSRF token | 回传 CSRF Token 的默认请求头
+
+	CookieSameSiteLax    = "lax"    // SameSite=Lax | SameSite=Lax
+	CookieSameSiteStrict = "strict" // SameSite=Strict | SameSite=Strict
+	CookieSameSiteNone   = "none"   // SameSite=None, requires Secure | SameSite=None，要求 Secure
 )
 
 const (
-	MsgErrUserKeyEmpty = "userKey empty"       // Error message when userKey is empty | 用户标识为空时的错误信息
-	MsgErrTokenEmpty   = "token is empty"      // Error message when token is empty | Token 为空时的错误信息
-	MsgErrTokenLen     = "token len error"     // Error message when token length is incorrect | Token 长度不正确时的错误信息
-	MsgErrValidate     = "user validate error" // Error message for user validation failure | 用户验证失败时的错误信息
-	MsgErrDataEmpty    = "cache value is nil"  // Error message when cache value is nil | 缓存值为空时的错误信息
+	MsgErrUserKeyEmpty    = "userKey empty"                        // Error message when userKey is empty | 用户标识为空时的错误信息
+	MsgErrUserKeyInvalid  = "userKey invalid"                      // Error message when userKey contains the device delimiter, index or tenant prefix | 用户标识包含设备分隔符、索引前缀或租户前缀时的错误信息
+	MsgErrDeviceIdInvalid = "deviceId invalid"                     // Error message when deviceId contains the device delimiter | 设备标识包含设备分隔符时的错误信息
+	MsgErrTokenEmpty      = "token is empty"                       // Error message when token is empty | Token 为空时的错误信息
+	MsgErrTokenLen        = "token len error"                      // Error message when token length is incorrect | Token 长度不正确时的错误信息
+	MsgErrTokenInvalid    = "token invalid"                        // Error message when token is malformed or tampered | Token 格式错误或被篡改时的错误信息
+	MsgErrValidate        = "user validate error"                  // Error message for user validation failure | 用户验证失败时的错误信息
+	MsgErrDataEmpty       = "cache value is nil"                   // Error message when cache value is nil | 缓存值为空时的错误信息
+	MsgErrTokenExpired    = "token expired"                        // Error message when the session has expired or been destroyed | 会话已过期或已销毁时的错误信息
+	MsgErrSessionLimit    = "session limit reached"                // Error message when max sessions per user is reached | 用户会话数达到上限时的错误信息
+	MsgErrRefreshOff      = "refresh token disabled"               // Error message when refresh cache is not configured | 未配置刷新令牌缓存时的错误信息
+	MsgErrRefresh         = "refresh token invalid"                // Error message when refresh token is unknown or expired | 刷新令牌无效或已过期时的错误信息
+	MsgErrRefreshReuse    = "refresh token reused"                 // Error message when a rotated refresh token is presented again | 已轮换的刷新令牌被重复使用时的错误信息
+	MsgErrRevoked         = "token revoked"                        // Error message when token is in the revocation list | Token 已被吊销时的错误信息
+	MsgErrRevokeOff       = "revocation disabled"                  // Error message when revoke cache is not configured | 未配置吊销缓存时的错误信息
+	MsgErrForbidden       = "permission denied"                    // Error message when session lacks required roles or permissions | 会话缺少所需角色或权限时的错误信息
+	MsgErrCookieOff       = "cookie mode disabled"                 // Error message when CookieName is not configured | 未配置 CookieName 时的错误信息
+	MsgErrCsrf            = "csrf token invalid"                   // Error message when a cookie-authenticated unsafe request fails the CSRF check | Cookie 认证的非安全请求未通过 CSRF 校验时的错误信息
+	MsgErrTenant          = "tenant invalid"                       // Error message when tenantId is malformed or does not match the token | 租户标识格式错误或与 Token 不匹配时的错误信息
+	MsgErrTenantOff       = "tenant not supported"                 // Error message when the token does not implement TenantToken | Token 未实现 TenantToken 时的错误信息
+	MsgErrRealm           = "token realm not found"                // Error message when no realm is registered under the name | 未注册该名称的 Token 域时的错误信息
+	MsgErrReloadTenant    = "tenant options follow the root token" // Error message when updating options of a tenant instance | 更新租户实例配置时的错误信息
+	MsgErrWatchOff        = "config adapter cannot be watched"     // Error message when the config adapter is not file based | 配置适配器不基于文件时的错误信息
+	MsgErrSwapOff         = "cache cannot swap atomically"         // Error message when the cache does not implement Swapper | 缓存未实现 Swapper 时的错误信息
+	MsgErrScanOff         = "cache cannot be scanned"              // Error message when the cache does not implement Scanner | 缓存未实现 Scanner 时的错误信息
 )
@@ -0,0 +1,125 @@
+package dtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"net/http"
+)
+
+// NewTokenCookie builds the HttpOnly session cookie, Max-Age follows Timeout | 构建 HttpOnly 会话 Cookie，Max-Age 与 Timeout 一致
+func NewTokenCookie(options Options, token string) *http.Cookie {
+	return newCookie(options, options.CookieName, token, true)
+}
+
+// NewCsrfCookie builds the readable cookie carrying the CSRF token of token | 构建携带该 Token 对应 CSRF Token 的可读 Cookie
+func NewCsrfCookie(options Options, token string) *http.Cookie {
+	return newCookie(options, options.CsrfCookieName, CsrfToken(options, token), false)
+}
+
+// SessionCookies returns the session and CSRF cookies to issue on login | 返回登录时下发的会话 Cookie 与 CSRF Cookie
+func SessionCookies(options Options, token string) []*http.Cookie {
+	return []*http.Cookie{NewTokenCookie(options, token), NewCsrfCookie(options, token)}
+}
+
+// ExpiredSessionCookies returns cookies clearing the session and CSRF cookies | 返回用于清除会话 Cookie 与 CSRF Cookie 的 Cookie
+func ExpiredSessionCookies(options Options) []*http.Cookie {
+	cookies := SessionCookies(options, "")
+	for _, cookie := range cookies {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	return cookies
+}
+
+// SetSessionCookies writes the session and CSRF cookies to w | 向 w 写入会话 Cookie 与 CSRF Cookie
+func SetSessionCookies(w http.ResponseWriter, options Options, token string) {
+	for _, cookie := range SessionCookies(options, token) {
+		http.SetCookie(w, cookie)
+	}
+}
+
+// ClearSessionCookies expires the session and CSRF cookies in w | 在 w 中使会话 Cookie 与 CSRF Cookie 过期
+func ClearSessionCookies(w http.ResponseWriter, options Options) {
+	for _, cookie := range ExpiredSessionCookies(options) {
+		http.SetCookie(w, cookie)
+	}
+}
+
+// CsrfToken derives the CSRF token bound to a session token | 计算与会话 Token 绑定的 CSRF Token
+// It is an HMAC of the token, so a tossed CSRF cookie cannot match another session | 其为 Token 的 HMAC，被注入的 CSRF Cookie 无法匹配其他会话
+func CsrfToken(options Options, token string) string {
+	mac := hmac.New(sha256.New, options.EncryptKey)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newCookie builds a cookie with the configured attributes | 使用配置的属性构建 Cookie
+func newCookie(options Options, name, value string, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   options.CookieDomain,
+		Path:     options.CookiePath,
+		MaxAge:   int(options.Timeout / 1000),
+		Secure:   options.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteLaxMode,
+	}
+	switch options.CookieSameSite {
+	case CookieSameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+	case CookieSameSiteNone:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
+
+// logout destroys the session of the request, preferring the one stored by Auth | 销毁请求对应的会话，优先使用认证中间件存入的会话
+func logout(token Token, extractor TokenExtractor, c Carrier) error {
+	session, ok := SessionFromContext(c.Context())
+	if !ok {
+		if extractor == nil {
+			extractor = defaultExtractor
+		}
+		tokenStr, err := extractor.Extract(c)
+		if err != nil {
+			return err
+		}
+		if session, err = token.ValidateSession(c.Context(), tokenStr); err != nil {
+			return err
+		}
+	}
+	return token.DestroySession(c.Context(), session.UserKey, session.DeviceId)
+}
+
+// isCookieToken reports whether token was read from the session cookie | 判断 Token 是否来自会话 Cookie
+func isCookieToken(options Options, c Carrier, token string) bool {
+	return options.CookieName != "" && token != "" && c.Cookie(options.CookieName) == token
+}
+
+// checkCsrf applies the double-submit check to cookie-authenticated unsafe requests | 对 Cookie 认证的非安全请求执行双重提交校验
+func checkCsrf(options Options, c Carrier, token string) error {
+	switch c.Method() {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return nil
+	}
+	if !isCookieToken(options, c, token) {
+		return nil
+	}
+	header := c.Header(options.CsrfHeaderName)
+	if header == "" || !hmac.Equal([]byte(header), []byte(CsrfToken(options, token))) {
+		return gerror.NewCode(CodeCsrfInvalid, MsgErrCsrf)
+	}
+	return nil
+}
+
+// shouldRefreshCookie reports whether the session renews at now (ms), see canRenew | 判断会话在 now（毫秒）时是否续期，参见 canRenew
+// Cookies are then re-issued so Max-Age follows the renewed session | 此时重新下发 Cookie，使 Max-Age 跟随续期后的会话
+func shouldRefreshCookie(options Options, c Carrier, session *Session, now int64) bool {
+	if !isCookieToken(options, c, session.Token) {
+		return false
+	}
+	return canRenew(&options, now, session.CreateTime, session.LastRenewTime, session.RefreshNum)
+}
@@ -0,0 +1,189 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddleware_CookieMode(t *testing.T) {
+	ctx := context.Background()
+	token := NewDefaultToken(Options{
+		CachePreKey:    "Test:" + t.Name() + ":",
+		Timeout:        60 * 1000,
+		CookieName:     "sid",
+		CookieSameSite: "None",
+	})
+	t.Cleanup(func() { token.Shutdown(ctx) })
+	options := token.GetOptions()
+	if !options.CookieSecure {
+		t.Fatal("expected SameSite=None to force Secure")
+	}
+
+	middleware := NewHTTPMiddleware(token)
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logout" {
+			if err := middleware.Logout(w, r); err != nil {
+				t.Errorf("logout failed: %v", err)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Login issues HttpOnly session cookie and readable CSRF cookie
+	rec := httptest.NewRecorder()
+	userToken, err := middleware.Login(rec, httptest.NewRequest(http.MethodPost, "/login", nil), "alice", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	sid, csrf := cookies["sid"], cookies[DefaultCsrfCookieName]
+	if sid == nil || sid.Value != userToken || !sid.HttpOnly || !sid.Secure || sid.MaxAge != 60 || sid.SameSite != http.SameSiteNoneMode {
+		t.Fatalf("unexpected session cookie %+v", sid)
+	}
+	if csrf == nil || csrf.HttpOnly || csrf.Value != CsrfToken(options, userToken) {
+		t.Fatalf("unexpected csrf cookie %+v", csrf)
+	}
+
+	serve := func(method, path string, setup func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		setup(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	withCookies := func(r *http.Request) {
+		r.AddCookie(sid)
+		r.AddCookie(csrf)
+	}
+
+	if rec = serve(http.MethodGet, "/orders", withCookies); rec.Code != http.StatusOK {
+		t.Fatalf("expected cookie auth on GET, got %d", rec.Code)
+	}
+	if rec = serve(http.MethodPost, "/orders", withCookies); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected CSRF rejection, got %d", rec.Code)
+	}
+	if rec = serve(http.MethodPost, "/orders", func(r *http.Request) {
+		withCookies(r)
+		r.Header.Set(DefaultCsrfHeaderName, "forged")
+	}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected CSRF rejection for forged header, got %d", rec.Code)
+	}
+	if rec = serve(http.MethodPost, "/orders", func(r *http.Request) {
+		withCookies(r)
+		r.Header.Set(DefaultCsrfHeaderName, csrf.Value)
+	}); rec.Code != http.StatusOK {
+		t.Fatalf("expected CSRF pass, got %d", rec.Code)
+	}
+	if rec = serve(http.MethodPost, "/orders", func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+userToken)
+	}); rec.Code != http.StatusOK {
+		t.Fatalf("expected header token to skip CSRF, got %d", rec.Code)
+	}
+
+	// Logout destroys the session and expires both cookies
+	rec = serve(http.MethodGet, "/logout", withCookies)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected logout, got %d", rec.Code)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge >= 0 || cookie.Value != "" {
+			t.Fatalf("expected expired cookie, got %+v", cookie)
+		}
+	}
+	if rec = serve(http.MethodGet, "/orders", withCookies); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected destroyed session, got %d", rec.Code)
+	}
+}
+
+func TestShouldRefreshCookie(t *testing.T) {
+	options := Options{CookieName: "sid", Timeout: 60 * 1000, MaxRefresh: 30 * 1000}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "t"})
+	c := NewHTTPCarrier(req)
+
+	now := gtime.Now().TimestampMilli()
+	fresh := &Session{Token: "t", CreateTime: now}
+	if shouldRefreshCookie(options, c, fresh, now) {
+		t.Fatal("fresh session should not refresh cookie")
+	}
+	aged := &Session{Token: "t", CreateTime: now - 40*1000}
+	if !shouldRefreshCookie(options, c, aged, now) {
+		t.Fatal("session in renew window should refresh cookie")
+	}
+	if shouldRefreshCookie(options, c, &Session{Token: "other", CreateTime: aged.CreateTime}, now) {
+		t.Fatal("header token should not refresh cookie")
+	}
+
+	// Cookies follow the renew limits of the session
+	limited := options
+	limited.MaxRefreshTimes, limited.RenewInterval = 2, 50*1000
+	if shouldRefreshCookie(limited, c, &Session{Token: "t", CreateTime: aged.CreateTime, RefreshNum: 2}, now) {
+		t.Fatal("session out of renewals should not refresh cookie")
+	}
+	if shouldRefreshCookie(limited, c, &Session{Token: "t", CreateTime: aged.CreateTime - 60*1000, LastRenewTime: aged.CreateTime, RefreshNum: 1}, now) {
+		t.Fatal("session within RenewInterval should not refresh cookie")
+	}
+	if !shouldRefreshCookie(limited, c, &Session{Token: "t", CreateTime: aged.CreateTime, RefreshNum: 1}, now+20*1000) {
+		t.Fatal("renewable session should refresh cookie")
+	}
+
+	// The token clock decides the renew window
+	clock := ClockFunc(func() time.Time { return time.UnixMilli(now + 40*1000) })
+	token := newTestToken(t, Options{})
+	token.Clock = clock
+	if !shouldRefreshCookie(options, c, fresh, tokenNow(token)) {
+		t.Fatal("session should enter the renew window by the token clock")
+	}
+}
+
+// countingToken counts ValidateSession calls of the wrapped token
+type countingToken struct {
+	Token
+	validations int
+}
+
+func (c *countingToken) ValidateSession(ctx context.Context, token string) (*Session, error) {
+	c.validations++
+	return c.Token.ValidateSession(ctx, token)
+}
+
+func TestAuthenticate_CsrfBeforeValidate(t *testing.T) {
+	ctx := context.Background()
+	inner, err := New(WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":", CookieName: "sid"}), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { inner.Shutdown(ctx) })
+	token := &countingToken{Token: inner}
+	extractor := mustExtractorByOptions(token.GetOptions())
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A forged cookie request fails before the session is validated and renewed
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: userToken})
+	if _, _, err = Authenticate(token, extractor, nil, NewHTTPCarrier(req)); !gerror.HasCode(err, CodeCsrfInvalid) {
+		t.Fatalf("expected csrf error, got %v", err)
+	}
+	if token.validations != 0 {
+		t.Fatalf("expected no validation, got %d", token.validations)
+	}
+
+	req.Header.Set(DefaultCsrfHeaderName, CsrfToken(token.GetOptions(), userToken))
+	if _, _, err = Authenticate(token, extractor, nil, NewHTTPCarrier(req)); err != nil {
+		t.Fatal(err)
+	}
+	if token.validations != 1 {
+		t.Fatalf("expected one validation, got %d", token.validations)
+	}
+}
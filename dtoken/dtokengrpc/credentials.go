@@ -0,0 +1,40 @@
+package dtokengrpc
+
+import (
+	"context"
+	"google.golang.org/grpc/credentials"
+)
+
+// PerRPCCredentials attaches a token to every call as "authorization: Bearer <token>" | 以 "authorization: Bearer <token>" 形式为每次调用附加 Token
+type PerRPCCredentials struct {
+	TokenSource   func(ctx context.Context) (string, error) // Returns the token of the call | 返回本次调用使用的 Token
+	AllowInsecure bool                                      // Allow sending over plaintext connections | 允许通过明文连接发送
+}
+
+// NewPerRPCCredentials creates credentials with a fixed token, TLS is required | 使用固定 Token 创建凭证，要求 TLS 连接
+func NewPerRPCCredentials(token string) *PerRPCCredentials {
+	return &PerRPCCredentials{
+		TokenSource: func(ctx context.Context) (string, error) {
+			return token, nil
+		},
+	}
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials | 实现 credentials.PerRPCCredentials 接口
+func (c *PerRPCCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.TokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials | 实现 credentials.PerRPCCredentials 接口
+func (c *PerRPCCredentials) RequireTransportSecurity() bool {
+	return !c.AllowInsecure
+}
+
+var _ credentials.PerRPCCredentials = (*PerRPCCredentials)(nil)
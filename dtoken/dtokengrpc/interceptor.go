@@ -0,0 +1,175 @@
+// Package dtokengrpc provides gRPC interceptors and credentials for dtoken | 为 dtoken 提供 gRPC 拦截器与凭证
+package dtokengrpc
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// Interceptor authenticates gRPC calls with dtoken sessions | 使用 dtoken 会话认证 gRPC 调用
+type Interceptor struct {
+	Token          dtoken.Token          // Token instance | Token 实例
+	Authorizer     *dtoken.Authorizer    // Role and permission checks, rule paths are full method names (nil skips authorization) | 角色与权限校验，规则路径为完整方法名（为 nil 时跳过授权）
+	ExcludeMethods []string              // Full method names excluded, "/pkg.Service/*" for a whole service | 免认证的完整方法名，"/pkg.Service/*" 表示整个服务
+	Extractor      dtoken.TokenExtractor // Token extraction chain over metadata (nil uses DefaultTokenLookup) | 基于元数据的 Token 提取链（为 nil 时使用 DefaultTokenLookup）
+	ExcludeRules   *dtoken.PathRules     // Compiled ExcludeMethods (nil scans ExcludeMethods) | 编译后的 ExcludeMethods（为 nil 时逐条匹配 ExcludeMethods）
+	TenantResolver dtoken.TenantResolver // Tenant resolution chain over metadata, host reads ":authority" (nil disables tenant scoping) | 基于元数据的租户解析链，host 读取 ":authority"（为 nil 时不按租户隔离）
+}
+
+// NewInterceptor creates an interceptor from token options | 根据 Token 配置创建拦截器
+// ExcludeMethods defaults to AuthExcludeRpcs, authorization is enabled when AuthRules are configured | ExcludeMethods 默认为 AuthExcludeRpcs，配置了 AuthRules 时启用授权
+// Header entries of TokenLookup are read from metadata, cookie and param entries never match | TokenLookup 中的 header 项从元数据读取，cookie 与 param 项不会命中
+// Like the HTTP middlewares it panics on invalid config | 与 HTTP 中间件一样，配置错误时 panic
+func NewInterceptor(token dtoken.Token) *Interceptor {
+	options := token.GetOptions()
+	i := &Interceptor{
+		Token:          token,
+		ExcludeMethods: options.AuthExcludeRpcs,
+	}
+	var err error
+	i.Extractor, err = dtoken.NewExtractorByOptions(options)
+	mustConfig(err, "TokenLookup")
+	i.TenantResolver, err = dtoken.NewTenantResolverByOptions(options)
+	mustConfig(err, "TenantLookup")
+	i.ExcludeRules, err = dtoken.NewPathRules(options.AuthExcludeRpcs, nil)
+	mustConfig(err, "AuthExcludeRpcs")
+	if len(options.AuthRules) > 0 {
+		i.Authorizer, err = dtoken.NewAuthorizerByOptions(options)
+		mustConfig(err, "AuthRules")
+	}
+	return i
+}
+
+// mustConfig panics when field of the options is invalid | 配置项 field 错误时 panic
+func mustConfig(err error, field string) {
+	if err != nil {
+		panic("invalid config: " + err.Error() + " | " + field + " 配置错误")
+	}
+}
+
+// Unary returns the unary server interceptor | 返回一元调用服务端拦截器
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the streaming server interceptor | 返回流式调用服务端拦截器
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate validates metadata token and returns ctx carrying the session | 校验元数据中的 Token 并返回携带会话的上下文
+func (i *Interceptor) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	// Skip authentication if method is excluded | 方法在排除列表中则跳过认证
+	if i.ExcludeRules != nil {
+		if i.ExcludeRules.IsExcluded(http.MethodPost, fullMethod) {
+			return ctx, nil
+		}
+	} else if dtoken.IsExcludePath(i.ExcludeMethods, fullMethod) {
+		return ctx, nil
+	}
+
+	// Scope token to the tenant of the call | 将 Token 限定在调用所属租户内
+	md, _ := metadata.FromIncomingContext(ctx)
+	carrier := &metadataCarrier{ctx: ctx, md: md, fullMethod: fullMethod}
+	token, tenantId, err := dtoken.ResolveTenant(i.Token, i.TenantResolver, carrier)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	// Validate token and check roles and permissions | 校验 Token 合法性及角色权限
+	session, grants, err := dtoken.Authenticate(token, i.Extractor, i.Authorizer, carrier)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	ctx = dtoken.WithSession(ctx, session, grants)
+	if tenantId != "" {
+		ctx = dtoken.WithTenant(ctx, tenantId)
+	}
+	return ctx, nil
+}
+
+// statusError converts an authentication failure to a gRPC status error | 将认证失败原因转换为 gRPC 状态错误
+func statusError(err error) error {
+	switch dtoken.ErrorStatus(err) {
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, dtoken.ErrorCode(err).Message())
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, dtoken.ErrorCode(err).Message())
+	default:
+		return status.Error(codes.Internal, dtoken.ErrorCode(err).Message())
+	}
+}
+
+// metadataCarrier adapts incoming gRPC metadata to dtoken.Carrier | 将 gRPC 入站元数据适配为 dtoken.Carrier
+type metadataCarrier struct {
+	ctx        context.Context
+	md         metadata.MD
+	fullMethod string
+}
+
+// Method implements dtoken.Carrier, gRPC calls are HTTP/2 POST | 实现 dtoken.Carrier 接口，gRPC 调用均为 HTTP/2 POST
+func (c *metadataCarrier) Method() string {
+	return http.MethodPost
+}
+
+// Path implements dtoken.Carrier with the full method name | 以完整方法名实现 dtoken.Carrier 接口
+func (c *metadataCarrier) Path() string {
+	return c.fullMethod
+}
+
+// Host implements dtoken.Carrier with the ":authority" pseudo header | 以 ":authority" 伪首部实现 dtoken.Carrier 接口
+func (c *metadataCarrier) Host() string {
+	return c.Header(":authority")
+}
+
+// Header implements dtoken.Carrier | 实现 dtoken.Carrier 接口
+func (c *metadataCarrier) Header(key string) string {
+	if values := c.md.Get(strings.ToLower(key)); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Param implements dtoken.Carrier, gRPC has no request parameters | 实现 dtoken.Carrier 接口，gRPC 没有请求参数
+func (c *metadataCarrier) Param(key string) string {
+	return ""
+}
+
+// Cookie implements dtoken.Carrier, gRPC has no cookies | 实现 dtoken.Carrier 接口，gRPC 没有 Cookie
+func (c *metadataCarrier) Cookie(name string) string {
+	return ""
+}
+
+// Context implements dtoken.Carrier | 实现 dtoken.Carrier 接口
+func (c *metadataCarrier) Context() context.Context {
+	return c.ctx
+}
+
+// authenticatedStream overrides the stream context | 替换流的上下文
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the session | 返回携带会话的上下文
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
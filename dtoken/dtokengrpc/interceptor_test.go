@@ -0,0 +1,132 @@
+package dtokengrpc
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/Zany2/dtoken/dtoken/dtokentest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestInterceptor_UnaryAndStream(t *testing.T) {
+	ctx := context.Background()
+	token := dtoken.NewDefaultToken(dtoken.Options{
+		CachePreKey:     "Test:" + t.Name() + ":",
+		AuthExcludeRpcs: []string{"/grpc.health.v1.Health/List"},
+		AuthRules:       []dtoken.AuthRule{{Path: "/grpc.health.v1.Health/Watch", Roles: []string{"admin"}}},
+	})
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	var (
+		mu       sync.Mutex
+		userKeys []string
+	)
+	capture := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if userKey, ok := dtoken.UserKeyFromContext(ctx); ok {
+			mu.Lock()
+			userKeys = append(userKeys, userKey)
+			mu.Unlock()
+		}
+		return handler(ctx, req)
+	}
+
+	interceptor := NewInterceptor(token)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptor.Unary(), capture),
+		grpc.ChainStreamInterceptor(interceptor.Stream()),
+	)
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	dial := func(token string) grpc_health_v1.HealthClient {
+		options := []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+		if token != "" {
+			credentials := NewPerRPCCredentials(token)
+			credentials.AllowInsecure = true
+			options = append(options, grpc.WithPerRPCCredentials(credentials))
+		}
+		conn, err := grpc.NewClient("passthrough:///bufnet", options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return grpc_health_v1.NewHealthClient(conn)
+	}
+
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := token.GenerateWithGrants(ctx, "root", "", nil, &dtoken.Grants{Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unary
+	if _, err = dial("").Check(ctx, &grpc_health_v1.HealthCheckRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+	if _, err = dial("bad").Check(ctx, &grpc_health_v1.HealthCheckRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+	if _, err = dial(userToken).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected authenticated call, got %v", err)
+	}
+	if _, err = dial("").List(ctx, &grpc_health_v1.HealthListRequest{}); err != nil {
+		t.Fatalf("expected excluded method, got %v", err)
+	}
+	mu.Lock()
+	if len(userKeys) != 1 || userKeys[0] != "alice" {
+		t.Fatalf("expected userKey in context, got %v", userKeys)
+	}
+	mu.Unlock()
+
+	// Stream
+	stream, err := dial(userToken).Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	stream, err = dial(adminToken).Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatalf("expected stream response, got %v", err)
+	}
+}
+
+func TestNewInterceptor_InvalidConfig(t *testing.T) {
+	for _, options := range []dtoken.Options{
+		{AuthExcludeRpcs: []string{"~/grpc.health.v1.Health/("}},
+		{TenantLookup: []string{"path:x"}},
+		{TokenLookup: []string{"query:token"}},
+		{AuthRules: []dtoken.AuthRule{{Path: "~/grpc.health.v1.Health/("}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected invalid config to panic: %+v", options)
+				}
+			}()
+			NewInterceptor(dtokentest.NewToken(options))
+		}()
+	}
+}
@@ -0,0 +1,124 @@
+package dtokentest
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Cache operations recorded by Cache | Cache 记录的操作类型
+const (
+	OpSet    = "set"
+	OpGet    = "get"
+	OpRemove = "remove"
+)
+
+// CacheOp is one recorded cache call | 一次被记录的缓存调用
+type CacheOp struct {
+	Op    string // OpSet, OpGet or OpRemove | 操作类型
+	Key   string // Cache key | 缓存 key
+	Value g.Map  // Value set or returned (nil on miss and remove) | 设置或返回的值（未命中与删除时为 nil）
+}
+
+// Cache is an in-memory dtoken.Cache recording every call, entries never expire | 记录每次调用的内存缓存，条目不会过期
+type Cache struct {
+	mu   sync.Mutex
+	data map[string]g.Map
+	ops  []CacheOp
+	Err  error // Returned by every call when set | 设置后所有调用均返回该错误
+}
+
+// NewCache creates a recording cache | 创建记录调用的缓存
+func NewCache() *Cache {
+	return &Cache{data: make(map[string]g.Map)}
+}
+
+// Set implements dtoken.Cache | 实现 dtoken.Cache 接口
+func (c *Cache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value := gconv.Map(cacheValue, gconv.MapOption{Deep: true})
+	c.ops = append(c.ops, CacheOp{Op: OpSet, Key: cacheKey, Value: value})
+	if c.Err != nil {
+		return c.Err
+	}
+	c.data[cacheKey] = value
+	return nil
+}
+
+// Get implements dtoken.Cache | 实现 dtoken.Cache 接口
+func (c *Cache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		c.ops = append(c.ops, CacheOp{Op: OpGet, Key: cacheKey})
+		return nil, c.Err
+	}
+	value := gconv.Map(c.data[cacheKey], gconv.MapOption{Deep: true})
+	c.ops = append(c.ops, CacheOp{Op: OpGet, Key: cacheKey, Value: value})
+	return value, nil
+}
+
+// Remove implements dtoken.Cache | 实现 dtoken.Cache 接口
+func (c *Cache) Remove(ctx context.Context, cacheKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ops = append(c.ops, CacheOp{Op: OpRemove, Key: cacheKey})
+	if c.Err != nil {
+		return c.Err
+	}
+	delete(c.data, cacheKey)
+	return nil
+}
+
+// Scan implements dtoken.Scanner in key order, calls are not recorded | 按 key 顺序实现 dtoken.Scanner 接口，不记录调用
+func (c *Cache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	c.mu.Lock()
+	err := c.Err
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, key := range c.Keys() {
+		if strings.HasPrefix(key, prefix) && !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Ops returns the recorded calls in order | 按顺序返回记录的调用
+func (c *Cache) Ops() []CacheOp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CacheOp(nil), c.ops...)
+}
+
+// Keys returns the keys currently stored in order | 按顺序返回当前存储的 key
+func (c *Cache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Reset drops stored entries and recorded calls | 清空存储的条目与记录的调用
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = make(map[string]g.Map)
+	c.ops = nil
+}
+
+var (
+	_ dtoken.Cache   = (*Cache)(nil)
+	_ dtoken.Scanner = (*Cache)(nil)
+)
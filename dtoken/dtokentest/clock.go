@@ -0,0 +1,39 @@
+// Package dtokentest provides test helpers for code using dtoken | 为使用 dtoken 的代码提供测试辅助工具
+package dtokentest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a dtoken.Clock that only moves when told to | 仅在手动推进时变化的 dtoken.Clock
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a fake clock starting at now | 创建从 now 开始的模拟时钟
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements dtoken.Clock | 实现 dtoken.Clock 接口
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d | 将时钟向前推进 d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to now | 将时钟设置为 now
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,78 @@
+package dtokentest
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"testing"
+	"time"
+)
+
+func TestFakeClock_RenewAndExpire(t *testing.T) {
+	ctx := context.Background()
+	clock := NewFakeClock(time.UnixMilli(1700000000000))
+	token, err := dtoken.New(
+		dtoken.WithOptions(dtoken.Options{
+			CachePreKey:     "Test:" + t.Name() + ":",
+			Timeout:         10000,
+			MaxRefresh:      5000,
+			MaxRefreshTimes: 1,
+		}),
+		dtoken.WithClock(clock),
+		dtoken.WithBannerDisabled(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(ctx)
+
+	renewed := make(chan *dtoken.Event, 1)
+	token.Subscribe(func(event *dtoken.Event) { renewed <- event }, dtoken.EventRenewed)
+
+	accessToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside the refresh window nothing is renewed | 未进入续期窗口时不续期
+	clock.Advance(4 * time.Second)
+	if _, err = token.Validate(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Inside the window the session is renewed at clock time | 进入续期窗口后按时钟时间续期
+	clock.Advance(2 * time.Second)
+	if _, err = token.Validate(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-renewed:
+		if event.Time != clock.Now().UnixMilli() {
+			t.Fatalf("expected renew at clock time, got %d", event.Time)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected session to be renewed")
+	}
+	sessions, err := token.ListSessions(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].RefreshNum != 1 || sessions[0].LastRenewTime != clock.Now().UnixMilli() {
+		t.Fatalf("unexpected session after renew: %+v", sessions)
+	}
+
+	// MaxRefreshTimes reached, the session expires a Timeout after the renewal | 达到最大续期次数后，会话在续期后 Timeout 过期
+	clock.Advance(9 * time.Second)
+	if _, err = token.Validate(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-renewed:
+		t.Fatal("expected no renewal after MaxRefreshTimes")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if _, err = token.Validate(ctx, accessToken); !gerror.HasCode(err, dtoken.CodeTokenExpired) {
+		t.Fatalf("expected expired session, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package dtokentest
+
+import (
+	"context"
+	"errors"
+	"github.com/Zany2/dtoken/dtoken"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodecPrefix prefixes every token issued by Codec | Codec 签发的 Token 前缀
+const CodecPrefix = "test:"
+
+// Codec is a deterministic dtoken.Codec issuing readable tokens "test:<userKey>:<n>" | 签发可读 Token "test:<userKey>:<n>" 的确定性编解码器
+// n counts the tokens issued for userKey starting from 1 | n 为该用户标识已签发的 Token 序号，从 1 开始
+type Codec struct {
+	mu  sync.Mutex
+	seq map[string]int
+}
+
+// NewCodec creates a deterministic codec | 创建确定性编解码器
+func NewCodec() *Codec {
+	return &Codec{seq: make(map[string]int)}
+}
+
+// Encode implements dtoken.Encoder | 实现 dtoken.Encoder 接口
+func (c *Codec) Encode(ctx context.Context, userKey string) (string, error) {
+	if userKey == "" {
+		return "", errors.New(dtoken.MsgErrUserKeyEmpty)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq[userKey]++
+	return CodecPrefix + userKey + ":" + strconv.Itoa(c.seq[userKey]), nil
+}
+
+// Decrypt implements dtoken.Decoder | 实现 dtoken.Decoder 接口
+func (c *Codec) Decrypt(ctx context.Context, token string) (string, error) {
+	body, ok := strings.CutPrefix(token, CodecPrefix)
+	if !ok {
+		return "", errors.New(dtoken.MsgErrTokenInvalid)
+	}
+	i := strings.LastIndex(body, ":")
+	if i <= 0 {
+		return "", errors.New(dtoken.MsgErrTokenInvalid)
+	}
+	if _, err := strconv.Atoi(body[i+1:]); err != nil {
+		return "", errors.New(dtoken.MsgErrTokenInvalid)
+	}
+	return body[:i], nil
+}
+
+var _ dtoken.Codec = (*Codec)(nil)
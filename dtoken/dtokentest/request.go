@@ -0,0 +1,57 @@
+package dtokentest
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/guid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// SetBearer sets "Authorization: Bearer <token>" on req | 为请求设置 "Authorization: Bearer <token>"
+func SetBearer(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// NewRequest creates an httptest request carrying a fresh token of userKey issued by token | 创建携带 token 为 userKey 新签发 Token 的 httptest 请求
+func NewRequest(t testing.TB, token dtoken.Token, method, target, userKey string) *http.Request {
+	t.Helper()
+	accessToken, err := token.Generate(context.Background(), userKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, target, nil)
+	SetBearer(req, accessToken)
+	return req
+}
+
+// NewGHttpServer starts a ghttp server on a random local port with routes bound behind the token middleware | 在随机本地端口启动 ghttp 服务，bind 注册的路由位于 Token 中间件之后
+// Requests are served in process by calling ServeHTTP, the server is shut down on test cleanup | 通过 ServeHTTP 在进程内处理请求，测试结束时关闭服务
+func NewGHttpServer(t testing.TB, token dtoken.Token, bind func(group *ghttp.RouterGroup)) *ghttp.Server {
+	t.Helper()
+	s := ghttp.GetServer("dtokentest-" + guid.S())
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(dtoken.NewDefaultMiddleware(token).Auth)
+		bind(group)
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown() })
+	return s
+}
+
+// ServeGHttp serves req with handler behind the token middleware and records the response | 使用位于 Token 中间件之后的 handler 处理请求并记录响应
+func ServeGHttp(t testing.TB, token dtoken.Token, req *http.Request, handler ghttp.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	s := NewGHttpServer(t, token, func(group *ghttp.RouterGroup) {
+		group.ALL("/*", handler)
+	})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
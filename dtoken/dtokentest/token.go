@@ -0,0 +1,501 @@
+package dtokentest
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"sort"
+	"sync"
+)
+
+// RenewCall is one recorded Renew call | 一次被记录的 Renew 调用
+type RenewCall struct {
+	UserKey string // Session key passed to Renew | 传入 Renew 的会话 key
+	Token   string // Token of the renewed session | 被续期会话的 Token
+}
+
+// refreshEntry is the refresh token family of a session | 会话的刷新令牌族
+type refreshEntry struct {
+	userKey  string
+	deviceId string
+	data     any
+	token    string              // Current refresh token | 当前刷新令牌
+	used     map[string]struct{} // Rotated refresh tokens | 已轮换的刷新令牌
+}
+
+// Token is an in-memory dtoken.Token for tests, without renew pool, banner or global cache | 用于测试的内存 Token，无续期协程池、Banner 与全局缓存
+// Renewals run synchronously and sessions expire by Clock | 续期同步执行，会话按 Clock 过期
+type Token struct {
+	Options dtoken.Options // Options returned by GetOptions, Timeout/MaxRefresh/MaxRefreshTimes/MultiLogin are honored | GetOptions 返回的配置，支持 Timeout/MaxRefresh/MaxRefreshTimes/MultiLogin
+	Codec   dtoken.Codec   // Token codec | Token 编解码器
+	Clock   dtoken.Clock   // Time source of expiry and renewal | 过期与续期的时间来源
+
+	mu         sync.Mutex
+	sessions   map[string]*dtoken.Session // Sessions by session key | 按会话 key 存储的会话
+	refresh    map[string]*refreshEntry   // Refresh families by session key | 按会话 key 存储的刷新令牌族
+	revoked    map[string]struct{}        // Revoked tokens | 已吊销的 Token
+	issued     []string
+	renewCalls []RenewCall
+	shutdown   bool
+	events     *dtoken.EventBus
+}
+
+// NewToken creates a fake token, zero Timeout and RefreshTimeout use defaults | 创建模拟 Token，Timeout 与 RefreshTimeout 为 0 时使用默认值
+func NewToken(options dtoken.Options) *Token {
+	if options.Timeout <= 0 {
+		options.Timeout = dtoken.DefaultTimeout
+	}
+	if options.RefreshTimeout <= 0 {
+		options.RefreshTimeout = dtoken.DefaultRefreshTimeout
+	}
+	return &Token{
+		Options:  options,
+		Codec:    NewCodec(),
+		Clock:    dtoken.SystemClock,
+		sessions: make(map[string]*dtoken.Session),
+		refresh:  make(map[string]*refreshEntry),
+		revoked:  make(map[string]struct{}),
+		events:   dtoken.NewEventBus(),
+	}
+}
+
+// IssuedTokens returns every access token issued so far in order | 按顺序返回已签发的所有访问令牌
+func (t *Token) IssuedTokens() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.issued...)
+}
+
+// RenewCalls returns every Renew call in order, including those triggered by Validate | 按顺序返回所有 Renew 调用，包括 Validate 触发的续期
+func (t *Token) RenewCalls() []RenewCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]RenewCall(nil), t.renewCalls...)
+}
+
+// IsShutdown reports whether Shutdown was called | 判断是否已调用 Shutdown
+func (t *Token) IsShutdown() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shutdown
+}
+
+// Generate implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Generate(ctx context.Context, userKey string, data any) (string, error) {
+	return t.GenerateWithGrants(ctx, userKey, "", data, nil)
+}
+
+// GenerateWithDevice implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) GenerateWithDevice(ctx context.Context, userKey, deviceId string, data any) (string, error) {
+	return t.GenerateWithGrants(ctx, userKey, deviceId, data, nil)
+}
+
+// GenerateWithGrants implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) GenerateWithGrants(ctx context.Context, userKey, deviceId string, data any, grants *dtoken.Grants) (string, error) {
+	if userKey == "" {
+		return "", gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	t.mu.Lock()
+	token, event, err := t.generate(ctx, userKey, deviceId, data, grants, t.Options.MultiLogin)
+	t.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	t.publish(ctx, event)
+	return token, nil
+}
+
+// generate creates or reuses a session, t.mu must be held | 创建或重用会话，需持有 t.mu
+func (t *Token) generate(ctx context.Context, userKey, deviceId string, data any, grants *dtoken.Grants, reuse bool) (string, *dtoken.Event, error) {
+	cacheKey := sessionKey(userKey, deviceId)
+	if session := t.session(cacheKey); session != nil && reuse {
+		if grants != nil {
+			session.Roles, session.Permissions = grants.Roles, grants.Permissions
+		}
+		return session.Token, nil, nil
+	}
+
+	token, err := t.Codec.Encode(ctx, cacheKey)
+	if err != nil {
+		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	session := &dtoken.Session{
+		UserKey:    userKey,
+		DeviceId:   deviceId,
+		Token:      token,
+		Data:       data,
+		CreateTime: t.Clock.Now().UnixMilli(),
+	}
+	if grants != nil {
+		session.Roles, session.Permissions = grants.Roles, grants.Permissions
+	}
+	t.sessions[cacheKey] = session
+	t.issued = append(t.issued, token)
+	return token, &dtoken.Event{Type: dtoken.EventGenerated, UserKey: userKey, DeviceId: deviceId, CreateTime: session.CreateTime}, nil
+}
+
+// SetGrants implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) SetGrants(ctx context.Context, userKey, deviceId string, grants *dtoken.Grants) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	session := t.session(sessionKey(userKey, deviceId))
+	if session == nil {
+		return gerror.NewCode(dtoken.CodeTokenExpired, dtoken.MsgErrTokenExpired)
+	}
+	session.Roles, session.Permissions = nil, nil
+	if grants != nil {
+		session.Roles, session.Permissions = grants.Roles, grants.Permissions
+	}
+	return nil
+}
+
+// Validate implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Validate(ctx context.Context, token string) (any, error) {
+	session, err := t.ValidateSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return session.Data, nil
+}
+
+// ValidateSession implements dtoken.Token, renewing synchronously inside the refresh window | 实现 dtoken.Token 接口，进入续期窗口时同步续期
+func (t *Token) ValidateSession(ctx context.Context, token string) (*dtoken.Session, error) {
+	t.mu.Lock()
+	session, err := t.validate(ctx, token)
+	if err != nil {
+		t.mu.Unlock()
+		t.publish(ctx, &dtoken.Event{Type: dtoken.EventValidationFailed, Err: err, Reason: gerror.Cause(err).Error()})
+		return nil, err
+	}
+	result := *session
+	result.ExpireTime = refTime(session) + t.Options.Timeout
+	renew := t.shouldRenew(session)
+	t.mu.Unlock()
+
+	t.publish(ctx, &dtoken.Event{Type: dtoken.EventValidated, UserKey: result.UserKey, DeviceId: result.DeviceId, CreateTime: result.CreateTime})
+	if renew {
+		t.Renew(ctx, sessionKey(result.UserKey, result.DeviceId), g.Map{dtoken.KeyToken: token})
+	}
+	return &result, nil
+}
+
+// validate returns the live session holding token, t.mu must be held | 返回持有该 Token 的有效会话，需持有 t.mu
+func (t *Token) validate(ctx context.Context, token string) (*dtoken.Session, error) {
+	if token == "" {
+		return nil, gerror.NewCode(dtoken.CodeTokenMissing, dtoken.MsgErrTokenEmpty)
+	}
+	cacheKey, err := t.Codec.Decrypt(ctx, token)
+	if err != nil {
+		return nil, gerror.WrapCode(dtoken.CodeTokenInvalid, err)
+	}
+	if _, ok := t.revoked[token]; ok {
+		return nil, gerror.NewCode(dtoken.CodeTokenRevoked, dtoken.MsgErrRevoked)
+	}
+	session := t.session(cacheKey)
+	if session == nil {
+		return nil, gerror.NewCode(dtoken.CodeTokenExpired, dtoken.MsgErrTokenExpired)
+	}
+	if session.Token != token {
+		return nil, gerror.NewCode(dtoken.CodeTokenKicked, dtoken.MsgErrValidate)
+	}
+	return session, nil
+}
+
+// session returns the unexpired session of cacheKey, t.mu must be held | 返回未过期的会话，需持有 t.mu
+func (t *Token) session(cacheKey string) *dtoken.Session {
+	session := t.sessions[cacheKey]
+	if session == nil {
+		return nil
+	}
+	if t.Clock.Now().UnixMilli()-refTime(session) >= t.Options.Timeout {
+		delete(t.sessions, cacheKey)
+		return nil
+	}
+	return session
+}
+
+// shouldRenew reports whether session entered the refresh window | 判断会话是否进入续期窗口
+func (t *Token) shouldRenew(session *dtoken.Session) bool {
+	if t.Options.MaxRefresh <= 0 {
+		return false
+	}
+	if t.Options.MaxRefreshTimes > 0 && session.RefreshNum >= t.Options.MaxRefreshTimes {
+		return false
+	}
+	return t.Options.Timeout-(t.Clock.Now().UnixMilli()-refTime(session)) <= t.Options.MaxRefresh
+}
+
+// Get implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Get(ctx context.Context, userKey string) (string, any, error) {
+	if userKey == "" {
+		return "", nil, gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	session := t.session(userKey)
+	if session == nil {
+		return "", nil, gerror.NewCode(dtoken.CodeTokenExpired, dtoken.MsgErrTokenExpired)
+	}
+	return session.Token, session.Data, nil
+}
+
+// ParseToken implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) ParseToken(ctx context.Context, token string) (string, any, error) {
+	if token == "" {
+		return "", nil, gerror.NewCode(dtoken.CodeTokenMissing, dtoken.MsgErrTokenEmpty)
+	}
+	cacheKey, err := t.Codec.Decrypt(ctx, token)
+	if err != nil {
+		return "", nil, gerror.WrapCode(dtoken.CodeTokenInvalid, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	session := t.session(cacheKey)
+	if session == nil {
+		return "", nil, gerror.NewCode(dtoken.CodeTokenExpired, dtoken.MsgErrTokenExpired)
+	}
+	return session.UserKey, session.Data, nil
+}
+
+// GeneratePair implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) GeneratePair(ctx context.Context, userKey, deviceId string, data any) (*dtoken.TokenPair, error) {
+	accessToken, err := t.GenerateWithDevice(ctx, userKey, deviceId, data)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := &refreshEntry{userKey: userKey, deviceId: deviceId, data: data, used: make(map[string]struct{})}
+	if entry.token, err = t.Codec.Encode(ctx, sessionKey(userKey, deviceId)); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	t.refresh[sessionKey(userKey, deviceId)] = entry
+	return t.newTokenPair(accessToken, entry.token), nil
+}
+
+// Refresh implements dtoken.Token, a reused refresh token destroys the session | 实现 dtoken.Token 接口，重复使用刷新令牌会销毁会话
+func (t *Token) Refresh(ctx context.Context, refreshToken string) (*dtoken.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, gerror.NewCode(dtoken.CodeTokenMissing, dtoken.MsgErrTokenEmpty)
+	}
+	cacheKey, err := t.Codec.Decrypt(ctx, refreshToken)
+	if err != nil {
+		return nil, gerror.WrapCode(dtoken.CodeRefreshInvalid, err)
+	}
+
+	t.mu.Lock()
+	entry := t.refresh[cacheKey]
+	if entry == nil {
+		t.mu.Unlock()
+		return nil, gerror.NewCode(dtoken.CodeRefreshInvalid, dtoken.MsgErrRefresh)
+	}
+	if refreshToken != entry.token {
+		_, reused := entry.used[refreshToken]
+		t.mu.Unlock()
+		if !reused {
+			return nil, gerror.NewCode(dtoken.CodeRefreshInvalid, dtoken.MsgErrRefresh)
+		}
+		if err = t.DestroySession(ctx, entry.userKey, entry.deviceId); err != nil {
+			return nil, err
+		}
+		return nil, gerror.NewCode(dtoken.CodeRefreshReused, dtoken.MsgErrRefreshReuse)
+	}
+	if _, ok := t.revoked[refreshToken]; ok {
+		t.mu.Unlock()
+		return nil, gerror.NewCode(dtoken.CodeTokenRevoked, dtoken.MsgErrRevoked)
+	}
+
+	// Issue a new access token keeping grants, then rotate the refresh token | 签发保留权限的新访问令牌并轮换刷新令牌
+	var grants *dtoken.Grants
+	if session := t.session(cacheKey); session != nil {
+		grants = &dtoken.Grants{Roles: session.Roles, Permissions: session.Permissions}
+	}
+	accessToken, event, err := t.generate(ctx, entry.userKey, entry.deviceId, entry.data, grants, false)
+	if err == nil {
+		entry.used[refreshToken] = struct{}{}
+		entry.token, err = t.Codec.Encode(ctx, cacheKey)
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	t.publish(ctx, event)
+	return t.newTokenPair(accessToken, entry.token), nil
+}
+
+// newTokenPair builds a token pair with configured lifetimes | 使用配置的有效期构建令牌对
+func (t *Token) newTokenPair(accessToken, refreshToken string) *dtoken.TokenPair {
+	return &dtoken.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        t.Options.Timeout,
+		RefreshExpiresIn: t.Options.RefreshTimeout,
+	}
+}
+
+// ListSessions implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) ListSessions(ctx context.Context, userKey string) ([]*dtoken.Session, error) {
+	if userKey == "" {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sessions := make([]*dtoken.Session, 0)
+	for cacheKey, session := range t.sessions {
+		if session.UserKey == userKey && t.session(cacheKey) != nil {
+			snapshot := *session
+			snapshot.ExpireTime = refTime(session) + t.Options.Timeout
+			sessions = append(sessions, &snapshot)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreateTime < sessions[j].CreateTime
+	})
+	return sessions, nil
+}
+
+// DestroySession implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) DestroySession(ctx context.Context, userKey, deviceId string) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	t.mu.Lock()
+	delete(t.sessions, sessionKey(userKey, deviceId))
+	delete(t.refresh, sessionKey(userKey, deviceId))
+	t.mu.Unlock()
+	t.publish(ctx, &dtoken.Event{Type: dtoken.EventDestroyed, UserKey: userKey, DeviceId: deviceId})
+	return nil
+}
+
+// Destroy implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Destroy(ctx context.Context, userKey string) error {
+	sessions, err := t.ListSessions(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err = t.DestroySession(ctx, userKey, session.DeviceId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeToken implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return gerror.NewCode(dtoken.CodeTokenMissing, dtoken.MsgErrTokenEmpty)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revoked[token] = struct{}{}
+	return nil
+}
+
+// RevokeUser implements dtoken.Token, sessions created before the time are destroyed | 实现 dtoken.Token 接口，销毁指定时间之前创建的会话
+func (t *Token) RevokeUser(ctx context.Context, userKey string, before int64) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, dtoken.MsgErrUserKeyEmpty)
+	}
+	return t.revokeBefore(ctx, userKey, before)
+}
+
+// RevokeAll implements dtoken.Token, sessions created before the time are destroyed | 实现 dtoken.Token 接口，销毁指定时间之前创建的会话
+func (t *Token) RevokeAll(ctx context.Context, before int64) error {
+	return t.revokeBefore(ctx, "", before)
+}
+
+// revokeBefore revokes sessions of userKey ("" for all) created before the time | 吊销用户（"" 表示所有用户）在指定时间之前创建的会话
+func (t *Token) revokeBefore(ctx context.Context, userKey string, before int64) error {
+	if before <= 0 {
+		before = t.Clock.Now().UnixMilli()
+	}
+	t.mu.Lock()
+	var revoked []*dtoken.Session
+	for cacheKey, session := range t.sessions {
+		if (userKey == "" || session.UserKey == userKey) && session.CreateTime < before {
+			t.revoked[session.Token] = struct{}{}
+			delete(t.sessions, cacheKey)
+			delete(t.refresh, cacheKey)
+			revoked = append(revoked, session)
+		}
+	}
+	t.mu.Unlock()
+	for _, session := range revoked {
+		t.publish(ctx, &dtoken.Event{Type: dtoken.EventDestroyed, UserKey: session.UserKey, DeviceId: session.DeviceId, Reason: dtoken.MsgErrRevoked})
+	}
+	return nil
+}
+
+// Subscribe implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Subscribe(listener dtoken.Listener, types ...dtoken.EventType) (unsubscribe func()) {
+	return t.events.Subscribe(listener, types...)
+}
+
+// SubscribeAsync implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) SubscribeAsync(listener dtoken.Listener, types ...dtoken.EventType) (unsubscribe func()) {
+	return t.events.SubscribeAsync(listener, types...)
+}
+
+// Renew implements dtoken.Token, recording the call and renewing synchronously | 实现 dtoken.Token 接口，记录调用并同步续期
+func (t *Token) Renew(ctx context.Context, userKey string, userCache g.Map) {
+	token := gconv.String(userCache[dtoken.KeyToken])
+	t.mu.Lock()
+	t.renewCalls = append(t.renewCalls, RenewCall{UserKey: userKey, Token: token})
+	session := t.session(userKey)
+	if session == nil || session.Token != token {
+		t.mu.Unlock()
+		return
+	}
+	session.RefreshNum++
+	session.LastRenewTime = t.Clock.Now().UnixMilli()
+	event := &dtoken.Event{Type: dtoken.EventRenewed, UserKey: session.UserKey, DeviceId: session.DeviceId, CreateTime: session.CreateTime}
+	t.mu.Unlock()
+	t.publish(ctx, event)
+}
+
+// Shutdown implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) Shutdown(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.shutdown = true
+}
+
+// GetOptions implements dtoken.Token | 实现 dtoken.Token 接口
+func (t *Token) GetOptions() dtoken.Options {
+	return t.Options
+}
+
+// publish sends event to subscribers | 向订阅者发布事件
+func (t *Token) publish(ctx context.Context, event *dtoken.Event) {
+	if event == nil || !t.events.HasListeners(event.Type) {
+		return
+	}
+	event.Ctx = ctx
+	event.Time = t.Clock.Now().UnixMilli()
+	t.events.Publish(event)
+}
+
+// sessionKey builds the session key like dtoken | 与 dtoken 相同地构建会话 key
+func sessionKey(userKey, deviceId string) string {
+	if deviceId == "" {
+		return userKey
+	}
+	return userKey + dtoken.DefaultDeviceDelimiter + deviceId
+}
+
+// refTime returns the time expiry is counted from | 返回计算过期的起始时间
+func refTime(session *dtoken.Session) int64 {
+	if session.LastRenewTime > 0 {
+		return session.LastRenewTime
+	}
+	return session.CreateTime
+}
+
+var _ dtoken.Token = (*Token)(nil)
@@ -0,0 +1,153 @@
+package dtokentest
+
+import (
+	"context"
+	"github.com/Zany2/dtoken/dtoken"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestToken_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	clock := NewFakeClock(time.UnixMilli(1700000000000))
+	token := NewToken(dtoken.Options{Timeout: 10000, MaxRefresh: 5000})
+	token.Clock = clock
+
+	first, err := token.Generate(ctx, "alice", g.Map{"name": "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "test:alice:1" {
+		t.Fatalf("expected deterministic token, got %s", first)
+	}
+	second, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, first); !gerror.HasCode(err, dtoken.CodeTokenKicked) {
+		t.Fatalf("expected kicked token, got %v", err)
+	}
+	if got := token.IssuedTokens(); len(got) != 2 || got[1] != second {
+		t.Fatalf("unexpected issued tokens %v", got)
+	}
+
+	// Validate renews synchronously inside the refresh window | 进入续期窗口时 Validate 同步续期
+	clock.Advance(6 * time.Second)
+	if _, err = token.Validate(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if calls := token.RenewCalls(); len(calls) != 1 || calls[0].Token != second {
+		t.Fatalf("unexpected renew calls %v", calls)
+	}
+	clock.Advance(9 * time.Second)
+	if _, err = token.Validate(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(10 * time.Second)
+	if _, err = token.Validate(ctx, second); !gerror.HasCode(err, dtoken.CodeTokenExpired) {
+		t.Fatalf("expected expired token, got %v", err)
+	}
+
+	if _, err = token.Validate(ctx, "bad"); !gerror.HasCode(err, dtoken.CodeTokenInvalid) {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+}
+
+func TestToken_RefreshAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	token := NewToken(dtoken.Options{})
+
+	pair, err := token.GeneratePair(ctx, "bob", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := token.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, next.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Refresh(ctx, pair.RefreshToken); !gerror.HasCode(err, dtoken.CodeRefreshReused) {
+		t.Fatalf("expected reused refresh token, got %v", err)
+	}
+	if _, err = token.Validate(ctx, next.AccessToken); !gerror.HasCode(err, dtoken.CodeTokenExpired) {
+		t.Fatalf("expected session destroyed on reuse, got %v", err)
+	}
+
+	accessToken, err := token.Generate(ctx, "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = token.RevokeToken(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, accessToken); !gerror.HasCode(err, dtoken.CodeTokenRevoked) {
+		t.Fatalf("expected revoked token, got %v", err)
+	}
+}
+
+func TestCache_Records(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache()
+	token, err := dtoken.New(dtoken.WithCache(cache), dtoken.WithCodec(NewCodec()), dtoken.WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(ctx)
+
+	if _, err = token.Generate(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	if keys := cache.Keys(); len(keys) != 2 || keys[0] != "alice" {
+		t.Fatalf("expected session and index keys, got %v", keys)
+	}
+	var sets int
+	for _, op := range cache.Ops() {
+		if op.Op == OpSet && op.Key == "alice" && op.Value[dtoken.KeyToken] == "test:alice:1" {
+			sets++
+		}
+	}
+	if sets != 1 {
+		t.Fatalf("expected one recorded session write, got %d", sets)
+	}
+
+	// Scanning lets the admin API list sessions | 扫描能力使管理接口可以列出会话
+	if count, err := token.(dtoken.SessionAdmin).CountSessions(ctx, dtoken.SessionQuery{}); err != nil || count != 1 {
+		t.Fatalf("expected one session, got %d %v", count, err)
+	}
+}
+
+func TestRequestHelpers(t *testing.T) {
+	token := NewToken(dtoken.Options{})
+
+	// net/http middleware | net/http 中间件
+	handler := dtoken.NewHTTPMiddleware(token).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := dtoken.SessionFromContext(r.Context())
+		_, _ = w.Write([]byte(session.UserKey))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, NewRequest(t, token, http.MethodGet, "/orders", "alice"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Fatalf("expected alice, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// ghttp middleware | ghttp 中间件
+	echo := func(r *ghttp.Request) {
+		session, _ := dtoken.SessionFromContext(r.Context())
+		r.Response.Write(session.UserKey)
+	}
+	rec = ServeGHttp(t, token, NewRequest(t, token, http.MethodGet, "/orders", "bob"), echo)
+	if rec.Code != http.StatusOK || rec.Body.String() != "bob" {
+		t.Fatalf("expected bob, got %d %q", rec.Code, rec.Body.String())
+	}
+	rec = ServeGHttp(t, token, httptest.NewRequest(http.MethodGet, "/orders", nil), echo)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
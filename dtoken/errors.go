@@ -0,0 +1,72 @@
+package dtoken
+
+import (
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"net/http"
+)
+
+// Error codes of failures returned by Token methods, check with gerror.HasCode | Token 方法返回的失败错误码，可通过 gerror.HasCode 判断
+// Other failures keep gcode codes: CodeMissingParameter for empty userKey, CodeNotSupported for disabled features, CodeInternalError for cache errors
+// 其他失败沿用 gcode 错误码：userKey 为空为 CodeMissingParameter，功能未启用为 CodeNotSupported，缓存错误为 CodeInternalError
+var (
+	CodeTokenMissing   = gcode.New(40101, MsgErrTokenEmpty, nil)   // No token in request or argument | 请求或参数中没有 Token
+	CodeTokenInvalid   = gcode.New(40102, MsgErrTokenInvalid, nil) // Token is malformed, tampered or signed by an unknown key | Token 格式错误、被篡改或密钥未知
+	CodeTokenExpired   = gcode.New(40103, MsgErrTokenExpired, nil) // Session expired or destroyed | 会话已过期或已销毁
+	CodeTokenKicked    = gcode.New(40104, MsgErrValidate, nil)     // Session was replaced by a newer login | 会话已被新的登录顶替
+	CodeTokenRevoked   = gcode.New(40105, MsgErrRevoked, nil)      // Token is in the revocation list | Token 已被吊销
+	CodeRefreshInvalid = gcode.New(40106, MsgErrRefresh, nil)      // Refresh token is unknown or expired | 刷新令牌无效或已过期
+	CodeRefreshReused  = gcode.New(40107, MsgErrRefreshReuse, nil) // Rotated refresh token presented again | 已轮换的刷新令牌被重复使用
+	CodeTenantInvalid  = gcode.New(40108, MsgErrTenant, nil)       // Tenant is malformed or does not own the token | 租户格式错误或不拥有该 Token
+	CodeForbidden      = gcode.New(40301, MsgErrForbidden, nil)    // Session lacks required roles or permissions | 会话缺少所需角色或权限
+	CodeCsrfInvalid    = gcode.New(40302, MsgErrCsrf, nil)         // Cookie-authenticated request failed the CSRF check | Cookie 认证的请求未通过 CSRF 校验
+	CodeSessionLimit   = gcode.New(40303, MsgErrSessionLimit, nil) // Max sessions per user reached | 用户会话数达到上限
+)
+
+// unauthorizedCodes are failures answered with 401 | 以 401 响应的失败错误码
+var unauthorizedCodes = []gcode.Code{
+	CodeTokenMissing, CodeTokenInvalid, CodeTokenExpired, CodeTokenKicked, CodeTokenRevoked,
+	CodeRefreshInvalid, CodeRefreshReused, CodeTenantInvalid, gcode.CodeMissingParameter, gcode.CodeInvalidParameter,
+}
+
+// forbiddenCodes are failures answered with 403 | 以 403 响应的失败错误码
+var forbiddenCodes = []gcode.Code{CodeForbidden, CodeCsrfInvalid, CodeSessionLimit}
+
+// ErrorStatus maps an error to its HTTP status: 401, 403 or 500 | 将错误映射为 HTTP 状态码：401、403 或 500
+func ErrorStatus(err error) int {
+	code := gerror.Code(err)
+	for _, c := range forbiddenCodes {
+		if code == c {
+			return http.StatusForbidden
+		}
+	}
+	for _, c := range unauthorizedCodes {
+		if code == c {
+			return http.StatusUnauthorized
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorCode returns the response code and message of err, hiding internal details | 返回错误的响应码与信息，隐藏内部细节
+func ErrorCode(err error) gcode.Code {
+	if ErrorStatus(err) == http.StatusInternalServerError {
+		return gcode.CodeInternalError
+	}
+	return gerror.Code(err)
+}
+
+// WWWAuthenticate builds the RFC 6750 challenge for err, "" for server errors | 按 RFC 6750 构建错误对应的认证质询，服务端错误返回空串
+func WWWAuthenticate(err error) string {
+	switch {
+	case ErrorStatus(err) == http.StatusInternalServerError:
+		return ""
+	case gerror.HasCode(err, CodeTokenMissing):
+		return `Bearer realm="dtoken"`
+	case ErrorStatus(err) == http.StatusForbidden:
+		return fmt.Sprintf(`Bearer realm="dtoken", error="insufficient_scope", error_description=%q`, ErrorCode(err).Message())
+	default:
+		return fmt.Sprintf(`Bearer realm="dtoken", error="invalid_token", error_description=%q`, ErrorCode(err).Message())
+	}
+}
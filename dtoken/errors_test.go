@@ -0,0 +1,83 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	expect := func(err error, code gcode.Code, status int) {
+		t.Helper()
+		if !gerror.HasCode(err, code) {
+			t.Fatalf("expected code %d, got %v", code.Code(), err)
+		}
+		if got := ErrorStatus(err); got != status {
+			t.Fatalf("expected status %d, got %d", status, got)
+		}
+	}
+
+	_, err := token.Validate(ctx, "")
+	expect(err, CodeTokenMissing, http.StatusUnauthorized)
+	_, err = token.Validate(ctx, "bad")
+	expect(err, CodeTokenInvalid, http.StatusUnauthorized)
+
+	first, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Generate(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = token.Validate(ctx, first)
+	expect(err, CodeTokenKicked, http.StatusUnauthorized)
+
+	if err = token.Destroy(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = token.Validate(ctx, first)
+	expect(err, CodeTokenExpired, http.StatusUnauthorized)
+	if message := ErrorCode(err).Message(); message != MsgErrTokenExpired {
+		t.Fatalf("expected expired message, got %q", message)
+	}
+
+	expect(gerror.NewCode(CodeForbidden, MsgErrForbidden), CodeForbidden, http.StatusForbidden)
+	expect(gerror.WrapCode(gcode.CodeInternalError, gerror.New("redis down")), gcode.CodeInternalError, http.StatusInternalServerError)
+	if code := ErrorCode(gerror.New("redis down")); code != gcode.CodeInternalError {
+		t.Fatalf("expected internal error to be hidden, got %v", code)
+	}
+}
+
+func TestHTTPMiddleware_ErrorResponse(t *testing.T) {
+	token := newTestToken(t, Options{})
+	handler := NewHTTPMiddleware(token).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		header    string
+		challenge string
+	}{
+		{"", `Bearer realm="dtoken"`},
+		{"Bearer bad", `error="invalid_token"`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%q: expected 401, got %d", c.header, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, c.challenge) {
+			t.Fatalf("%q: expected challenge %q, got %q", c.header, c.challenge, got)
+		}
+	}
+}
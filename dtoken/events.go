@@ -0,0 +1,210 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+	"sync"
+)
+
+// EventType identifies a token lifecycle event | Token 生命周期事件类型
+type EventType int
+
+// Token lifecycle events | Token 生命周期事件
+const (
+	EventGenerated        EventType = iota + 1 // Session created | 会话已创建
+	EventValidated                             // Token validated | Token 校验通过
+	EventValidationFailed                      // Token rejected, see Reason | Token 校验失败，原因见 Reason
+	EventRenewed                               // Session renewed | 会话已续期
+	EventRenewFailed                           // Session renewal failed, see Err | 会话续期失败，错误见 Err
+	EventDestroyed                             // Session destroyed | 会话已销毁
+)
+
+// String returns the event name | 返回事件名称
+func (t EventType) String() string {
+	switch t {
+	case EventGenerated:
+		return "Generated"
+	case EventValidated:
+		return "Validated"
+	case EventValidationFailed:
+		return "ValidationFailed"
+	case EventRenewed:
+		return "Renewed"
+	case EventRenewFailed:
+		return "RenewFailed"
+	case EventDestroyed:
+		return "Destroyed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event carries information of a token lifecycle event | Token 生命周期事件信息
+type Event struct {
+	Type       EventType       // Event type | 事件类型
+	Ctx        context.Context // Context of the operation (never done for async listeners) | 操作的上下文（异步监听器中不会被取消）
+	UserKey    string          // User identifier, may be empty on validation failure | 用户标识，校验失败时可能为空
+	DeviceId   string          // Device identifier | 设备标识
+	TenantId   string          // Tenant identifier ("" for the default tenant) | 租户标识（默认租户为空）
+	TokenId    string          // Token identifier, see GTokenV2.TokenId | Token 标识，参见 GTokenV2.TokenId
+	CreateTime int64           // Session creation time (ms) | 会话创建时间（毫秒）
+	Time       int64           // Event time (ms) | 事件发生时间（毫秒）
+	Reason     string          // Failure or destroy reason | 失败或销毁原因
+	Err        error           // Underlying error of failure events | 失败事件的原始错误
+}
+
+// Listener handles token events | Token 事件监听器
+type Listener func(event *Event)
+
+// eventListener is a registered listener | 已注册的监听器
+type eventListener struct {
+	id       int64
+	listener Listener
+	async    bool
+	types    map[EventType]struct{} // Empty for all events | 为空时监听全部事件
+}
+
+// EventBus dispatches token events to listeners, safe for concurrent use | 向监听器分发 Token 事件，并发安全
+type EventBus struct {
+	mu        sync.RWMutex
+	nextId    int64
+	listeners []*eventListener
+}
+
+// NewEventBus creates a new EventBus instance | 创建一个新的 EventBus 实例
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a listener called synchronously in the operation goroutine | 注册在操作协程中同步调用的监听器
+// No types means all events; the returned function unsubscribes | 未指定类型时监听全部事件；返回值用于取消订阅
+func (b *EventBus) Subscribe(listener Listener, types ...EventType) (unsubscribe func()) {
+	return b.subscribe(listener, false, types)
+}
+
+// SubscribeAsync registers a listener called in its own goroutine | 注册在独立协程中异步调用的监听器
+func (b *EventBus) SubscribeAsync(listener Listener, types ...EventType) (unsubscribe func()) {
+	return b.subscribe(listener, true, types)
+}
+
+// subscribe adds a listener | 添加监听器
+func (b *EventBus) subscribe(listener Listener, async bool, types []EventType) func() {
+	l := &eventListener{listener: listener, async: async, types: make(map[EventType]struct{}, len(types))}
+	for _, t := range types {
+		l.types[t] = struct{}{}
+	}
+
+	b.mu.Lock()
+	b.nextId++
+	l.id = b.nextId
+	// Copy on write so Publish can iterate without holding the lock | 写时复制，Publish 遍历时无需持锁
+	listeners := make([]*eventListener, len(b.listeners), len(b.listeners)+1)
+	copy(listeners, b.listeners)
+	b.listeners = append(listeners, l)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		listeners := make([]*eventListener, 0, len(b.listeners))
+		for _, item := range b.listeners {
+			if item.id != l.id {
+				listeners = append(listeners, item)
+			}
+		}
+		b.listeners = listeners
+	}
+}
+
+// HasListeners reports whether any listener wants the event type | 判断是否有监听器订阅该事件类型
+func (b *EventBus) HasListeners(t EventType) bool {
+	for _, l := range b.snapshot() {
+		if l.accepts(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish dispatches the event, listener panics are recovered and logged | 分发事件，监听器的 panic 会被恢复并记录日志
+func (b *EventBus) Publish(event *Event) {
+	if event.Ctx == nil {
+		event.Ctx = gctx.New()
+	}
+	if event.Time == 0 {
+		event.Time = gtime.Now().TimestampMilli()
+	}
+	for _, l := range b.snapshot() {
+		if !l.accepts(event.Type) {
+			continue
+		}
+		if !l.async {
+			l.call(event)
+			continue
+		}
+		// Async listeners get their own copy with a context that outlives the request | 异步监听器获得独立副本，上下文不随请求结束而取消
+		asyncEvent := *event
+		asyncEvent.Ctx = gctx.NeverDone(event.Ctx)
+		go l.call(&asyncEvent)
+	}
+}
+
+// snapshot returns current listeners | 返回当前监听器列表
+func (b *EventBus) snapshot() []*eventListener {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.listeners
+}
+
+// accepts reports whether the listener wants the event type | 判断监听器是否订阅该事件类型
+func (l *eventListener) accepts(t EventType) bool {
+	if len(l.types) == 0 {
+		return true
+	}
+	_, ok := l.types[t]
+	return ok
+}
+
+// call invokes the listener, recovering panics | 调用监听器并恢复 panic
+func (l *eventListener) call(event *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.Log().Errorf(event.Ctx, "Token event listener panic on %s: %v", event.Type, r)
+		}
+	}()
+	l.listener(event)
+}
+
+// Subscribe registers a synchronous event listener | 注册同步事件监听器
+func (m *GTokenV2) Subscribe(listener Listener, types ...EventType) (unsubscribe func()) {
+	return m.Events.Subscribe(listener, types...)
+}
+
+// SubscribeAsync registers an asynchronous event listener | 注册异步事件监听器
+func (m *GTokenV2) SubscribeAsync(listener Listener, types ...EventType) (unsubscribe func()) {
+	return m.Events.SubscribeAsync(listener, types...)
+}
+
+// emit publishes an event when someone listens, filling in the token id | 有监听器时发布事件，并补充 Token 标识
+func (m *GTokenV2) emit(ctx context.Context, token string, event *Event) {
+	if m.Events == nil || !m.Events.HasListeners(event.Type) {
+		return
+	}
+	event.Ctx = ctx
+	if event.Time == 0 {
+		event.Time = m.now()
+	}
+	if event.TenantId == "" {
+		event.TenantId = m.TenantId
+	}
+	if event.TokenId == "" && token != "" {
+		event.TokenId, _ = m.TokenId(ctx, token)
+	}
+	if event.Err != nil && event.Reason == "" {
+		event.Reason = gerror.Cause(event.Err).Error()
+	}
+	m.Events.Publish(event)
+}
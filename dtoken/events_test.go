@@ -0,0 +1,121 @@
+package dtoken
+
+import (
+	"context"
+	"errors"
+	"github.com/gogf/gf/v2/frame/g"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingSetCache fails every Set after the first n calls
+type failingSetCache struct {
+	Cache
+	mu    sync.Mutex
+	allow int
+}
+
+func (c *failingSetCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.allow <= 0 {
+		return errors.New("set failed")
+	}
+	c.allow--
+	return c.Cache.Set(ctx, cacheKey, cacheValue)
+}
+
+func TestEvents_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	var (
+		mu     sync.Mutex
+		events []*Event
+	)
+	unsubscribe := token.Subscribe(func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	asyncDone := make(chan *Event, 1)
+	token.SubscribeAsync(func(event *Event) {
+		asyncDone <- event
+	}, EventDestroyed)
+
+	userToken, err := token.GenerateWithDevice(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, userToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, "bad"); err == nil {
+		t.Fatal("expected invalid token")
+	}
+	if err = token.DestroySession(ctx, "alice", "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-asyncDone:
+		if event.UserKey != "alice" || event.DeviceId != "web" {
+			t.Fatalf("unexpected async event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("async listener not called")
+	}
+
+	unsubscribe()
+	if _, err = token.Generate(ctx, "bob", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []EventType{EventGenerated, EventValidated, EventValidationFailed, EventDestroyed}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Type != want[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, want[i], event.Type)
+		}
+	}
+	if events[0].TokenId == "" || events[0].TokenId != events[1].TokenId || events[0].CreateTime == 0 {
+		t.Fatalf("expected token id and create time: %+v", events[0])
+	}
+	if events[2].Reason == "" || events[2].Err == nil {
+		t.Fatalf("expected failure reason: %+v", events[2])
+	}
+}
+
+func TestEvents_RenewFailed(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{Timeout: 1000, MaxRefresh: 1000})
+	token.Cache = &failingSetCache{Cache: token.Cache, allow: 2} // Session and index only
+
+	failed := make(chan *Event, 1)
+	token.Subscribe(func(event *Event) {
+		failed <- event
+	}, EventRenewFailed)
+
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, userToken); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-failed:
+		if event.UserKey != "alice" || event.Err == nil {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected RenewFailed event")
+	}
+}
@@ -0,0 +1,136 @@
+package dtoken
+
+import (
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"strings"
+)
+
+// Token sources used in Options.TokenLookup | Options.TokenLookup 中使用的 Token 来源
+const (
+	LookupHeader = "header" // "header:<name>[:<scheme>]" | 请求头
+	LookupCookie = "cookie" // "cookie:<name>" | Cookie
+	LookupParam  = "param"  // "param:<name>", query or form parameter | 查询或表单参数
+)
+
+// DefaultTokenLookup is the lookup used when Options.TokenLookup is empty | Options.TokenLookup 为空时使用的查找顺序
+var DefaultTokenLookup = []string{"header:Authorization:Bearer", "param:" + KeyToken}
+
+// defaultExtractor is the chain built from DefaultTokenLookup | 由 DefaultTokenLookup 构建的提取链
+var defaultExtractor = mustExtractorByOptions(Options{})
+
+// TokenExtractor extracts the token from a request, "" with nil error means not found | 从请求中提取 Token，返回空串且无错误表示未找到
+type TokenExtractor interface {
+	Extract(c Carrier) (token string, err error)
+}
+
+// TokenExtractorFunc adapts a function to TokenExtractor | 将函数适配为 TokenExtractor
+type TokenExtractorFunc func(c Carrier) (string, error)
+
+// Extract implements TokenExtractor | 实现 TokenExtractor 接口
+func (f TokenExtractorFunc) Extract(c Carrier) (string, error) {
+	return f(c)
+}
+
+// HeaderExtractor reads a header, stripping the scheme when set | 读取请求头，设置了 Scheme 时去除认证方案前缀
+// Headers using another scheme are ignored so later extractors can run | 使用其他认证方案的请求头会被忽略，以便后续提取器继续尝试
+type HeaderExtractor struct {
+	Name   string // Header name, e.g. "Authorization" or "X-Access-Token" | 请求头名称
+	Scheme string // Auth scheme matched case-insensitively, e.g. "Bearer" ("" for raw value) | 认证方案，不区分大小写（为空时取原始值）
+}
+
+// Extract implements TokenExtractor | 实现 TokenExtractor 接口
+func (e HeaderExtractor) Extract(c Carrier) (string, error) {
+	value := strings.TrimSpace(c.Header(e.Name))
+	if value == "" || e.Scheme == "" {
+		return value, nil
+	}
+	scheme, token, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(scheme, e.Scheme) {
+		return "", nil
+	}
+	if token = strings.TrimSpace(token); token == "" {
+		return "", gerror.NewCode(CodeTokenInvalid, e.Scheme+" param empty | "+e.Scheme+" 参数为空")
+	}
+	return token, nil
+}
+
+// CookieExtractor reads a cookie | 读取 Cookie
+type CookieExtractor struct {
+	Name string // Cookie name | Cookie 名称
+}
+
+// Extract implements TokenExtractor | 实现 TokenExtractor 接口
+func (e CookieExtractor) Extract(c Carrier) (string, error) {
+	return c.Cookie(e.Name), nil
+}
+
+// ParamExtractor reads a query or form parameter | 读取查询或表单参数
+type ParamExtractor struct {
+	Name string // Parameter name | 参数名称
+}
+
+// Extract implements TokenExtractor | 实现 TokenExtractor 接口
+func (e ParamExtractor) Extract(c Carrier) (string, error) {
+	return c.Param(e.Name), nil
+}
+
+// ExtractorChain tries extractors in order and returns the first token found | 按顺序尝试提取器，返回第一个找到的 Token
+type ExtractorChain []TokenExtractor
+
+// NewExtractorChain creates a new ExtractorChain instance | 创建一个新的 ExtractorChain 实例
+func NewExtractorChain(extractors ...TokenExtractor) ExtractorChain {
+	return extractors
+}
+
+// Extract implements TokenExtractor, failing when no extractor finds a token | 实现 TokenExtractor 接口，所有提取器均未找到时返回错误
+func (chain ExtractorChain) Extract(c Carrier) (string, error) {
+	for _, extractor := range chain {
+		token, err := extractor.Extract(c)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+	}
+	return "", gerror.NewCode(CodeTokenMissing, "token empty | 缺少 token 参数")
+}
+
+// NewExtractorByOptions builds the chain described by TokenLookup | 根据 TokenLookup 构建提取链
+// Omit "param" entries to disable query-string tokens | 去掉 "param" 项即可禁用查询串中的 Token
+func NewExtractorByOptions(options Options) (ExtractorChain, error) {
+	lookup := options.TokenLookup
+	if len(lookup) == 0 {
+		lookup = DefaultTokenLookup
+	}
+	chain := make(ExtractorChain, 0, len(lookup))
+	for _, item := range lookup {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 3)
+		if len(parts) < 2 || parts[1] == "" {
+			return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid TokenLookup item %q", item)
+		}
+		switch {
+		case parts[0] == LookupHeader && len(parts) == 3:
+			chain = append(chain, HeaderExtractor{Name: parts[1], Scheme: parts[2]})
+		case parts[0] == LookupHeader:
+			chain = append(chain, HeaderExtractor{Name: parts[1]})
+		case parts[0] == LookupCookie && len(parts) == 2:
+			chain = append(chain, CookieExtractor{Name: parts[1]})
+		case parts[0] == LookupParam && len(parts) == 2:
+			chain = append(chain, ParamExtractor{Name: parts[1]})
+		default:
+			return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid TokenLookup item %q", item)
+		}
+	}
+	return chain, nil
+}
+
+// mustExtractorByOptions builds the chain, panicking on invalid config | 构建提取链，配置错误时 panic
+func mustExtractorByOptions(options Options) TokenExtractor {
+	chain, err := NewExtractorByOptions(options)
+	if err != nil {
+		panic("invalid config: " + err.Error() + " | TokenLookup 配置错误")
+	}
+	return chain
+}
@@ -0,0 +1,93 @@
+package dtoken
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewExtractorByOptions(t *testing.T) {
+	extractor, err := NewExtractorByOptions(Options{
+		TokenLookup: []string{"header:X-Access-Token", "header:Authorization:Token", "cookie:access_token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		setup  func(r *http.Request)
+		expect string
+		fail   bool
+	}{
+		{"raw header", func(r *http.Request) { r.Header.Set("X-Access-Token", "t1") }, "t1", false},
+		{"custom scheme", func(r *http.Request) { r.Header.Set("Authorization", "token t2") }, "t2", false},
+		{"cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "access_token", Value: "t3"}) }, "t3", false},
+		{"other scheme falls through", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer x")
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: "t4"})
+		}, "t4", false},
+		{"query disabled", func(r *http.Request) { r.URL.RawQuery = "token=t5" }, "", true},
+		{"empty scheme value", func(r *http.Request) { r.Header.Set("Authorization", "Token  ") }, "", true},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c.setup(req)
+		token, err := extractor.Extract(NewHTTPCarrier(req))
+		if (err != nil) != c.fail || token != c.expect {
+			t.Fatalf("%s: got %q, %v", c.name, token, err)
+		}
+	}
+
+	for _, lookup := range [][]string{{"header"}, {"cookie:"}, {"query:token"}, {"cookie:a:b"}} {
+		if _, err = NewExtractorByOptions(Options{TokenLookup: lookup}); err == nil {
+			t.Fatalf("expected error for %v", lookup)
+		}
+	}
+}
+
+func TestExtractorChain_Custom(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	userToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	middleware := NewHTTPMiddleware(token)
+	middleware.Extractor = NewExtractorChain(
+		TokenExtractorFunc(func(c Carrier) (string, error) {
+			return c.Header("X-Custom"), nil
+		}),
+		HeaderExtractor{Name: "Authorization", Scheme: "Bearer"},
+	)
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-Custom", userToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected custom extractor to authenticate, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "bearer "+userToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected case-insensitive scheme, got %d", rec.Code)
+	}
+}
+
+func TestNewDefaultToken_InvalidTokenLookup(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for invalid TokenLookup")
+		}
+	}()
+	NewDefaultToken(Options{CachePreKey: "Test:" + t.Name() + ":", TokenLookup: []string{"form:token"}})
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package dtoken
+
+import "os"
+
+// lockFile is a no-op on platforms without advisory file locks | 不支持文件锁的平台上为空操作
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without advisory file locks | 不支持文件锁的平台上为空操作
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package dtoken
+
+import (
+	"errors"
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockFile takes an exclusive non-blocking LockFileEx lock | 获取非阻塞的排他 LockFileEx 锁
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errors.New(MsgErrFileCacheLocked)
+	}
+	return err
+}
+
+// unlockFile releases the LockFileEx lock | 释放 LockFileEx 锁
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,230 @@
+package dtoken
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gogf/gf/v2/os/gtime"
+	"sort"
+	"sync"
+)
+
+// Key status in KeyRing | 密钥环中的密钥状态
+const (
+	KeyStatusActive      = 1 // Encrypts new tokens and decrypts | 用于加密新 Token 及解密
+	KeyStatusDecryptOnly = 2 // Only decrypts previously issued tokens | 仅用于解密已签发的 Token
+	KeyStatusRetired     = 3 // Rejected, kept only until removed | 已退役，不再接受，等待移除
+)
+
+const (
+	MsgErrKeyIdEmpty   = "key id empty"                      // Error message when key id is empty | 密钥 ID 为空
+	MsgErrKeyIdLen     = "key id longer than 255 bytes"      // Error message when key id is too long | 密钥 ID 过长
+	MsgErrKeyExists    = "key id already exists"             // Error message when key id is duplicated | 密钥 ID 重复
+	MsgErrKeyNotFound  = "key id not found"                  // Error message when key id is unknown | 密钥 ID 不存在
+	MsgErrKeySize      = "key length must be 16, 24 or 32"   // Error message when AES key size is invalid | AES 密钥长度错误
+	MsgErrKeyStatus    = "key status invalid"                // Error message when key status is unknown | 密钥状态无效
+	MsgErrKeyActive    = "active key cannot be retired"      // Error message when retiring the active key | 不能退役当前激活密钥
+	MsgErrKeyNoActive  = "key ring has no active key"        // Error message when no active key exists | 密钥环中没有激活密钥
+	MsgErrKeyMultiAct  = "key ring has multiple active keys" // Error message when several keys are active | 密钥环中存在多个激活密钥
+	MsgErrKeyNotUsable = "key is retired"                    // Error message when promoting a retired key | 密钥已退役
+	MsgErrKeyChanged   = "key id reused with another key"    // Error message when an existing key id gets new key material | 已有密钥 ID 对应的密钥内容被修改
+)
+
+// RingKey is an encryption key with id and status | 带 ID 与状态的加密密钥
+type RingKey struct {
+	Id         string // Key identifier embedded in tokens | 写入 Token 的密钥标识
+	Key        []byte // AES key (16, 24 or 32 bytes) | AES 密钥（16、24 或 32 字节）
+	Status     int8   // 1-active 2-decrypt-only 3-retired | 1 激活 2 仅解密 3 退役
+	UpdateTime int64  // Last status change (ms) | 最近一次状态变更时间（毫秒）
+}
+
+// KeyRing holds rotating encryption keys, safe for concurrent use | 可轮换的加密密钥环，并发安全
+type KeyRing struct {
+	mu       sync.RWMutex
+	keys     map[string]*RingKey
+	activeId string
+}
+
+// NewKeyRing creates a key ring, exactly one key must be active | 创建密钥环，必须有且仅有一个激活密钥
+func NewKeyRing(keys ...RingKey) (*KeyRing, error) {
+	r := &KeyRing{keys: make(map[string]*RingKey, len(keys))}
+	for _, key := range keys {
+		if key.Status == 0 {
+			key.Status = KeyStatusDecryptOnly
+		}
+		if err := r.add(key); err != nil {
+			return nil, err
+		}
+		if key.Status == KeyStatusActive {
+			if r.activeId != "" {
+				return nil, errors.New(MsgErrKeyMultiAct)
+			}
+			r.activeId = key.Id
+		}
+	}
+	if r.activeId == "" {
+		return nil, errors.New(MsgErrKeyNoActive)
+	}
+	return r, nil
+}
+
+// Add adds a key in decrypt-only state, call Promote to start using it | 以仅解密状态添加密钥，调用 Promote 后开始使用
+func (r *KeyRing) Add(id string, key []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(RingKey{Id: id, Key: key, Status: KeyStatusDecryptOnly})
+}
+
+// Promote makes the key active and demotes the previous one to decrypt-only | 激活密钥，原激活密钥降级为仅解密
+func (r *KeyRing) Promote(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return errors.New(MsgErrKeyNotFound)
+	}
+	if key.Status == KeyStatusRetired {
+		return errors.New(MsgErrKeyNotUsable)
+	}
+	now := gtime.Now().TimestampMilli()
+	if previous, ok := r.keys[r.activeId]; ok && previous.Id != id {
+		previous.Status, previous.UpdateTime = KeyStatusDecryptOnly, now
+	}
+	key.Status, key.UpdateTime = KeyStatusActive, now
+	r.activeId = id
+	return nil
+}
+
+// Retire stops accepting tokens encrypted with the key | 退役密钥，不再接受其加密的 Token
+func (r *KeyRing) Retire(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return errors.New(MsgErrKeyNotFound)
+	}
+	if id == r.activeId {
+		return errors.New(MsgErrKeyActive)
+	}
+	key.Status, key.UpdateTime = KeyStatusRetired, gtime.Now().TimestampMilli()
+	return nil
+}
+
+// Remove drops a non-active key from the ring | 从密钥环中移除非激活密钥
+func (r *KeyRing) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[id]; !ok {
+		return errors.New(MsgErrKeyNotFound)
+	}
+	if id == r.activeId {
+		return errors.New(MsgErrKeyActive)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// Update makes the ring hold exactly keys: new ids are added, statuses follow keys and missing ids are removed | 使密钥环与 keys 一致：新增 ID、状态跟随 keys、移除缺失的 ID
+// Existing ids must keep their key material and retired keys stay retired, the ring is unchanged on error | 已有 ID 必须保持密钥内容不变，已退役密钥不可恢复，出错时密钥环保持不变
+func (r *KeyRing) Update(keys ...RingKey) error {
+	next, err := NewKeyRing(keys...)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := gtime.Now().TimestampMilli()
+	for id, key := range next.keys {
+		previous, ok := r.keys[id]
+		if !ok {
+			continue
+		}
+		if !bytes.Equal(previous.Key, key.Key) {
+			return errors.New(MsgErrKeyChanged)
+		}
+		if previous.Status == KeyStatusRetired && key.Status != KeyStatusRetired {
+			return errors.New(MsgErrKeyNotUsable)
+		}
+		key.UpdateTime = previous.UpdateTime
+		if previous.Status != key.Status {
+			key.UpdateTime = now
+		}
+	}
+	r.keys, r.activeId = next.keys, next.activeId
+	return nil
+}
+
+// Prune removes non-active keys demoted more than maxAge ms ago, returns removed ids | 移除降级超过 maxAge 毫秒的非激活密钥，返回被移除的 ID
+// Use the longest token lifetime as maxAge so no live token loses its key | 以最长 Token 有效期作为 maxAge，确保有效 Token 不会丢失密钥
+func (r *KeyRing) Prune(maxAge int64) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var (
+		removed  []string
+		deadline = gtime.Now().TimestampMilli() - maxAge
+	)
+	for id, key := range r.keys {
+		if id != r.activeId && key.UpdateTime <= deadline {
+			delete(r.keys, id)
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Active returns the active key | 返回当前激活密钥
+func (r *KeyRing) Active() RingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return *r.keys[r.activeId]
+}
+
+// Lookup returns a key usable for decryption | 返回可用于解密的密钥
+func (r *KeyRing) Lookup(id string) ([]byte, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok || key.Status == KeyStatusRetired {
+		return nil, false
+	}
+	return key.Key, true
+}
+
+// List returns a snapshot of all keys ordered by id | 按 ID 顺序返回所有密钥的快照
+func (r *KeyRing) List() []RingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]RingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys
+}
+
+// add validates and stores a key, caller must hold the lock | 校验并保存密钥，调用方需持有锁
+func (r *KeyRing) add(key RingKey) error {
+	if key.Id == "" {
+		return errors.New(MsgErrKeyIdEmpty)
+	}
+	if len(key.Id) > 255 {
+		return errors.New(MsgErrKeyIdLen)
+	}
+	if _, ok := r.keys[key.Id]; ok {
+		return errors.New(MsgErrKeyExists)
+	}
+	if len(key.Key) != 16 && len(key.Key) != 24 && len(key.Key) != 32 {
+		return errors.New(MsgErrKeySize)
+	}
+	if key.Status != KeyStatusActive && key.Status != KeyStatusDecryptOnly && key.Status != KeyStatusRetired {
+		return errors.New(MsgErrKeyStatus)
+	}
+	if key.UpdateTime == 0 {
+		key.UpdateTime = gtime.Now().TimestampMilli()
+	}
+	key.Key = append([]byte(nil), key.Key...)
+	r.keys[key.Id] = &key
+	return nil
+}
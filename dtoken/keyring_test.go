@@ -0,0 +1,55 @@
+package dtoken
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeyRing_Rotation(t *testing.T) {
+	ctx := context.Background()
+	ring, err := NewKeyRing(RingKey{Id: "k1", Key: []byte(DefaultEncryptKey), Status: KeyStatusActive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := &AEADCodec{KeyRing: ring}
+
+	oldToken, err := codec.Encode(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate without invalidating issued tokens
+	if err = ring.Add("k2", []byte("abcdefghijklmnop")); err != nil {
+		t.Fatal(err)
+	}
+	if err = ring.Promote("k2"); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := codec.Encode(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userKey, err := codec.Decrypt(ctx, oldToken); err != nil || userKey != "alice" {
+		t.Fatalf("old token should still decrypt: %q %v", userKey, err)
+	}
+	if userKey, err := codec.Decrypt(ctx, newToken); err != nil || userKey != "bob" {
+		t.Fatalf("new token should decrypt: %q %v", userKey, err)
+	}
+
+	// Retire and drop the old key
+	if err = ring.Retire("k2"); err == nil {
+		t.Fatal("active key must not be retired")
+	}
+	if err = ring.Retire("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = codec.Decrypt(ctx, oldToken); err == nil {
+		t.Fatal("token of retired key must be rejected")
+	}
+	if removed := ring.Prune(0); len(removed) != 1 || removed[0] != "k1" {
+		t.Fatalf("unexpected pruned keys: %v", removed)
+	}
+	if keys := ring.List(); len(keys) != 1 || keys[0].Id != "k2" || keys[0].Status != KeyStatusActive {
+		t.Fatalf("unexpected keys: %+v", keys)
+	}
+}
@@ -4,42 +4,75 @@ import (
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/net/ghttp"
-	"github.com/gogf/gf/v2/text/gstr"
-	"strings"
+	"net/http"
 )
 
 // Middleware defines the authentication middleware | 认证中间件结构体
 type Middleware struct {
-	Token  Token                  // Token instance | Token 实例
-	ResFun func(r *ghttp.Request) // Custom response for validation failure | 自定义 Token 校验失败响应方法
+	Token          Token                             // Token instance | Token 实例
+	ResFun         func(r *ghttp.Request)            // Custom response for validation failure, used when ErrorFun is nil | 自定义 Token 校验失败响应方法，ErrorFun 为 nil 时使用
+	Authorizer     *Authorizer                       // Role and permission checks (nil skips authorization) | 角色与权限校验（为 nil 时跳过授权）
+	ForbiddenFun   func(r *ghttp.Request)            // Custom response when permission is denied, used when ErrorFun is nil | 自定义权限不足响应方法，ErrorFun 为 nil 时使用
+	ErrorFun       func(r *ghttp.Request, err error) // Custom response receiving the failure, see ErrorStatus | 接收失败原因的自定义响应方法，参见 ErrorStatus
+	Extractor      TokenExtractor                    // Token extraction chain (nil uses DefaultTokenLookup) | Token 提取链（为 nil 时使用 DefaultTokenLookup）
+	ExcludeRules   *PathRules                        // Compiled exclude rules (nil follows the token's PathRules or scans AuthExcludePaths) | 编译后的免认证规则（为 nil 时使用 Token 的 PathRules 或逐条匹配 AuthExcludePaths）
+	TenantResolver TenantResolver                    // Tenant resolution chain (nil disables tenant scoping) | 租户解析链（为 nil 时不按租户隔离）
 }
 
 // NewDefaultMiddleware creates a middleware instance | 创建默认中间件实例
-// If resFun is provided, it will be used as the custom response handler | 如果传入 resFun，将使用自定义响应函数
+// If resFun is provided, it will be used as the custom response handler, otherwise DefaultErrorFun is used | 如果传入 resFun，将使用自定义响应函数，否则使用 DefaultErrorFun
+// The extractor follows TokenLookup, authorization is enabled when AuthRules are configured | 提取链遵循 TokenLookup，配置了 AuthRules 时启用授权
 func NewDefaultMiddleware(token Token, resFun ...func(r *ghttp.Request)) Middleware {
+	options := token.GetOptions()
+	m := Middleware{
+		Token:          token,
+		ForbiddenFun:   DefaultForbiddenFun,
+		Extractor:      mustExtractorByOptions(options),
+		ExcludeRules:   mustPathRulesByOptions(options),
+		TenantResolver: mustTenantResolverByOptions(options),
+	}
+	if _, ok := token.(PathRulesProvider); ok {
+		m.ExcludeRules = nil // Follow rules reloaded by the token | 跟随 Token 重新加载的规则
+	}
+	if len(options.AuthRules) > 0 {
+		m.Authorizer = mustAuthorizerByOptions(options)
+	}
 	if len(resFun) > 0 {
-		return Middleware{
-			Token:  token,
-			ResFun: resFun[0],
-		}
-	}
-
-	// Default error response when validation fails | 默认 Token 校验失败响应
-	return Middleware{
-		Token: token,
-		ResFun: func(r *ghttp.Request) {
-			r.Response.WriteJson(ghttp.DefaultHandlerResponse{
-				Code:    gcode.CodeInternalError.Code(),
-				Message: gcode.CodeInternalError.Message(),
-				Data:    []interface{}{},
-			})
-		},
+		m.ResFun = resFun[0]
+		return m
+	}
+	m.ErrorFun = DefaultErrorFun
+	return m
+}
+
+// DefaultErrorFun responds 401, 403 or 500 by ErrorStatus with a WWW-Authenticate challenge | 按 ErrorStatus 返回 401、403 或 500，并附带 WWW-Authenticate 质询
+func DefaultErrorFun(r *ghttp.Request, err error) {
+	code := ErrorCode(err)
+	if challenge := WWWAuthenticate(err); challenge != "" {
+		r.Response.Header().Set("WWW-Authenticate", challenge)
 	}
+	r.Response.WriteStatus(ErrorStatus(err))
+	r.Response.ClearBuffer()
+	r.Response.WriteJson(ghttp.DefaultHandlerResponse{
+		Code:    code.Code(),
+		Message: code.Message(),
+		Data:    []interface{}{},
+	})
+}
+
+// DefaultForbiddenFun responds 403 when permission is denied | 权限不足时返回 403
+func DefaultForbiddenFun(r *ghttp.Request) {
+	r.Response.WriteStatus(http.StatusForbidden)
+	r.Response.ClearBuffer()
+	r.Response.WriteJson(ghttp.DefaultHandlerResponse{
+		Code:    CodeForbidden.Code(),
+		Message: CodeForbidden.Message(),
+		Data:    []interface{}{},
+	})
 }
 
 // Auth performs token authentication for requests | 执行请求认证拦截
-// If validation fails, returns a unified error response | 校验失败返回统一错误响应
-// Error Code: gcode.CodeBusinessValidationFailed | 错误码：gcode.CodeBusinessValidationFailed
+// If validation fails, the failure is passed to ErrorFun | 校验失败时将失败原因交给 ErrorFun 处理
 func (m Middleware) Auth(r *ghttp.Request) {
 	// Skip authentication if path is excluded | 路径在排除列表中则跳过认证
 	if m.HasExcludePath(r) {
@@ -47,95 +80,97 @@ func (m Middleware) Auth(r *ghttp.Request) {
 		return
 	}
 
-	// Extract token from request | 从请求中获取 Token
-	token, err := GetRequestToken(r)
+	// Scope token to the tenant of the request | 将 Token 限定在请求所属租户内
+	token, tenantId, err := ResolveTenant(m.Token, m.TenantResolver, NewGHttpCarrier(r))
 	if err != nil {
-		m.ResFun(r)
+		m.fail(r, err)
 		return
 	}
 
-	// Validate token | 校验 Token 合法性
-	userCacheValue, err := m.Token.Validate(r.Context(), token)
+	// Validate token and check roles and permissions | 校验 Token 合法性及角色权限
+	session, grants, err := Authenticate(token, m.Extractor, m.Authorizer, NewGHttpCarrier(r))
 	if err != nil {
-		m.ResFun(r)
+		m.fail(r, err)
 		return
 	}
 
+	// Re-issue session cookies once the session enters the renew window | 会话进入续期窗口后重新下发 Cookie
+	if options := token.GetOptions(); shouldRefreshCookie(options, NewGHttpCarrier(r), session, tokenNow(token)) {
+		setRequestCookies(r, SessionCookies(options, session.Token))
+	}
+
 	// Store user info in request context | 将用户数据存入请求上下文
-	r.SetCtxVar(KeyUserKey, userCacheValue)
+	ctx := WithSession(r.Context(), session, grants)
+	if tenantId != "" {
+		ctx = WithTenant(ctx, tenantId)
+	}
+	r.SetCtx(ctx)
+	r.SetCtxVar(KeyUserKey, session.Data)
+	if grants != nil {
+		r.SetCtxVar(KeyGrants, grants)
+	}
 
 	// Continue request | 执行后续中间件链
 	r.Middleware.Next()
 }
 
+// fail writes the failure response, falling back to ResFun and ForbiddenFun without ErrorFun | 输出失败响应，未设置 ErrorFun 时回退到 ResFun 与 ForbiddenFun
+func (m Middleware) fail(r *ghttp.Request, err error) {
+	switch {
+	case m.ErrorFun != nil:
+		m.ErrorFun(r, err)
+	case ErrorStatus(err) == http.StatusForbidden && m.ForbiddenFun != nil:
+		m.ForbiddenFun(r)
+	case ErrorStatus(err) != http.StatusForbidden && m.ResFun != nil:
+		m.ResFun(r)
+	default:
+		DefaultErrorFun(r, err)
+	}
+}
+
+// Login generates a session token and issues it as HttpOnly and CSRF cookies | 生成会话 Token 并以 HttpOnly Cookie 与 CSRF Cookie 下发
+// Cookie mode must be enabled with Options.CookieName | 需通过 Options.CookieName 启用 Cookie 模式
+func (m Middleware) Login(r *ghttp.Request, userKey, deviceId string, data any) (token string, err error) {
+	scoped, _, err := ResolveTenant(m.Token, m.TenantResolver, NewGHttpCarrier(r))
+	if err != nil {
+		return "", err
+	}
+	options := scoped.GetOptions()
+	if options.CookieName == "" {
+		return "", gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrCookieOff)
+	}
+	if token, err = scoped.GenerateWithDevice(r.Context(), userKey, deviceId, data); err != nil {
+		return "", err
+	}
+	setRequestCookies(r, SessionCookies(options, token))
+	return token, nil
+}
+
+// Logout destroys the session of the request and clears its cookies | 销毁请求对应的会话并清除 Cookie
+func (m Middleware) Logout(r *ghttp.Request) error {
+	scoped, _, err := ResolveTenant(m.Token, m.TenantResolver, NewGHttpCarrier(r))
+	if err != nil {
+		return err
+	}
+	setRequestCookies(r, ExpiredSessionCookies(scoped.GetOptions()))
+	return logout(scoped, m.Extractor, NewGHttpCarrier(r))
+}
+
+// setRequestCookies queues cookies on the ghttp response | 将 Cookie 加入 ghttp 响应
+func setRequestCookies(r *ghttp.Request, cookies []*http.Cookie) {
+	for _, cookie := range cookies {
+		r.Cookie.SetHttpCookie(cookie)
+	}
+}
+
 // HasExcludePath determines if the current request path should bypass authentication | 判断路径是否应跳过认证
 // @return true: skip authentication | true 表示不需要认证
 func (m Middleware) HasExcludePath(r *ghttp.Request) bool {
-	var (
-		urlPath      = r.URL.Path
-		excludePaths = m.Token.GetOptions().AuthExcludePaths
-	)
-
-	// No exclusion rules configured | 未配置排除路径
-	if len(excludePaths) == 0 {
-		return false
-	}
-
-	// Remove trailing slash | 去除路径末尾斜杠
-	if strings.HasSuffix(urlPath, "/") {
-		urlPath = gstr.SubStr(urlPath, 0, len(urlPath)-1)
-	}
-
-	// Iterate through exclude paths | 遍历排除路径规则
-	for _, excludePath := range excludePaths {
-		tmpPath := excludePath
-
-		// Prefix match: e.g., "/api/*" | 前缀匹配（如 /api/*）
-		if strings.HasSuffix(tmpPath, "/*") {
-			tmpPath = gstr.SubStr(tmpPath, 0, len(tmpPath)-2)
-			if gstr.HasPrefix(urlPath, tmpPath) {
-				// Path matches prefix -> skip authentication | 匹配前缀路径则跳过认证
-				return true
-			}
-		} else {
-			// Full path match | 全路径匹配
-			if strings.HasSuffix(tmpPath, "/") {
-				tmpPath = gstr.SubStr(tmpPath, 0, len(tmpPath)-1)
-			}
-			if urlPath == tmpPath {
-				// Exact match -> skip authentication | 精确匹配则跳过认证
-				return true
-			}
-		}
-	}
-
-	// No exclusion match -> require authentication | 未匹配排除规则则需认证
-	return false
+	return isExcluded(m.ExcludeRules, m.Token, r.Method, r.URL.Path)
 }
 
 // GetRequestToken extracts token from HTTP request | 从 HTTP 请求中提取 Token
 // Supported methods: Header("Authorization: Bearer <token>") or param "token" | 支持 Header 方式和参数方式
 func GetRequestToken(r *ghttp.Request) (string, error) {
-	// 1. Try Authorization header | 优先从 Authorization 头中获取
-	authHeader := r.Header.Get("Authorization")
-	if authHeader != "" {
-		parts := strings.SplitN(authHeader, " ", 2)
-
-		// Validate Bearer token format | 校验 Bearer 格式是否正确
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			return "", gerror.NewCode(gcode.CodeInvalidParameter, "Bearer param invalid | Bearer 参数格式错误")
-		} else if parts[1] == "" {
-			return "", gerror.NewCode(gcode.CodeInvalidParameter, "Bearer param empty | Bearer 参数为空")
-		}
-
-		return parts[1], nil
-	}
-
-	// 2. Fallback to token parameter | 尝试从请求参数中读取 Token
-	authHeader = r.Get(KeyToken).String()
-	if authHeader == "" {
-		return "", gerror.NewCode(gcode.CodeMissingParameter, "token empty | 缺少 token 参数")
-	}
-
-	return authHeader, nil
+	return GetCarrierToken(NewGHttpCarrier(r))
 }
@@ -0,0 +1,163 @@
+package dtoken
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"net/http"
+)
+
+// HTTPMiddleware is the authentication middleware for net/http, chi, gin and alike | 适用于 net/http、chi、gin 等框架的认证中间件
+type HTTPMiddleware struct {
+	Token          Token                                                   // Token instance | Token 实例
+	Authorizer     *Authorizer                                             // Role and permission checks (nil skips authorization) | 角色与权限校验（为 nil 时跳过授权）
+	ResFun         func(w http.ResponseWriter, r *http.Request, err error) // Custom response for validation failure and server error | 自定义 Token 校验失败及服务端错误响应方法
+	ForbiddenFun   func(w http.ResponseWriter, r *http.Request, err error) // Custom response when permission is denied | 自定义权限不足响应方法
+	Extractor      TokenExtractor                                          // Token extraction chain (nil uses DefaultTokenLookup) | Token 提取链（为 nil 时使用 DefaultTokenLookup）
+	ExcludeRules   *PathRules                                              // Compiled exclude rules (nil follows the token's PathRules or scans AuthExcludePaths) | 编译后的免认证规则（为 nil 时使用 Token 的 PathRules 或逐条匹配 AuthExcludePaths）
+	TenantResolver TenantResolver                                          // Tenant resolution chain (nil disables tenant scoping) | 租户解析链（为 nil 时不按租户隔离）
+}
+
+// NewHTTPMiddleware creates a net/http middleware instance | 创建 net/http 中间件实例
+// The extractor follows TokenLookup, authorization is enabled when AuthRules are configured | 提取链遵循 TokenLookup，配置了 AuthRules 时启用授权
+func NewHTTPMiddleware(token Token) HTTPMiddleware {
+	options := token.GetOptions()
+	m := HTTPMiddleware{
+		Token:          token,
+		ResFun:         DefaultHTTPResFun,
+		ForbiddenFun:   DefaultHTTPForbiddenFun,
+		Extractor:      mustExtractorByOptions(options),
+		ExcludeRules:   mustPathRulesByOptions(options),
+		TenantResolver: mustTenantResolverByOptions(options),
+	}
+	if _, ok := token.(PathRulesProvider); ok {
+		m.ExcludeRules = nil // Follow rules reloaded by the token | 跟随 Token 重新加载的规则
+	}
+	if len(options.AuthRules) > 0 {
+		m.Authorizer = mustAuthorizerByOptions(options)
+	}
+	return m
+}
+
+// Handler wraps next with authentication, usable as func(http.Handler) http.Handler | 为 next 添加认证，可作为 func(http.Handler) http.Handler 使用
+// The session is available through SessionFromContext in next | 在 next 中可通过 SessionFromContext 获取会话
+func (m HTTPMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Skip authentication if path is excluded | 路径在排除列表中则跳过认证
+		if m.hasExcludePath(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Scope token to the tenant of the request | 将 Token 限定在请求所属租户内
+		token, tenantId, err := ResolveTenant(m.Token, m.TenantResolver, NewHTTPCarrier(r))
+		if err != nil {
+			m.unauthorized(w, r, err)
+			return
+		}
+
+		// Validate token and check roles and permissions | 校验 Token 合法性及角色权限
+		session, grants, err := Authenticate(token, m.Extractor, m.Authorizer, NewHTTPCarrier(r))
+		if err != nil {
+			if ErrorStatus(err) == http.StatusForbidden {
+				m.forbidden(w, r, err)
+			} else {
+				m.unauthorized(w, r, err)
+			}
+			return
+		}
+
+		// Re-issue session cookies once the session enters the renew window | 会话进入续期窗口后重新下发 Cookie
+		if options := token.GetOptions(); shouldRefreshCookie(options, NewHTTPCarrier(r), session, tokenNow(token)) {
+			SetSessionCookies(w, options, session.Token)
+		}
+
+		// Store session in request context | 将会话存入请求上下文
+		ctx := WithSession(r.Context(), session, grants)
+		if tenantId != "" {
+			ctx = WithTenant(ctx, tenantId)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Login generates a session token and issues it as HttpOnly and CSRF cookies | 生成会话 Token 并以 HttpOnly Cookie 与 CSRF Cookie 下发
+// Cookie mode must be enabled with Options.CookieName | 需通过 Options.CookieName 启用 Cookie 模式
+func (m HTTPMiddleware) Login(w http.ResponseWriter, r *http.Request, userKey, deviceId string, data any) (token string, err error) {
+	scoped, _, err := ResolveTenant(m.Token, m.TenantResolver, NewHTTPCarrier(r))
+	if err != nil {
+		return "", err
+	}
+	options := scoped.GetOptions()
+	if options.CookieName == "" {
+		return "", gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrCookieOff)
+	}
+	if token, err = scoped.GenerateWithDevice(r.Context(), userKey, deviceId, data); err != nil {
+		return "", err
+	}
+	SetSessionCookies(w, options, token)
+	return token, nil
+}
+
+// Logout destroys the session of the request and clears its cookies | 销毁请求对应的会话并清除 Cookie
+func (m HTTPMiddleware) Logout(w http.ResponseWriter, r *http.Request) error {
+	scoped, _, err := ResolveTenant(m.Token, m.TenantResolver, NewHTTPCarrier(r))
+	if err != nil {
+		return err
+	}
+	ClearSessionCookies(w, scoped.GetOptions())
+	return logout(scoped, m.Extractor, NewHTTPCarrier(r))
+}
+
+// hasExcludePath determines if the request should bypass authentication | 判断请求是否应跳过认证
+func (m HTTPMiddleware) hasExcludePath(r *http.Request) bool {
+	return isExcluded(m.ExcludeRules, m.Token, r.Method, r.URL.Path)
+}
+
+// unauthorized writes the validation failure response | 输出 Token 校验失败响应
+func (m HTTPMiddleware) unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if m.ResFun != nil {
+		m.ResFun(w, r, err)
+		return
+	}
+	DefaultHTTPResFun(w, r, err)
+}
+
+// forbidden writes the permission denied response | 输出权限不足响应
+func (m HTTPMiddleware) forbidden(w http.ResponseWriter, r *http.Request, err error) {
+	if m.ForbiddenFun != nil {
+		m.ForbiddenFun(w, r, err)
+		return
+	}
+	DefaultHTTPForbiddenFun(w, r, err)
+}
+
+// DefaultHTTPResFun responds 401 when token validation fails and 500 on server errors | Token 校验失败时返回 401，服务端错误时返回 500
+func DefaultHTTPResFun(w http.ResponseWriter, r *http.Request, err error) {
+	writeHTTPError(w, err)
+}
+
+// DefaultHTTPForbiddenFun responds 403 when permission is denied | 权限不足时返回 403
+func DefaultHTTPForbiddenFun(w http.ResponseWriter, r *http.Request, err error) {
+	writeHTTPError(w, err)
+}
+
+// writeHTTPError writes the ErrorStatus response with a WWW-Authenticate challenge | 输出 ErrorStatus 对应的响应并附带 WWW-Authenticate 质询
+func writeHTTPError(w http.ResponseWriter, err error) {
+	code := ErrorCode(err)
+	if challenge := WWWAuthenticate(err); challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	writeHTTPJson(w, ErrorStatus(err), code.Code(), code.Message())
+}
+
+// writeHTTPJson writes a response shaped like ghttp.DefaultHandlerResponse | 输出与 ghttp.DefaultHandlerResponse 结构一致的响应
+func writeHTTPJson(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"code":    code,
+		"message": message,
+		"data":    []any{},
+	})
+}
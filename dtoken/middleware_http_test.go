@@ -0,0 +1,58 @@
+package dtoken
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHTTPMiddleware_Handler(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{
+		AuthExcludePaths: []string{"/public/*"},
+		AuthRules:        []AuthRule{{Path: "/admin/*", Roles: []string{"admin"}}},
+	})
+	middleware := NewHTTPMiddleware(token)
+
+	var gotUserKey string
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserKey, _ = UserKeyFromContext(r.Context())
+		if data, ok := DataFromContext(r.Context()); ok && data != "d" {
+			t.Errorf("unexpected data %v", data)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	userToken, err := token.GenerateWithDevice(ctx, "alice", "web", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path, header string
+		status       int
+	}{
+		{"/public/ping", "", http.StatusOK},
+		{"/orders", "", http.StatusUnauthorized},
+		{"/orders", "Bearer bad", http.StatusUnauthorized},
+		{"/orders", "Bearer " + userToken, http.StatusOK},
+		{"/orders?token=" + url.QueryEscape(userToken), "", http.StatusOK},
+		{"/admin/users", "Bearer " + userToken, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Fatalf("%s %q: expected %d, got %d", c.path, c.header, c.status, rec.Code)
+		}
+	}
+	if gotUserKey != "alice" {
+		t.Fatalf("expected userKey in context, got %q", gotUserKey)
+	}
+}
@@ -0,0 +1,89 @@
+package dtoken
+
+import (
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// Option configures a token created by New | 配置 New 创建的 Token
+type Option func(b *builder)
+
+// builder collects the settings of New | 收集 New 的构建参数
+type builder struct {
+	options        Options
+	codec          Codec
+	cache          Cache
+	refreshCache   Cache
+	revokeCache    Cache
+	renewPool      *RenewPoolManager
+	logger         *glog.Logger
+	clock          Clock
+	bannerDisabled bool
+}
+
+// WithOptions sets the config options, defaults apply to unset fields | 设置配置参数，未设置的字段使用默认值
+func WithOptions(options Options) Option {
+	return func(b *builder) {
+		b.options = options
+	}
+}
+
+// WithCodec replaces the codec selected by CodecMode | 替换 CodecMode 指定的编解码器
+func WithCodec(codec Codec) Option {
+	return func(b *builder) {
+		b.codec = codec
+	}
+}
+
+// WithCache replaces the session cache selected by CacheMode | 替换 CacheMode 指定的会话缓存
+// The token owns the cache and closes it on Shutdown if it implements io.Closer | Token 持有该缓存，若实现 io.Closer 则在 Shutdown 时关闭
+func WithCache(cache Cache) Option {
+	return func(b *builder) {
+		b.cache = cache
+	}
+}
+
+// WithRefreshCache replaces the refresh token cache selected by CacheMode | 替换 CacheMode 指定的刷新令牌缓存
+func WithRefreshCache(cache Cache) Option {
+	return func(b *builder) {
+		b.refreshCache = cache
+	}
+}
+
+// WithRevokeCache replaces the revocation cache selected by CacheMode | 替换 CacheMode 指定的吊销列表缓存
+func WithRevokeCache(cache Cache) Option {
+	return func(b *builder) {
+		b.revokeCache = cache
+	}
+}
+
+// WithRenewPool replaces the renew pool built from Pool* options, it is stopped on Shutdown | 替换按 Pool* 配置构建的续期协程池，Shutdown 时会被停止
+func WithRenewPool(pool *RenewPoolManager) Option {
+	return func(b *builder) {
+		b.renewPool = pool
+	}
+}
+
+// WithLogger sets the logger of config warnings and token errors | 设置配置告警与 Token 错误的日志器
+func WithLogger(logger *glog.Logger) Option {
+	return func(b *builder) {
+		if logger != nil {
+			b.logger = logger
+		}
+	}
+}
+
+// WithClock sets the time source of token logic and in-memory cache expiry | 设置 Token 逻辑与内存缓存过期的时间来源
+func WithClock(clock Clock) Option {
+	return func(b *builder) {
+		if clock != nil {
+			b.clock = clock
+		}
+	}
+}
+
+// WithBannerDisabled skips printing the banner and configuration | 不打印 Banner 与配置信息
+func WithBannerDisabled() Option {
+	return func(b *builder) {
+		b.bannerDisabled = true
+	}
+}
@@ -0,0 +1,73 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/frame/g"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCache counts Set calls of the wrapped cache
+type countingCache struct {
+	Cache
+	sets atomic.Int32
+}
+
+func (c *countingCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	c.sets.Add(1)
+	return c.Cache.Set(ctx, cacheKey, cacheValue)
+}
+
+func TestNew_WithComponents(t *testing.T) {
+	ctx := context.Background()
+	codec := NewAEADCodec([]byte(DefaultEncryptKey))
+	cache := &countingCache{Cache: NewDefaultCache(CacheModeCache, "Test:"+t.Name()+":", DefaultTimeout)}
+	pool, err := NewRenewPoolBuilder().MinSize(1).MaxSize(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.UnixMilli(1700000000000)
+
+	token, err := New(
+		WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":"}),
+		WithCodec(codec),
+		WithCache(cache),
+		WithRenewPool(pool),
+		WithClock(ClockFunc(func() time.Time { return now })),
+		WithBannerDisabled(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(ctx)
+
+	gfToken := token.(*GTokenV2)
+	if gfToken.Codec != codec || gfToken.Cache != cache || gfToken.RenewPoolManager != pool {
+		t.Fatal("expected injected components to be used")
+	}
+	if gfToken.RefreshCache == nil || gfToken.RevokeCache == nil {
+		t.Fatal("expected missing caches to be built from options")
+	}
+	if gfToken.Options.Timeout != DefaultTimeout {
+		t.Fatalf("expected defaults to apply, got Timeout=%d", gfToken.Options.Timeout)
+	}
+
+	if _, err = token.Generate(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	if cache.sets.Load() == 0 {
+		t.Fatal("expected injected cache to store the session")
+	}
+	sessions, err := token.ListSessions(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].CreateTime != now.UnixMilli() {
+		t.Fatalf("expected session created at clock time, got %+v", sessions)
+	}
+
+	if _, err = New(WithOptions(Options{CacheMode: 9}), WithCodec(codec), WithBannerDisabled()); err == nil {
+		t.Fatal("expected validation to run with injected components")
+	}
+}
@@ -0,0 +1,262 @@
+package dtoken
+
+import (
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gstr"
+	"regexp"
+	"strings"
+)
+
+// Path rule syntax | 路径规则语法
+//
+//	[METHOD[,METHOD] ]pattern
+//
+//	/user/login        exact path | 精确路径
+//	/user/{id}         named parameter, one segment | 命名参数，匹配单段
+//	/user/*/profile    any single segment | 匹配任意单段
+//	/static/**         any number of segments, including none | 匹配任意多段（含零段）
+//	/public/*          trailing "/*" matches the prefix and everything below | 末尾 "/*" 匹配前缀及其所有子路径
+//	~^/api/v[0-9]+/    regular expression on the full path | 对完整路径的正则表达式
+//	GET,HEAD /articles/* only for the listed methods | 仅对列出的方法生效
+const (
+	pathSegmentAny   = "*"  // Matches one segment | 匹配单段
+	pathSegmentMulti = "**" // Matches any number of segments | 匹配任意多段
+	pathRegexPrefix  = "~"  // Prefix of regular expression rules | 正则规则前缀
+)
+
+// PathRules is a precompiled set of exclude rules with include overrides | 预编译的排除规则集合，支持包含覆盖
+// Glob rules are matched through a segment trie, regex rules are scanned in order | 通配规则通过分段前缀树匹配，正则规则按顺序扫描
+type PathRules struct {
+	exclude *pathRuleSet
+	include *pathRuleSet
+}
+
+// PathRulesProvider is implemented by tokens keeping compiled path rules of their current options | 持有当前配置编译后路径规则的 Token 实现该接口
+type PathRulesProvider interface {
+	PathRules() *PathRules // Rules of the current options, nil if they cannot be compiled | 当前配置的路径规则，无法编译时为 nil
+}
+
+// NewPathRules compiles exclude rules and the include rules overriding them | 编译排除规则及覆盖它们的包含规则
+func NewPathRules(excludes, includes []string) (*PathRules, error) {
+	exclude, err := newPathRuleSet(excludes)
+	if err != nil {
+		return nil, err
+	}
+	include, err := newPathRuleSet(includes)
+	if err != nil {
+		return nil, err
+	}
+	return &PathRules{exclude: exclude, include: include}, nil
+}
+
+// NewPathRulesByOptions compiles AuthExcludePaths and AuthIncludePaths | 编译 AuthExcludePaths 与 AuthIncludePaths
+func NewPathRulesByOptions(options Options) (*PathRules, error) {
+	return NewPathRules(options.AuthExcludePaths, options.AuthIncludePaths)
+}
+
+// mustPathRulesByOptions compiles path rules, panicking on invalid config | 编译路径规则，配置错误时 panic
+func mustPathRulesByOptions(options Options) *PathRules {
+	rules, err := NewPathRulesByOptions(options)
+	if err != nil {
+		panic("invalid config: " + err.Error() + " | AuthExcludePaths 或 AuthIncludePaths 配置错误")
+	}
+	return rules
+}
+
+// isExcluded checks rules, falling back to the token's PathRules and then to AuthExcludePaths | 使用 rules 判断，依次回退到 Token 的 PathRules 与 AuthExcludePaths
+func isExcluded(rules *PathRules, token Token, method, urlPath string) bool {
+	if rules == nil {
+		if provider, ok := token.(PathRulesProvider); ok {
+			rules = provider.PathRules()
+		}
+	}
+	if rules != nil {
+		return rules.IsExcluded(method, urlPath)
+	}
+	return IsExcludePath(token.GetOptions().AuthExcludePaths, urlPath)
+}
+
+// IsExcluded reports whether the request bypasses authentication | 判断请求是否跳过认证
+// A request is excluded when an exclude rule matches and no include rule does | 命中排除规则且未命中包含规则时跳过认证
+func (p *PathRules) IsExcluded(method, urlPath string) bool {
+	if p == nil {
+		return false
+	}
+	segments := splitPath(urlPath)
+	return p.exclude.match(method, urlPath, segments) && !p.include.match(method, urlPath, segments)
+}
+
+// matchPath matches urlPath against a single rule ignoring method lists, invalid rules never match | 使用单条规则匹配路径（不含方法列表），非法规则永不匹配
+func matchPath(rule, urlPath string) bool {
+	set, err := newPathRuleSet([]string{rule})
+	if err != nil {
+		return false
+	}
+	return set.match("", urlPath, splitPath(urlPath))
+}
+
+// pathRuleSet holds compiled glob and regex rules | 保存编译后的通配与正则规则
+type pathRuleSet struct {
+	root    *pathNode
+	regexes []*regexRule
+}
+
+// regexRule is a compiled regular expression rule | 编译后的正则规则
+type regexRule struct {
+	methods map[string]struct{}
+	regex   *regexp.Regexp
+}
+
+// pathNode is a trie node keyed by path segment | 以路径段为键的前缀树节点
+type pathNode struct {
+	static   map[string]*pathNode // Literal segments | 字面量段
+	any      *pathNode            // "*" and "{param}" segments | "*" 与 "{param}" 段
+	multi    *pathNode            // "**" segments | "**" 段
+	terminal bool                 // A rule ends here | 有规则在此结束
+	methods  map[string]struct{}  // Methods of rules ending here (nil = all) | 在此结束的规则的方法（nil 表示全部）
+}
+
+// newPathRuleSet compiles rules into a rule set | 将规则编译为规则集合
+func newPathRuleSet(rules []string) (*pathRuleSet, error) {
+	set := &pathRuleSet{root: &pathNode{}}
+	for _, rule := range rules {
+		if err := set.add(rule); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// add compiles a single rule into the set | 将单条规则编译进集合
+func (s *pathRuleSet) add(rule string) error {
+	methods, pattern := parsePathRule(rule)
+	if pattern == "" {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid path rule %q", rule)
+	}
+	if expr, ok := strings.CutPrefix(pattern, pathRegexPrefix); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return gerror.WrapCodef(gcode.CodeInvalidConfiguration, err, "invalid path rule %q", rule)
+		}
+		s.regexes = append(s.regexes, &regexRule{methods: methods, regex: regex})
+		return nil
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid path rule %q, must start with / or ~", rule)
+	}
+
+	// Legacy trailing "/*" matches the prefix and everything below | 兼容末尾 "/*" 匹配前缀及其所有子路径
+	segments := splitPath(pattern)
+	if n := len(segments); n > 0 && segments[n-1] == pathSegmentAny {
+		segments[n-1] = pathSegmentMulti
+	}
+
+	node := s.root
+	for _, segment := range segments {
+		switch {
+		case segment == pathSegmentMulti:
+			if node.multi == nil {
+				node.multi = &pathNode{}
+			}
+			node = node.multi
+		case segment == pathSegmentAny || (strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")):
+			if node.any == nil {
+				node.any = &pathNode{}
+			}
+			node = node.any
+		default:
+			if node.static == nil {
+				node.static = make(map[string]*pathNode)
+			}
+			if node.static[segment] == nil {
+				node.static[segment] = &pathNode{}
+			}
+			node = node.static[segment]
+		}
+	}
+	node.addMethods(methods)
+	return nil
+}
+
+// addMethods merges methods of a rule ending at node | 合并在该节点结束的规则的方法
+func (n *pathNode) addMethods(methods map[string]struct{}) {
+	switch {
+	case n.terminal && n.methods == nil:
+		// Already matches all methods | 已匹配全部方法
+	case methods == nil:
+		n.methods = nil
+	default:
+		if n.methods == nil {
+			n.methods = make(map[string]struct{}, len(methods))
+		}
+		for method := range methods {
+			n.methods[method] = struct{}{}
+		}
+	}
+	n.terminal = true
+}
+
+// match reports whether any rule in the set matches | 判断集合中是否有规则命中
+func (s *pathRuleSet) match(method, urlPath string, segments []string) bool {
+	if s.root.match(method, segments) {
+		return true
+	}
+	for _, rule := range s.regexes {
+		if allowMethod(rule.methods, method) && rule.regex.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// match walks the trie, backtracking over "*" and "**" branches | 遍历前缀树，对 "*" 与 "**" 分支回溯
+func (n *pathNode) match(method string, segments []string) bool {
+	if len(segments) == 0 && n.terminal && allowMethod(n.methods, method) {
+		return true
+	}
+	if n.multi != nil {
+		for i := 0; i <= len(segments); i++ {
+			if n.multi.match(method, segments[i:]) {
+				return true
+			}
+		}
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if child := n.static[segments[0]]; child != nil && child.match(method, segments[1:]) {
+		return true
+	}
+	return n.any != nil && n.any.match(method, segments[1:])
+}
+
+// allowMethod reports whether method is allowed, nil allows all | 判断方法是否允许，nil 表示全部允许
+func allowMethod(methods map[string]struct{}, method string) bool {
+	if methods == nil {
+		return true
+	}
+	_, ok := methods[gstr.ToUpper(method)]
+	return ok
+}
+
+// parsePathRule splits the optional method list from the pattern | 拆分可选的方法列表与路径模式
+func parsePathRule(rule string) (map[string]struct{}, string) {
+	rule = strings.TrimSpace(rule)
+	prefix, pattern, ok := strings.Cut(rule, " ")
+	if !ok || strings.HasPrefix(rule, "/") || strings.HasPrefix(rule, pathRegexPrefix) {
+		return nil, rule
+	}
+	methods := make(map[string]struct{})
+	for _, method := range strings.Split(prefix, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methods[gstr.ToUpper(method)] = struct{}{}
+		}
+	}
+	return methods, strings.TrimSpace(pattern)
+}
+
+// splitPath splits a path into non-empty segments | 将路径拆分为非空段
+func splitPath(urlPath string) []string {
+	return strings.FieldsFunc(urlPath, func(r rune) bool { return r == '/' })
+}
@@ -0,0 +1,87 @@
+package dtoken
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathRules_IsExcluded(t *testing.T) {
+	rules, err := NewPathRules([]string{
+		"/login",
+		"/public/*",
+		"/static/**/app.css",
+		"/user/{id}/avatar",
+		"/docs/*/index",
+		"GET,HEAD /articles/*",
+		"~^/api/v[0-9]+/health$",
+		"POST ~^/hooks/",
+	}, []string{
+		"/public/admin/*",
+		"GET /articles/drafts",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		method, path string
+		excluded     bool
+	}{
+		{http.MethodGet, "/login", true},
+		{http.MethodGet, "/login/", true},
+		{http.MethodGet, "/login/extra", false},
+		{http.MethodGet, "/public", true},
+		{http.MethodGet, "/public/a/b/c", true},
+		{http.MethodGet, "/publicity", false},
+		{http.MethodGet, "/public/admin", false},
+		{http.MethodGet, "/public/admin/users", false},
+		{http.MethodGet, "/static/app.css", true},
+		{http.MethodGet, "/static/a/b/app.css", true},
+		{http.MethodGet, "/static/a/b/app.js", false},
+		{http.MethodGet, "/user/42/avatar", true},
+		{http.MethodGet, "/user/42/profile", false},
+		{http.MethodGet, "/docs/v1/index", true},
+		{http.MethodGet, "/docs/v1/v2/index", false},
+		{http.MethodGet, "/articles/1", true},
+		{"head", "/articles/1", true},
+		{http.MethodPost, "/articles/1", false},
+		{http.MethodGet, "/articles/drafts", false},
+		{http.MethodHead, "/articles/drafts", true},
+		{http.MethodGet, "/api/v2/health", true},
+		{http.MethodGet, "/api/vx/health", false},
+		{http.MethodPost, "/hooks/github", true},
+		{http.MethodGet, "/hooks/github", false},
+		{http.MethodGet, "/orders", false},
+	}
+	for _, c := range cases {
+		if got := rules.IsExcluded(c.method, c.path); got != c.excluded {
+			t.Errorf("%s %s: expected %t, got %t", c.method, c.path, c.excluded, got)
+		}
+	}
+
+	for _, rule := range []string{"", "public", "GET", "~(", "GET  "} {
+		if _, err = NewPathRules([]string{rule}, nil); err == nil {
+			t.Errorf("expected error for rule %q", rule)
+		}
+	}
+}
+
+func TestPathRules_Multi(t *testing.T) {
+	rules, err := NewPathRules([]string{"/**", "/a/**/z"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rules.IsExcluded(http.MethodGet, "/") || !rules.IsExcluded(http.MethodGet, "/x/y") {
+		t.Fatal("expected /** to match everything")
+	}
+
+	rules, err = NewPathRules([]string{"/a/**/z"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, excluded := range map[string]bool{"/a/z": true, "/a/b/c/z": true, "/a/b/c": false, "/b/z": false} {
+		if rules.IsExcluded(http.MethodGet, path) != excluded {
+			t.Errorf("%s: expected %t", path, excluded)
+		}
+	}
+}
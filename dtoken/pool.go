@@ -190,6 +190,32 @@ func (m *RenewPoolManager) Stats() (running, capacity int, usage float64) {
 	return
 }
 
+// Tune updates size limits and scale thresholds, clamping the capacity into [minSize, maxSize] | 调整协程数上下限与扩缩容阈值，并将容量限制在 [minSize, maxSize] 内
+// Non-positive rates keep the current thresholds | 阈值不为正数时保持不变
+func (m *RenewPoolManager) Tune(minSize, maxSize int, scaleUpRate, scaleDownRate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if minSize <= 0 {
+		minSize = DefaultMinSize
+	}
+	if maxSize < minSize {
+		maxSize = minSize
+	}
+	m.config.MinSize, m.config.MaxSize = minSize, maxSize
+	if scaleUpRate > 0 {
+		m.config.ScaleUpRate = scaleUpRate
+	}
+	if scaleDownRate > 0 {
+		m.config.ScaleDownRate = scaleDownRate
+	}
+	switch capacity := m.pool.Cap(); {
+	case capacity < minSize:
+		m.pool.Tune(minSize)
+	case capacity > maxSize:
+		m.pool.Tune(maxSize)
+	}
+}
+
 // PrintStatus prints current pool status | 打印池状态
 func (m *RenewPoolManager) PrintStatus() {
 	r, c, u := m.Stats()
@@ -0,0 +1,227 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/gconv"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds named token realms, each with its own options and caches | 保存命名 Token 域，每个域拥有独立的配置与缓存
+// Realms never share cache keys: registering a realm whose CachePreKey overlaps another one fails | 各域之间不共享缓存 key：注册 CachePreKey 与其他域重叠的域会失败
+type Registry struct {
+	mu     sync.RWMutex
+	realms map[string]Token
+}
+
+// NewRegistry creates an empty registry | 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{realms: make(map[string]Token)}
+}
+
+// NewRegistryByConfig creates a registry from the gToken node of global config | 从全局配置的 gToken 节点创建注册表
+// Map nodes such as "gToken.admin" that are not option fields become named realms, options set directly under gToken form DefaultRealm
+// 非配置字段的子节点（如 "gToken.admin"）作为命名域，直接配置在 gToken 下的参数组成 DefaultRealm
+// Realms with HotReload watch their own node | 开启 HotReload 的域监听各自的节点
+func NewRegistryByConfig() (*Registry, error) {
+	data := g.Cfg().MustGet(gctx.New(), GTokenCfgName).Map()
+	base := make(g.Map, len(data))
+	realms := make(map[string]g.Map)
+	for key, value := range data {
+		if node, ok := value.(map[string]any); ok && !isOptionsField(key) {
+			realms[key] = node
+			continue
+		}
+		base[key] = value
+	}
+	if len(base) > 0 || len(realms) == 0 {
+		realms[DefaultRealm] = base
+	}
+
+	names := make([]string, 0, len(realms))
+	for name := range realms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r := NewRegistry()
+	for _, name := range names {
+		var options Options
+		if err := gconv.Struct(realms[name], &options); err != nil {
+			r.Shutdown(context.Background())
+			return nil, gerror.WrapCodef(gcode.CodeInvalidConfiguration, err, "gToken realm %q options init failed", name)
+		}
+		token, err := r.RegisterOptions(name, options)
+		if err != nil {
+			r.Shutdown(context.Background())
+			return nil, err
+		}
+		if !options.HotReload {
+			continue
+		}
+		if err = token.(*GTokenV2).WatchConfig(name); err != nil {
+			r.Shutdown(context.Background())
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// RegisterOptions creates a token with options and registers it as realm name | 使用配置创建 Token 并注册为 name 域
+// A named realm without CachePreKey uses RealmCacheKey + name + ":" | 未配置 CachePreKey 的命名域使用 RealmCacheKey + name + ":"
+func (r *Registry) RegisterOptions(name string, options Options, opts ...Option) (Token, error) {
+	if err := checkRealmName(name); err != nil {
+		return nil, err
+	}
+	token, err := New(append([]Option{WithOptions(realmOptions(name, options))}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.Register(name, token); err != nil {
+		token.Shutdown(context.Background())
+		return nil, err
+	}
+	return token, nil
+}
+
+// Register adds token as realm name, failing on duplicate names or overlapping cache prefixes | 将 token 注册为 name 域，名称重复或缓存前缀重叠时失败
+func (r *Registry) Register(name string, token Token) error {
+	if err := checkRealmName(name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.realms[name]; ok {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "gToken realm %q already registered", name)
+	}
+	options := token.GetOptions()
+	for other, otherToken := range r.realms {
+		if cacheOverlaps(options, otherToken.GetOptions()) {
+			return gerror.NewCodef(gcode.CodeInvalidConfiguration, "gToken realm %q CachePreKey %q overlaps realm %q", name, options.CachePreKey, other)
+		}
+	}
+	r.realms[name] = token
+	return nil
+}
+
+// Get returns the token of realm name | 返回 name 域的 Token
+func (r *Registry) Get(name string) (Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	token, ok := r.realms[name]
+	if !ok {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "%s: %q", MsgErrRealm, name)
+	}
+	return token, nil
+}
+
+// MustGet returns the token of realm name, panicking when it is not registered | 返回 name 域的 Token，未注册时 panic
+func (r *Registry) MustGet(name string) Token {
+	token, err := r.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// Names returns the registered realm names in order | 按顺序返回已注册的域名称
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.realms))
+	for name := range r.realms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Middleware creates the ghttp middleware bound to realm name, panicking when it is not registered | 创建绑定到 name 域的 ghttp 中间件，未注册时 panic
+func (r *Registry) Middleware(name string, resFun ...func(r *ghttp.Request)) Middleware {
+	return NewDefaultMiddleware(r.MustGet(name), resFun...)
+}
+
+// HTTPMiddleware creates the net/http middleware bound to realm name, panicking when it is not registered | 创建绑定到 name 域的 net/http 中间件，未注册时 panic
+func (r *Registry) HTTPMiddleware(name string) HTTPMiddleware {
+	return NewHTTPMiddleware(r.MustGet(name))
+}
+
+// Shutdown shuts down and removes every realm | 关闭并移除所有域
+func (r *Registry) Shutdown(ctx context.Context) {
+	r.mu.Lock()
+	realms := r.realms
+	r.realms = make(map[string]Token)
+	r.mu.Unlock()
+	for _, token := range realms {
+		token.Shutdown(ctx)
+	}
+}
+
+// optionsByConfig reads the options of realm from the gToken node of global config | 从全局配置的 gToken 节点读取 realm 的配置
+func optionsByConfig(realm ...string) (Options, error) {
+	node, name := GTokenCfgName, DefaultRealm
+	if len(realm) > 0 && realm[0] != "" && realm[0] != DefaultRealm {
+		if err := checkRealmName(realm[0]); err != nil {
+			return Options{}, err
+		}
+		node, name = GTokenCfgName+"."+realm[0], realm[0]
+	}
+	var options Options
+	if err := g.Cfg().MustGet(gctx.New(), node).Struct(&options); err != nil {
+		return Options{}, gerror.WrapCode(gcode.CodeInvalidConfiguration, err, "gToken options init failed")
+	}
+	return realmOptions(name, options), nil
+}
+
+// realmOptions applies the default cache prefix of a named realm | 为命名域应用默认缓存前缀
+func realmOptions(name string, options Options) Options {
+	if options.CachePreKey == "" && name != DefaultRealm {
+		options.CachePreKey = RealmCacheKey + name + ":"
+	}
+	return options
+}
+
+// checkRealmName rejects names that cannot form a cache prefix | 拒绝无法组成缓存前缀的域名称
+func checkRealmName(name string) error {
+	if name == "" || strings.ContainsAny(name, ":. ") {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid gToken realm name %q", name)
+	}
+	return nil
+}
+
+// cacheOverlaps reports whether two realms could read each other's cache keys | 判断两个域是否可能读取对方的缓存 key
+// In-memory caches are private to each token, other backends may be shared and need disjoint prefixes | 内存缓存为各 Token 私有，其他存储可能共享，需要互不包含的前缀
+func cacheOverlaps(a, b Options) bool {
+	if a.CacheMode == CacheModeCache || b.CacheMode == CacheModeCache {
+		return false
+	}
+	return strings.HasPrefix(a.CachePreKey, b.CachePreKey) || strings.HasPrefix(b.CachePreKey, a.CachePreKey)
+}
+
+// optionsFields holds the normalized field names of Options | Options 字段名的归一化集合
+var optionsFields = func() map[string]struct{} {
+	t := reflect.TypeOf(Options{})
+	fields := make(map[string]struct{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fields[normalizeFieldName(t.Field(i).Name)] = struct{}{}
+	}
+	return fields
+}()
+
+// isOptionsField reports whether a config key maps to an Options field the way gconv matches it | 按 gconv 的匹配方式判断配置 key 是否对应 Options 字段
+func isOptionsField(key string) bool {
+	_, ok := optionsFields[normalizeFieldName(key)]
+	return ok
+}
+
+// normalizeFieldName lowercases name and drops separators | 将名称转为小写并去除分隔符
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "", ".", "").Replace(name))
+}
@@ -0,0 +1,124 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRegistryByConfig(t *testing.T) {
+	ctx := context.Background()
+	adapter, err := gcfg.NewAdapterContent(`
+gToken:
+  Timeout: 600000
+  EncryptKey: "default-default-default-default-"
+  admin:
+    Timeout: 60000
+    EncryptKey: "admin-admin-admin-admin-admin-ad"
+  app:
+    CachePreKey: "App:"
+    multi_login: true
+  AuthRules:
+    - Path: "/admin/*"
+      Roles: ["admin"]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() { g.Cfg().SetAdapter(previous) })
+
+	registry, err := NewRegistryByConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { registry.Shutdown(ctx) })
+
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"admin", "app", DefaultRealm}) {
+		t.Fatalf("unexpected realms %v", names)
+	}
+	admin, app, def := registry.MustGet("admin"), registry.MustGet("app"), registry.MustGet(DefaultRealm)
+	if options := admin.GetOptions(); options.Timeout != 60000 || options.CachePreKey != RealmCacheKey+"admin:" {
+		t.Fatalf("admin options: timeout %d, prefix %q", options.Timeout, options.CachePreKey)
+	}
+	if options := app.GetOptions(); !options.MultiLogin || options.CachePreKey != "App:" || options.Timeout != DefaultTimeout {
+		t.Fatalf("app options: multi %t, prefix %q, timeout %d", options.MultiLogin, options.CachePreKey, options.Timeout)
+	}
+	if options := def.GetOptions(); options.Timeout != 600000 || options.CachePreKey != DefaultCacheKey || len(options.AuthRules) != 1 {
+		t.Fatalf("default options: timeout %d, prefix %q, rules %d", options.Timeout, options.CachePreKey, len(options.AuthRules))
+	}
+
+	// Realms never accept each other's tokens | 各域不接受其他域的 Token
+	adminToken, err := admin.Generate(ctx, "bob", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := admin.Validate(ctx, adminToken); err != nil || data != "admin" {
+		t.Fatalf("admin validate: %v %v", data, err)
+	}
+	for _, other := range []Token{app, def} {
+		if _, err = other.Validate(ctx, adminToken); err == nil {
+			t.Fatal("token of admin realm should not validate in other realms")
+		}
+	}
+
+	// Middleware is bound to its realm | 中间件绑定到所属域
+	handler := registry.HTTPMiddleware("app").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 in app realm, got %d", rec.Code)
+	}
+
+	// A single realm can be created from its node | 可从单个节点创建域
+	token, err := NewTokenByConfig("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(ctx)
+	if options := token.GetOptions(); options.Timeout != 60000 || options.CachePreKey != RealmCacheKey+"admin:" {
+		t.Fatalf("NewTokenByConfig options: timeout %d, prefix %q", options.Timeout, options.CachePreKey)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	t.Cleanup(func() { registry.Shutdown(ctx) })
+
+	if _, err := registry.RegisterOptions("app", Options{CacheMode: CacheModeFile, CacheFileDir: t.TempDir()}, WithBannerDisabled()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.RegisterOptions("app", Options{}, WithBannerDisabled()); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("expected duplicate realm error, got %v", err)
+	}
+	if _, err := registry.RegisterOptions("a:b", Options{}, WithBannerDisabled()); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+
+	// Shared backends need disjoint prefixes, in-memory caches are private | 共享存储需要互不包含的前缀，内存缓存为私有
+	if _, err := registry.RegisterOptions("partner", Options{CacheMode: CacheModeFile, CacheFileDir: t.TempDir(), CachePreKey: RealmCacheKey + "app:partner:"}, WithBannerDisabled()); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("expected overlapping prefix error, got %v", err)
+	}
+	if _, err := registry.RegisterOptions("memory", Options{CachePreKey: RealmCacheKey + "app:"}, WithBannerDisabled()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := registry.Get("partner"); gerror.Code(err) != gcode.CodeNotFound {
+		t.Fatalf("expected CodeNotFound, got %v", err)
+	}
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"app", "memory"}) {
+		t.Fatalf("unexpected realms %v", names)
+	}
+}
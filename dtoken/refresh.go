@@ -0,0 +1,189 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// TokenPair holds an access token and its refresh token | 访问令牌与刷新令牌对
+type TokenPair struct {
+	AccessToken      string `json:"accessToken"`      // Access token validated by Validate | 通过 Validate 校验的访问令牌
+	RefreshToken     string `json:"refreshToken"`     // Single-use refresh token for Refresh | 供 Refresh 使用的一次性刷新令牌
+	ExpiresIn        int64  `json:"expiresIn"`        // Access token lifetime (ms) | 访问令牌有效期（毫秒）
+	RefreshExpiresIn int64  `json:"refreshExpiresIn"` // Refresh token lifetime (ms) | 刷新令牌有效期（毫秒）
+}
+
+// refreshTokenContextKey marks codec calls encoding a refresh token | 标记编码刷新令牌的编解码调用
+type refreshTokenContextKey struct{}
+
+// withRefreshToken marks ctx for encoding or decoding a refresh token | 标记上下文用于编码或解码刷新令牌
+func withRefreshToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshTokenContextKey{}, true)
+}
+
+// IsRefreshToken reports whether a Codec is encoding or decoding a refresh token in ctx | 判断 Codec 是否正在上下文中编码或解码刷新令牌
+func IsRefreshToken(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshTokenContextKey{}).(bool)
+	return refresh
+}
+
+// GeneratePair creates an access token together with a refresh token | 生成访问令牌及对应的刷新令牌
+// Each device session owns one refresh family; a new login starts a new family | 每个设备会话拥有一个刷新令牌族，重新登录开启新的令牌族
+func (m *GTokenV2) GeneratePair(ctx context.Context, userKey, deviceId string, data any) (*TokenPair, error) {
+	if m.RefreshCache == nil {
+		return nil, gerror.NewCode(gcode.CodeNotSupported, MsgErrRefreshOff)
+	}
+	accessToken, err := m.GenerateWithDevice(ctx, userKey, deviceId, data)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshCache := g.Map{
+		KeyUserKey:    userKey,
+		KeyDeviceId:   deviceId,
+		KeyData:       data,
+		KeyCreateTime: m.now(),
+		KeyRotateNum:  0,
+		KeyUsedTokens: g.SliceStr{},
+	}
+	refreshToken, err := m.issueRefreshToken(ctx, sessionKey(userKey, deviceId), refreshCache)
+	if err != nil {
+		return nil, err
+	}
+	return m.newTokenPair(accessToken, refreshToken), nil
+}
+
+// Refresh exchanges a refresh token for a fresh token pair | 使用刷新令牌换取新的令牌对
+// Presenting an already rotated refresh token revokes the whole family | 重复使用已轮换的刷新令牌将吊销整个令牌族
+func (m *GTokenV2) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if m.RefreshCache == nil {
+		return nil, gerror.NewCode(gcode.CodeNotSupported, MsgErrRefreshOff)
+	}
+	if refreshToken == "" {
+		return nil, gerror.NewCode(CodeTokenMissing, MsgErrTokenEmpty)
+	}
+
+	// Decode refresh token to get session key | 解码刷新令牌获取会话 key
+	ctx = withTokenIdSlot(ctx)
+	cacheKey, err := m.Codec.Decrypt(withRefreshToken(ctx), refreshToken)
+	if err != nil {
+		return nil, gerror.WrapCode(CodeRefreshInvalid, err)
+	}
+	unlock := m.refreshLocks.lock(cacheKey)
+	defer unlock()
+	refreshCache, err := m.RefreshCache.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if refreshCache == nil {
+		return nil, gerror.NewCode(CodeRefreshInvalid, MsgErrRefresh)
+	}
+
+	var (
+		userKey  = gconv.String(refreshCache[KeyUserKey])
+		deviceId = gconv.String(refreshCache[KeyDeviceId])
+		used     = gconv.Strings(refreshCache[KeyUsedTokens])
+	)
+
+	// Reuse detection | 重放检测
+	if refreshToken != gconv.String(refreshCache[KeyToken]) {
+		tokenHash := gmd5.MustEncryptString(refreshToken)
+		for _, usedHash := range used {
+			if usedHash == tokenHash {
+				return nil, m.refreshReused(ctx, userKey, deviceId)
+			}
+		}
+		return nil, gerror.NewCode(CodeRefreshInvalid, MsgErrRefresh)
+	}
+
+	// Check revocation list | 检查吊销列表
+	revoked, err := m.IsRevoked(ctx, refreshToken, userKey, gconv.Int64(refreshCache[KeyCreateTime]))
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if revoked {
+		return nil, gerror.NewCode(CodeTokenRevoked, MsgErrRevoked)
+	}
+
+	// Issue a brand-new access token for the session | 为会话签发全新的访问令牌
+	accessToken, err := m.generate(ctx, userKey, deviceId, refreshCache[KeyData], grantsFromCache(refreshCache), false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotate refresh token, losing a concurrent rotation counts as reuse | 轮换刷新令牌，并发轮换失败视为重复使用
+	used = append(used, gmd5.MustEncryptString(refreshToken))
+	if len(used) > DefaultRefreshHistory {
+		used = used[len(used)-DefaultRefreshHistory:]
+	}
+	refreshCache[KeyUsedTokens] = used
+	refreshCache[KeyRotateNum] = gconv.Int(refreshCache[KeyRotateNum]) + 1
+	newRefreshToken, err := m.Codec.Encode(withRefreshToken(ctx), cacheKey)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	refreshCache[KeyToken] = newRefreshToken
+	swapped, err := m.swapRefreshToken(ctx, cacheKey, refreshToken, refreshCache)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if !swapped {
+		return nil, m.refreshReused(ctx, userKey, deviceId)
+	}
+	return m.newTokenPair(accessToken, newRefreshToken), nil
+}
+
+// swapRefreshToken replaces the family record only if it still holds token | 仅当令牌族记录仍持有 token 时替换该记录
+// Caches without Swapper rely on refreshLocks, which only guards a single process | 未实现 Swapper 的缓存依赖 refreshLocks，仅在单进程内有效
+func (m *GTokenV2) swapRefreshToken(ctx context.Context, cacheKey, token string, refreshCache g.Map) (bool, error) {
+	if swapper, ok := m.RefreshCache.(Swapper); ok {
+		swapped, err := swapper.Swap(ctx, cacheKey, token, refreshCache)
+		if gerror.Code(err) != gcode.CodeNotSupported {
+			return swapped, err
+		}
+	}
+	return true, m.RefreshCache.Set(ctx, cacheKey, refreshCache)
+}
+
+// refreshReused destroys the session of a replayed refresh family | 销毁被重放的刷新令牌族所属会话
+func (m *GTokenV2) refreshReused(ctx context.Context, userKey, deviceId string) error {
+	if err := m.DestroySession(ctx, userKey, deviceId); err != nil {
+		return err
+	}
+	return gerror.NewCode(CodeRefreshReused, MsgErrRefreshReuse)
+}
+
+// issueRefreshToken encodes a new refresh token and stores the family record | 编码新的刷新令牌并保存令牌族记录
+func (m *GTokenV2) issueRefreshToken(ctx context.Context, cacheKey string, refreshCache g.Map) (string, error) {
+	refreshToken, err := m.Codec.Encode(withRefreshToken(ctx), cacheKey)
+	if err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	refreshCache[KeyToken] = refreshToken
+	if err = m.RefreshCache.Set(ctx, cacheKey, refreshCache); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return refreshToken, nil
+}
+
+// removeRefreshToken drops the refresh family of a session if enabled | 删除会话的刷新令牌族（若已启用）
+func (m *GTokenV2) removeRefreshToken(ctx context.Context, cacheKey string) error {
+	if m.RefreshCache == nil {
+		return nil
+	}
+	return m.RefreshCache.Remove(ctx, cacheKey)
+}
+
+// newTokenPair builds TokenPair with configured lifetimes | 按配置的有效期构建令牌对
+func (m *GTokenV2) newTokenPair(accessToken, refreshToken string) *TokenPair {
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        m.options().Timeout,
+		RefreshExpiresIn: m.options().RefreshTimeout,
+	}
+}
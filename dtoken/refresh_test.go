@@ -0,0 +1,122 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresh_Rotation(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	pair, err := token.GeneratePair(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, pair.RefreshToken); err == nil {
+		t.Fatal("refresh token must not be accepted as access token")
+	}
+
+	rotated, err := token.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.AccessToken == pair.AccessToken || rotated.RefreshToken == pair.RefreshToken {
+		t.Fatal("expected a fresh token pair")
+	}
+	if _, err = token.Validate(ctx, pair.AccessToken); err == nil {
+		t.Fatal("expected previous access token to be replaced")
+	}
+	data, err := token.Validate(ctx, rotated.AccessToken)
+	if err != nil || data != "w" {
+		t.Fatalf("unexpected validate result: %v %v", data, err)
+	}
+
+	// Reusing a rotated refresh token revokes the whole family
+	if _, err = token.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Fatal("expected reuse to be rejected")
+	}
+	if _, err = token.Validate(ctx, rotated.AccessToken); err == nil {
+		t.Fatal("expected access token to be revoked after reuse")
+	}
+	if _, err = token.Refresh(ctx, rotated.RefreshToken); err == nil {
+		t.Fatal("expected family to be revoked after reuse")
+	}
+}
+
+// concurrentRefresh refreshes the same token from every token at once and returns the number of successes
+func concurrentRefresh(t *testing.T, refreshToken string, tokens ...*GTokenV2) int32 {
+	var (
+		wg        sync.WaitGroup
+		successes atomic.Int32
+	)
+	for _, token := range tokens {
+		wg.Add(1)
+		go func(token *GTokenV2) {
+			defer wg.Done()
+			_, err := token.Refresh(context.Background(), refreshToken)
+			switch {
+			case err == nil:
+				successes.Add(1)
+			case gerror.Code(err) != CodeRefreshReused && gerror.Code(err) != CodeRefreshInvalid:
+				t.Error(err)
+			}
+		}(token)
+	}
+	wg.Wait()
+	return successes.Load()
+}
+
+func TestRefresh_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	token.RefreshCache = &slowCache{Cache: token.RefreshCache}
+
+	pair, err := token.GeneratePair(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := make([]*GTokenV2, 10)
+	for i := range tokens {
+		tokens[i] = token
+	}
+	if successes := concurrentRefresh(t, pair.RefreshToken, tokens...); successes != 1 {
+		t.Fatalf("expected exactly one refresh to succeed, got %d", successes)
+	}
+}
+
+// slowRedisCache widens the read-modify-write window of RedisCache, keeping Swapper
+type slowRedisCache struct {
+	*RedisCache
+}
+
+func (c *slowRedisCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	cacheValue, err := c.RedisCache.Get(ctx, cacheKey)
+	time.Sleep(10 * time.Millisecond)
+	return cacheValue, err
+}
+
+func TestRefresh_ConcurrentInstances(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestRedisCache(t, "GToken:", DefaultTimeout)
+	refreshCache := &slowRedisCache{&RedisCache{Redis: cache.Redis, PreKey: "GToken:" + RefreshPreKey, Timeout: DefaultRefreshTimeout}}
+
+	// Two processes sharing Redis do not share refreshLocks
+	first, second := newTestToken(t, Options{}), newTestToken(t, Options{})
+	for _, token := range []*GTokenV2{first, second} {
+		token.Cache = cache
+		token.RefreshCache = refreshCache
+	}
+	pair, err := first.GeneratePair(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if successes := concurrentRefresh(t, pair.RefreshToken, first, second); successes != 1 {
+		t.Fatalf("expected exactly one refresh to succeed, got %d", successes)
+	}
+}
@@ -0,0 +1,291 @@
+package dtoken
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfsnotify"
+	"reflect"
+	"strings"
+)
+
+// OptionsUpdater is implemented by tokens accepting option changes at runtime | 支持运行时修改配置的 Token 实现该接口
+type OptionsUpdater interface {
+	UpdateOptions(ctx context.Context, options Options) error // Apply options, rejecting them all if any change is unsafe | 应用配置，存在不可热更新的修改时全部拒绝
+}
+
+// OptionsChange is a field that differs between two options | 两份配置之间不同的字段
+type OptionsChange struct {
+	Field string // Options field name | Options 字段名
+	Old   any    // Previous value, secrets are masked | 原值，密钥类字段已脱敏
+	New   any    // New value, secrets are masked | 新值，密钥类字段已脱敏
+}
+
+// String formats the change as "Field: old -> new" | 将修改格式化为 "Field: old -> new"
+func (c OptionsChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Field, c.Old, c.New)
+}
+
+// reloadableFields are the options UpdateOptions applies at runtime | UpdateOptions 可在运行时应用的配置项
+var reloadableFields = map[string]struct{}{
+	"Timeout":            {},
+	"MaxRefresh":         {},
+	"MaxRefreshTimes":    {},
+	"RefreshTimeout":     {},
+	"RenewInterval":      {},
+	"MultiLogin":         {},
+	"MaxSessions":        {},
+	"SessionEvictPolicy": {},
+	"AuthExcludePaths":   {},
+	"AuthIncludePaths":   {},
+	"EncryptKeys":        {},
+	"PoolMinSize":        {},
+	"PoolMaxSize":        {},
+	"PoolScaleUpRate":    {},
+	"PoolScaleDownRate":  {},
+	"Strict":             {},
+}
+
+// secretFields are masked in OptionsChange | 在 OptionsChange 中脱敏的字段
+var secretFields = map[string]struct{}{
+	"EncryptKey":    {},
+	"EncryptKeys":   {},
+	"JwtSecret":     {},
+	"JwtPrivateKey": {},
+	"Tenants":       {},
+}
+
+// DiffOptions lists the fields that differ between old and new in declaration order | 按声明顺序列出 old 与 new 之间不同的字段
+func DiffOptions(old, new Options) []OptionsChange {
+	var (
+		changes []OptionsChange
+		t       = reflect.TypeOf(old)
+		ov, nv  = reflect.ValueOf(old), reflect.ValueOf(new)
+	)
+	for i := 0; i < t.NumField(); i++ {
+		a, b := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		field := t.Field(i).Name
+		if _, ok := secretFields[field]; ok {
+			a, b = "***", "***"
+		}
+		changes = append(changes, OptionsChange{Field: field, Old: a, New: b})
+	}
+	return changes
+}
+
+// UpdateOptions implements OptionsUpdater | 实现 OptionsUpdater 接口
+// Defaults and validation apply as in New; only timeouts, refresh and session policy, path rules, EncryptKeys and pool sizes can change,
+// any other change rejects the whole update with a logged diff and leaves the token untouched. Configured tenants follow the root token.
+// In JWT mode timeouts cannot change the "exp" lifetimes of issued tokens.
+// 与 New 一样应用默认值与校验；仅超时、刷新与会话策略、路径规则、EncryptKeys 与协程池大小可修改，
+// 其他修改会导致整体拒绝并记录差异日志，Token 保持不变。已配置的租户跟随根 Token 更新。JWT 模式下超时修改不能改变 Token 的 exp 有效期
+func (m *GTokenV2) UpdateOptions(ctx context.Context, options Options) error {
+	if m.TenantId != "" {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrReloadTenant)
+	}
+	if err := options.normalize(options.Strict, m.logger()); err != nil {
+		return err
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	old := m.options()
+	changes := DiffOptions(*old, options)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Reject unsafe changes before applying anything | 应用前拒绝不可热更新的修改
+	var (
+		problems    []string
+		diff        = make([]string, 0, len(changes))
+		encryptKeys bool
+	)
+	for _, change := range changes {
+		diff = append(diff, change.String())
+		if change.Field == "EncryptKeys" {
+			encryptKeys = true
+		}
+		if _, ok := reloadableFields[change.Field]; !ok {
+			problems = append(problems, change.String()+" requires restart | 需要重启才能生效")
+		}
+	}
+	if old.Timeout != options.Timeout && !isTimeoutSetter(m.Cache) {
+		problems = append(problems, "Timeout cannot change on a cache without TimeoutSetter | 缓存未实现 TimeoutSetter，无法修改 Timeout")
+	}
+	if old.RefreshTimeout != options.RefreshTimeout && (!isTimeoutSetter(m.RefreshCache) || !isTimeoutSetter(m.RevokeCache)) {
+		problems = append(problems, "RefreshTimeout cannot change on a cache without TimeoutSetter | 缓存未实现 TimeoutSetter，无法修改 RefreshTimeout")
+	}
+	if jwtLifetimeChanged(old, &options) {
+		problems = append(problems, "JWT lifetimes from Timeout, MaxRefreshTimes or RefreshTimeout require restart | 由 Timeout、MaxRefreshTimes 或 RefreshTimeout 决定的 JWT 有效期需要重启才能生效")
+	}
+	if encryptKeys && m.KeyRing == nil {
+		problems = append(problems, "EncryptKeys cannot change without a key ring | 未启用密钥环，无法修改 EncryptKeys")
+	}
+	if len(problems) > 0 {
+		m.logger().Warningf(ctx, "Token options update rejected, changes: %s", strings.Join(diff, "; "))
+		return &OptionsError{Problems: problems}
+	}
+
+	rules, err := NewPathRulesByOptions(options)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidConfiguration, err)
+	}
+	if encryptKeys {
+		if err = m.KeyRing.Update(options.EncryptKeys...); err != nil {
+			return gerror.WrapCode(gcode.CodeInvalidConfiguration, err, "EncryptKeys update failed")
+		}
+	}
+
+	// Apply the new options | 应用新配置
+	m.setCacheTimeouts(old, &options)
+	if m.RenewPoolManager != nil {
+		m.RenewPoolManager.Tune(options.PoolMinSize, options.PoolMaxSize, options.PoolScaleUpRate, options.PoolScaleDownRate)
+	}
+	m.pathRules.Store(rules)
+	m.current.Store(&options)
+	m.rangeTenants(func(tenantId string, tenant *GTokenV2) {
+		tenantOptions := options.tenantOptions(tenantId)
+		tenant.setCacheTimeouts(tenant.options(), &tenantOptions)
+		tenant.pathRules.Store(rules)
+		tenant.current.Store(&tenantOptions)
+	})
+	m.logger().Infof(ctx, "Token options updated: %s", strings.Join(diff, "; "))
+	return nil
+}
+
+// jwtLifetimeChanged reports whether new changes "exp" lifetimes fixed in the JWT codecs of the token and its tenants | 判断新配置是否修改了 Token 及其租户 JWT 编解码器中固定的 exp 有效期
+func jwtLifetimeChanged(old, new *Options) bool {
+	if old.CodecMode != CodecModeJWT {
+		return false
+	}
+	if old.jwtExpire() != new.jwtExpire() || old.RefreshTimeout != new.RefreshTimeout {
+		return true
+	}
+	for tenantId, tenant := range old.Tenants {
+		if len(tenant.EncryptKey) == 0 {
+			continue // Shares the codec of the root token | 共享根 Token 的编解码器
+		}
+		oldTenant, newTenant := old.tenantOptions(tenantId), new.tenantOptions(tenantId)
+		if oldTenant.jwtExpire() != newTenant.jwtExpire() || oldTenant.RefreshTimeout != newTenant.RefreshTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheTimeouts moves cache lifetimes from old to new options | 将缓存有效期从旧配置调整为新配置
+func (m *GTokenV2) setCacheTimeouts(old, new *Options) {
+	if old.Timeout != new.Timeout {
+		setCacheTimeout(m.Cache, new.Timeout)
+	}
+	if old.RefreshTimeout != new.RefreshTimeout {
+		setCacheTimeout(m.RefreshCache, new.RefreshTimeout)
+		setCacheTimeout(m.RevokeCache, new.RefreshTimeout)
+	}
+}
+
+// setCacheTimeout sets the lifetime of cache when it implements TimeoutSetter | 缓存实现 TimeoutSetter 时设置其有效期
+func setCacheTimeout(cache Cache, timeout int64) {
+	if setter, ok := cache.(TimeoutSetter); ok {
+		setter.SetTimeout(timeout)
+	}
+}
+
+// isTimeoutSetter reports whether cache is unset or implements TimeoutSetter beneath its decorators | 判断缓存是否未设置，或去除装饰器后实现了 TimeoutSetter
+func isTimeoutSetter(cache Cache) bool {
+	if cache == nil {
+		return true
+	}
+	for {
+		wrapper, ok := cache.(interface{ Unwrap() Cache })
+		if !ok {
+			break
+		}
+		cache = wrapper.Unwrap()
+	}
+	_, ok := cache.(TimeoutSetter)
+	return ok
+}
+
+// PathRules implements PathRulesProvider | 实现 PathRulesProvider 接口
+func (m *GTokenV2) PathRules() *PathRules {
+	if rules := m.pathRules.Load(); rules != nil {
+		return rules
+	}
+	rules, err := NewPathRulesByOptions(*m.options())
+	if err != nil {
+		return nil
+	}
+	if !m.pathRules.CompareAndSwap(nil, rules) {
+		return m.pathRules.Load()
+	}
+	return rules
+}
+
+// ReloadConfig reads the options of realm from global config and applies them with UpdateOptions | 从全局配置读取 realm 的配置并通过 UpdateOptions 应用
+func (m *GTokenV2) ReloadConfig(ctx context.Context, realm ...string) error {
+	options, err := optionsByConfig(realm...)
+	if err != nil {
+		return err
+	}
+	return m.UpdateOptions(ctx, options)
+}
+
+// WatchConfig reloads the options of realm whenever the config file changes, replacing any previous watch | 配置文件变更时重新加载 realm 的配置，替换之前的监听
+// Only file based config adapters can be watched, rejected changes are logged and the token keeps its options
+// 仅支持基于文件的配置适配器，被拒绝的修改会记录日志，Token 保持原配置
+func (m *GTokenV2) WatchConfig(realm ...string) error {
+	if m.TenantId != "" {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrReloadTenant)
+	}
+	adapter, ok := g.Cfg().GetAdapter().(*gcfg.AdapterFile)
+	if !ok {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrWatchOff)
+	}
+	path, err := adapter.GetFilePath()
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeNotSupported, err, MsgErrWatchOff)
+	}
+	realm = append([]string(nil), realm...)
+	callback, err := gfsnotify.Add(path, func(event *gfsnotify.Event) {
+		if !event.IsWrite() && !event.IsCreate() && !event.IsRename() {
+			return
+		}
+		ctx := gctx.New()
+		adapter.Clear()
+		if err := m.ReloadConfig(ctx, realm...); err != nil {
+			m.logger().Warningf(ctx, "Token config reload failed: %v", err)
+		}
+	}, gfsnotify.WatchOption{NoRecursive: true})
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	m.reloadMu.Lock()
+	previous := m.watcher
+	m.watcher = callback
+	m.reloadMu.Unlock()
+	if previous != nil {
+		_ = gfsnotify.RemoveCallback(previous.Id)
+	}
+	return nil
+}
+
+// stopWatch stops watching the config file | 停止监听配置文件
+func (m *GTokenV2) stopWatch() {
+	m.reloadMu.Lock()
+	watcher := m.watcher
+	m.watcher = nil
+	m.reloadMu.Unlock()
+	if watcher != nil {
+		_ = gfsnotify.RemoveCallback(watcher.Id)
+	}
+}
@@ -0,0 +1,290 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/gogf/gf/v2/os/gfile"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateOptions(t *testing.T) {
+	ctx := context.Background()
+	options := Options{CachePreKey: "Test:" + t.Name() + ":", AuthExcludePaths: []string{"/public/*"}}
+	token, err := New(WithOptions(options), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+	updater := token.(OptionsUpdater)
+
+	handler := NewHTTPMiddleware(token).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	if status("/public/a") != http.StatusOK || status("/health") != http.StatusUnauthorized {
+		t.Fatal("unexpected initial exclude paths")
+	}
+
+	// Safe changes are applied at once | 可热更新的修改立即生效
+	options.Timeout, options.AuthExcludePaths, options.PoolMinSize, options.PoolMaxSize = 60*1000, []string{"/health"}, 5, 50
+	if err = updater.UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+	current := token.GetOptions()
+	if current.Timeout != 60*1000 || current.MaxRefresh != 30*1000 || current.PoolMaxSize != 50 {
+		t.Fatalf("options not applied: timeout %d, maxRefresh %d, pool %d", current.Timeout, current.MaxRefresh, current.PoolMaxSize)
+	}
+	if timeout := token.(*GTokenV2).Cache.(*DefaultCache).timeout(); timeout != 60*1000 {
+		t.Fatalf("cache timeout not applied: %d", timeout)
+	}
+	if status("/public/a") != http.StatusUnauthorized || status("/health") != http.StatusOK {
+		t.Fatal("middleware should follow the new exclude paths")
+	}
+	if _, capacity, _ := token.(*GTokenV2).RenewPoolManager.Stats(); capacity < 5 || capacity > 50 {
+		t.Fatalf("pool capacity %d outside new limits", capacity)
+	}
+
+	// Unsafe changes reject the whole update | 不可热更新的修改导致整体拒绝
+	unsafe := options
+	unsafe.Timeout, unsafe.CachePreKey, unsafe.MultiLogin = 90*1000, "Other:", true
+	err = updater.UpdateOptions(ctx, unsafe)
+	optionsErr, ok := err.(*OptionsError)
+	if !ok || len(optionsErr.Problems) != 1 || gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("expected one problem, got %v", err)
+	}
+	if current = token.GetOptions(); current.Timeout != 60*1000 || current.MultiLogin || current.CachePreKey != options.CachePreKey {
+		t.Fatalf("rejected update must not apply: %+v", current)
+	}
+	if changes := DiffOptions(options, unsafe); len(changes) != 3 || changes[0].Field != "CachePreKey" {
+		t.Fatalf("unexpected diff %v", changes)
+	}
+	if token.(*GTokenV2).Options.Timeout != DefaultTimeout {
+		t.Fatal("Options keeps the construction-time config")
+	}
+}
+
+func TestUpdateOptions_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	created, err := New(WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":"}), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { created.Shutdown(ctx) })
+	token := created.(*GTokenV2)
+	options := token.GetOptions()
+
+	// Readers of the current options never race with updates | 读取当前配置不会与更新产生竞争
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if timeout := token.GetOptions().Timeout; timeout <= 0 {
+					t.Errorf("unexpected timeout %d", timeout)
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 10; i++ {
+		options.MaxRefresh = int64(i) * 1000
+		if err := token.UpdateOptions(ctx, options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if maxRefresh := token.GetOptions().MaxRefresh; maxRefresh != 10*1000 {
+		t.Fatalf("unexpected MaxRefresh %d", maxRefresh)
+	}
+}
+
+func TestUpdateOptions_JwtLifetime(t *testing.T) {
+	ctx := context.Background()
+	options := Options{
+		CachePreKey: "Test:" + t.Name() + ":",
+		CodecMode:   CodecModeJWT,
+		JwtSecret:   []byte("secret"),
+		Tenants:     map[string]TenantOptions{"acme": {EncryptKey: []byte("acme-acme-acme-a"), Timeout: 60 * 1000}},
+	}
+	token, err := New(WithOptions(options), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+	updater := token.(OptionsUpdater)
+	options = token.GetOptions()
+
+	// Timeouts shaping "exp" are fixed in the codecs | 决定 exp 的超时固定在编解码器中
+	for _, update := range []func(o *Options){
+		func(o *Options) { o.Timeout = 60 * 1000 },
+		func(o *Options) { o.MaxRefreshTimes = 3 },
+		func(o *Options) { o.RefreshTimeout = 60 * 60 * 1000 },
+	} {
+		changed := options
+		update(&changed)
+		if _, ok := updater.UpdateOptions(ctx, changed).(*OptionsError); !ok {
+			t.Fatalf("expected JWT lifetime change rejected: %v", DiffOptions(options, changed))
+		}
+	}
+
+	// Other timeouts still reload, JwtExpire decouples Timeout from "exp" | 其他超时仍可重载，JwtExpire 使 Timeout 与 exp 解耦
+	options.MaxRefresh = 10 * 1000
+	if err = updater.UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+	options.JwtExpire = 3600 * 1000
+	token, err = New(WithOptions(options), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+	options.Timeout = 60 * 1000
+	if err = token.(OptionsUpdater).UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateOptions_Telemetry(t *testing.T) {
+	ctx := context.Background()
+	options := Options{CachePreKey: "Test:" + t.Name() + ":", Telemetry: true}
+	token, err := New(WithOptions(options), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	// Traced caches keep TimeoutSetter of the decorated caches | 装饰后的缓存保留被装饰缓存的 TimeoutSetter
+	options.Timeout, options.RefreshTimeout = 60*1000, 120*1000
+	if err = token.(OptionsUpdater).UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+	gfToken := token.(*GTokenV2)
+	if timeout := gfToken.Cache.(*TracedCache).Unwrap().(*DefaultCache).timeout(); timeout != 60*1000 {
+		t.Fatalf("cache timeout not applied: %d", timeout)
+	}
+	if timeout := gfToken.RefreshCache.(*TracedCache).Unwrap().(*DefaultCache).timeout(); timeout != 120*1000 {
+		t.Fatalf("refresh cache timeout not applied: %d", timeout)
+	}
+}
+
+func TestUpdateOptions_EncryptKeys(t *testing.T) {
+	ctx := context.Background()
+	k1, k2 := RingKey{Id: "k1", Key: []byte(DefaultEncryptKey), Status: KeyStatusActive}, RingKey{Id: "k2", Key: []byte("abcdefghijklmnop")}
+	options := Options{CachePreKey: "Test:" + t.Name() + ":", CodecMode: CodecModeAEAD, EncryptKeys: []RingKey{k1}}
+	token, err := New(WithOptions(options), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	oldToken, err := token.Generate(ctx, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add k2 and promote it, k1 keeps decrypting | 添加 k2 并激活，k1 仍可解密
+	k1.Status, k2.Status = KeyStatusDecryptOnly, KeyStatusActive
+	options.EncryptKeys = []RingKey{k1, k2}
+	if err = token.(OptionsUpdater).UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+	if active := token.(*GTokenV2).KeyRing.Active(); active.Id != "k2" {
+		t.Fatalf("expected k2 active, got %s", active.Id)
+	}
+	newToken, err := token.Generate(ctx, "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{oldToken, newToken} {
+		if _, err = token.Validate(ctx, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Key material of an existing id cannot change | 已有 ID 的密钥内容不可修改
+	k2.Key = []byte("ponmlkjihgfedcba")
+	options.EncryptKeys = []RingKey{k1, k2}
+	if err = token.(OptionsUpdater).UpdateOptions(ctx, options); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("expected CodeInvalidConfiguration, got %v", err)
+	}
+	if _, err = token.Validate(ctx, newToken); err != nil {
+		t.Fatalf("failed update must keep the ring: %v", err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	ctx := context.Background()
+	setConfig := func(content string) {
+		adapter, err := gcfg.NewAdapterContent(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Cfg().SetAdapter(adapter)
+	}
+	previous := g.Cfg().GetAdapter()
+	t.Cleanup(func() { g.Cfg().SetAdapter(previous) })
+
+	setConfig("gToken:\n  admin:\n    Timeout: 60000\n    HotReload: true\n")
+	if _, err := NewTokenByConfig("admin"); gerror.Code(err) != gcode.CodeNotSupported {
+		t.Fatalf("content adapter cannot be watched, got %v", err)
+	}
+
+	setConfig("gToken:\n  admin:\n    Timeout: 60000\n")
+	token, err := NewTokenByConfig("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	setConfig("gToken:\n  admin:\n    Timeout: 120000\n    MaxSessions: 3\n")
+	if err = token.(*GTokenV2).ReloadConfig(ctx, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if options := token.GetOptions(); options.Timeout != 120000 || options.MaxSessions != 3 || options.CachePreKey != RealmCacheKey+"admin:" {
+		t.Fatalf("reload not applied: timeout %d, sessions %d, prefix %q", options.Timeout, options.MaxSessions, options.CachePreKey)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := gfile.PutContents(path, "gToken:\n  Timeout: 60000\n  HotReload: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	adapter, err := gcfg.NewAdapterFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() { g.Cfg().SetAdapter(previous) })
+
+	token, err := NewTokenByConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	if err = gfile.PutContents(path, "gToken:\n  Timeout: 90000\n  HotReload: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); token.GetOptions().Timeout != 90000; {
+		if time.Now().After(deadline) {
+			t.Fatalf("config change not applied, timeout %d", token.GetOptions().Timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
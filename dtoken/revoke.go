@@ -0,0 +1,198 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// TokenIdentifier is implemented by codecs whose tokens carry an identifier | Token 自带唯一标识的编解码器实现该接口
+type TokenIdentifier interface {
+	TokenId(ctx context.Context, token string) (tokenId string, err error) // Extract token identifier | 提取 Token 唯一标识
+}
+
+// TokenId returns the "jti" claim of a valid JWT | 返回有效 JWT 的 jti 声明
+func (c *JWTCodec) TokenId(ctx context.Context, token string) (string, error) {
+	claims, err := c.ParseClaims(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return gconv.String(claims[JwtClaimJti]), nil
+}
+
+// tokenIdContextKey is the context key of the token id recorded by Decrypt | Decrypt 记录的 Token 标识的上下文 key
+type tokenIdContextKey struct{}
+
+// tokenIdSlot holds the identifier of the token a codec verified in this request | 保存本次请求中编解码器已校验 Token 的标识
+type tokenIdSlot struct {
+	token   string
+	tokenId string
+}
+
+// withTokenIdSlot lets codecs record the identifier of the token they verify in ctx | 使编解码器在上下文中记录其校验的 Token 标识
+func withTokenIdSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tokenIdContextKey{}, &tokenIdSlot{})
+}
+
+// recordTokenId stores the identifier of a verified token in the slot of ctx, if any | 将已校验 Token 的标识存入上下文中的槽位（如有）
+func recordTokenId(ctx context.Context, token, tokenId string) {
+	if slot, ok := ctx.Value(tokenIdContextKey{}).(*tokenIdSlot); ok {
+		slot.token, slot.tokenId = token, tokenId
+	}
+}
+
+// recordedTokenId returns the identifier recorded for token in ctx | 返回上下文中为 token 记录的标识
+func recordedTokenId(ctx context.Context, token string) string {
+	if slot, ok := ctx.Value(tokenIdContextKey{}).(*tokenIdSlot); ok && slot.token == token {
+		return slot.tokenId
+	}
+	return ""
+}
+
+// revokeTokenKey builds the revocation key of a token id | 构建 Token 吊销记录的缓存 key
+func revokeTokenKey(tokenId string) string {
+	return RevokeTokenPreKey + tokenId
+}
+
+// revokeUserKey builds the "not before" key of a user | 构建用户吊销时间点的缓存 key
+func revokeUserKey(userKey string) string {
+	return RevokeUserPreKey + userKey
+}
+
+// TokenId returns the identifier used by the revocation list | 返回吊销列表使用的 Token 标识
+// Codecs implementing TokenIdentifier provide it, otherwise the md5 of the token is used | 编解码器实现 TokenIdentifier 时使用其标识，否则使用 Token 的 md5
+func (m *GTokenV2) TokenId(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", gerror.NewCode(CodeTokenMissing, MsgErrTokenEmpty)
+	}
+	if tokenId := recordedTokenId(ctx, token); tokenId != "" {
+		return tokenId, nil // Already verified by Decrypt | 已由 Decrypt 校验
+	}
+	if identifier, ok := m.Codec.(TokenIdentifier); ok {
+		tokenId, err := identifier.TokenId(ctx, token)
+		if err != nil {
+			return "", gerror.WrapCode(CodeTokenInvalid, err)
+		}
+		if tokenId != "" {
+			return tokenId, nil
+		}
+	}
+	return gmd5.MustEncryptString(token), nil
+}
+
+// RevokeToken blocks a single token, e.g. a leaked one | 吊销单个 Token，例如已泄露的 Token
+func (m *GTokenV2) RevokeToken(ctx context.Context, token string) error {
+	tokenId, err := m.TokenId(ctx, token)
+	if err != nil {
+		return err
+	}
+	return m.RevokeTokenId(ctx, tokenId)
+}
+
+// RevokeTokenId blocks a single token by its identifier | 按标识吊销单个 Token
+func (m *GTokenV2) RevokeTokenId(ctx context.Context, tokenId string) error {
+	if m.RevokeCache == nil {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrRevokeOff)
+	}
+	if tokenId == "" {
+		return gerror.NewCode(CodeTokenMissing, MsgErrTokenEmpty)
+	}
+
+	// Mark the list non-empty first, entries never outlive the mark | 先标记列表非空，记录不会比标记存活更久
+	if err := m.RevokeCache.Set(ctx, RevokeTokensKey, g.Map{KeyCreateTime: m.now()}); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if err := m.RevokeCache.Set(ctx, revokeTokenKey(tokenId), g.Map{KeyCreateTime: m.now()}); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return nil
+}
+
+// RevokeUser invalidates all tokens of user issued before the given time (ms, <=0 for now) | 吊销用户在指定时间（毫秒，<=0 表示当前）之前签发的所有 Token
+// Sessions created before that time are also removed from cache | 同时从缓存中移除该时间之前创建的会话
+func (m *GTokenV2) RevokeUser(ctx context.Context, userKey string, before int64) error {
+	if m.RevokeCache == nil {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrRevokeOff)
+	}
+	if err := checkSessionKey(userKey, ""); err != nil {
+		return err
+	}
+	if before <= 0 {
+		before = m.now()
+	}
+	if err := m.RevokeCache.Set(ctx, revokeUserKey(userKey), g.Map{KeyNotBefore: before}); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	sessions, err := m.ListSessions(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.CreateTime < before {
+			if err = m.DestroySession(ctx, userKey, session.DeviceId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RevokeAll sets a global epoch, every token issued before it becomes invalid (ms, <=0 for now) | 设置全局吊销时间点，之前签发的 Token 全部失效（毫秒，<=0 表示当前）
+func (m *GTokenV2) RevokeAll(ctx context.Context, before int64) error {
+	if m.RevokeCache == nil {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrRevokeOff)
+	}
+	if before <= 0 {
+		before = m.now()
+	}
+	if err := m.RevokeCache.Set(ctx, RevokeGlobalKey, g.Map{KeyNotBefore: before}); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether a token of user issued at issuedAt (ms) is revoked | 判断用户在 issuedAt（毫秒）签发的 Token 是否已被吊销
+func (m *GTokenV2) IsRevoked(ctx context.Context, token, userKey string, issuedAt int64) (bool, error) {
+	if m.RevokeCache == nil {
+		return false, nil
+	}
+
+	// Global epoch | 全局吊销时间点
+	global, err := m.RevokeCache.Get(ctx, RevokeGlobalKey)
+	if err != nil {
+		return false, err
+	}
+	if global != nil && issuedAt < gconv.Int64(global[KeyNotBefore]) {
+		return true, nil
+	}
+
+	// User epoch | 用户吊销时间点
+	if userKey != "" {
+		user, err := m.RevokeCache.Get(ctx, revokeUserKey(userKey))
+		if err != nil {
+			return false, err
+		}
+		if user != nil && issuedAt < gconv.Int64(user[KeyNotBefore]) {
+			return true, nil
+		}
+	}
+
+	// Single token, skipped while no token is revoked | 单个 Token，没有被吊销的 Token 时跳过
+	marked, err := m.RevokeCache.Get(ctx, RevokeTokensKey)
+	if err != nil || marked == nil {
+		return false, err
+	}
+	tokenId, err := m.TokenId(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	revoked, err := m.RevokeCache.Get(ctx, revokeTokenKey(tokenId))
+	if err != nil {
+		return false, err
+	}
+	return revoked != nil, nil
+}
@@ -0,0 +1,165 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"testing"
+	"time"
+)
+
+func TestRevoke_TokenUserAll(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	// Single token
+	webToken, err := token.GenerateWithDevice(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = token.RevokeToken(ctx, webToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, webToken); err == nil {
+		t.Fatal("expected revoked token to be rejected")
+	}
+
+	// Logout everywhere, later logins stay valid
+	appToken, err := token.GenerateWithDevice(ctx, "alice", "app", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err = token.RevokeUser(ctx, "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, appToken); err == nil {
+		t.Fatal("expected user tokens to be revoked")
+	}
+	sessions, err := token.ListSessions(ctx, "alice")
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected sessions removed, got %d %v", len(sessions), err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	newToken, err := token.GenerateWithDevice(ctx, "alice", "app", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, newToken); err != nil {
+		t.Fatalf("expected new login to be valid: %v", err)
+	}
+
+	// Global epoch, refresh tokens are rejected too
+	pair, err := token.GeneratePair(ctx, "bob", "", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err = token.RevokeAll(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, newToken); err == nil {
+		t.Fatal("expected global revocation")
+	}
+	if _, err = token.Validate(ctx, pair.AccessToken); err == nil {
+		t.Fatal("expected global revocation")
+	}
+	if _, err = token.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Fatal("expected refresh token to be revoked")
+	}
+}
+
+func TestRevoke_JWTTokenId(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	codec, err := NewJWTCodec(JwtAlgHS256, []byte(DefaultEncryptKey), DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token.Codec = codec
+
+	first, err := token.GenerateWithDevice(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenId, err := token.TokenId(ctx, first)
+	if err != nil || len(tokenId) != 32 {
+		t.Fatalf("expected jti as token id, got %q %v", tokenId, err)
+	}
+	if err = token.RevokeTokenId(ctx, tokenId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, first); err == nil {
+		t.Fatal("expected revoked jwt to be rejected")
+	}
+}
+
+// countingCodec counts TokenId calls that verify the token again
+type countingCodec struct {
+	*JWTCodec
+	tokenIds int
+}
+
+func (c *countingCodec) TokenId(ctx context.Context, token string) (string, error) {
+	c.tokenIds++
+	return c.JWTCodec.TokenId(ctx, token)
+}
+
+// getCountingCache counts Get calls by key
+type getCountingCache struct {
+	Cache
+	gets map[string]int
+}
+
+func (c *getCountingCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	c.gets[cacheKey]++
+	return c.Cache.Get(ctx, cacheKey)
+}
+
+func TestRevoke_ValidateLookups(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+	jwtCodec, err := NewJWTCodec(JwtAlgHS256, []byte("secret"), DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := &countingCodec{JWTCodec: jwtCodec}
+	revokeCache := &getCountingCache{Cache: token.RevokeCache, gets: make(map[string]int)}
+	token.Codec, token.RevokeCache = codec, revokeCache
+
+	first, err := token.GenerateWithDevice(ctx, "alice", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := token.GenerateWithDevice(ctx, "bob", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty list skips the per-token lookup
+	if _, err = token.Validate(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	tokenId, err := token.TokenId(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codec.tokenIds != 1 || revokeCache.gets[revokeTokenKey(tokenId)] != 0 {
+		t.Fatalf("expected no per-token lookup, got %d TokenId calls and %v", codec.tokenIds, revokeCache.gets)
+	}
+
+	// Validate reuses the jti verified by Decrypt
+	if err = token.RevokeToken(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	codec.tokenIds = 0
+	if _, err = token.Validate(ctx, first); !gerror.HasCode(err, CodeTokenRevoked) {
+		t.Fatalf("expected revoked token rejected, got %v", err)
+	}
+	if _, err = token.Validate(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if codec.tokenIds != 0 || revokeCache.gets[revokeTokenKey(tokenId)] != 1 {
+		t.Fatalf("expected jti reused, got %d TokenId calls and %v", codec.tokenIds, revokeCache.gets)
+	}
+}
@@ -0,0 +1,316 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// keyLockStripes is the number of mutexes shared by keyLocks | keyLocks 共享的互斥锁数量
+const keyLockStripes = 64
+
+// Session describes one login session of a user on a device | 用户在某一设备上的登录会话
+type Session struct {
+	UserKey       string   `json:"userKey"`       // User identifier | 用户标识
+	DeviceId      string   `json:"deviceId"`      // Device identifier ("" for the default session) | 设备标识（默认会话为空）
+	Token         string   `json:"token"`         // Token of this session | 会话 Token
+	Data          any      `json:"data"`          // Custom data | 自定义数据
+	CreateTime    int64    `json:"createTime"`    // Creation time (ms) | 创建时间（毫秒）
+	RefreshNum    int      `json:"refreshNum"`    // Renew counter | 已续期次数
+	LastRenewTime int64    `json:"lastRenewTime"` // Last renewal time (ms, 0 if never) | 上次续期时间（毫秒，未续期为 0）
+	Roles         []string `json:"roles"`         // Roles attached at generation | 生成时附加的角色
+	Permissions   []string `json:"permissions"`   // Permissions attached at generation | 生成时附加的权限
+	TenantId      string   `json:"tenantId"`      // Tenant of the session ("" for the default tenant) | 会话所属租户（默认租户为空）
+	ExpireTime    int64    `json:"expireTime"`    // Expiry unless renewed (ms) | 未续期时的过期时间（毫秒）
+}
+
+// newSessionFromCache converts a cached user map to Session, expiry counts timeout from the last renewal | 将缓存中的用户信息转换为 Session，过期时间从上次续期起计算 timeout
+func newSessionFromCache(userCache g.Map, timeout int64) *Session {
+	session := &Session{
+		UserKey:       gconv.String(userCache[KeyUserKey]),
+		DeviceId:      gconv.String(userCache[KeyDeviceId]),
+		Token:         gconv.String(userCache[KeyToken]),
+		Data:          userCache[KeyData],
+		CreateTime:    gconv.Int64(userCache[KeyCreateTime]),
+		RefreshNum:    gconv.Int(userCache[KeyRefreshNum]),
+		LastRenewTime: gconv.Int64(userCache[KeyLastRenewTime]),
+		Roles:         gconv.Strings(userCache[KeyRoles]),
+		Permissions:   gconv.Strings(userCache[KeyPermissions]),
+		TenantId:      gconv.String(userCache[KeyTenantId]),
+	}
+	session.ExpireTime = max(session.CreateTime, session.LastRenewTime) + timeout
+	return session
+}
+
+// sessionKey builds the cache key of a device session | 构建设备会话的缓存 key
+// The default session (empty deviceId) keeps using userKey for compatibility | 默认会话（设备标识为空）沿用 userKey 以保持兼容
+func sessionKey(userKey, deviceId string) string {
+	if deviceId == "" {
+		return userKey
+	}
+	return userKey + DefaultDeviceDelimiter + deviceId
+}
+
+// checkSessionKey rejects userKey and deviceId that would collide with other session, index or tenant keys | 拒绝会与其他会话 key、索引 key 或租户 key 冲突的 userKey 与 deviceId
+func checkSessionKey(userKey, deviceId string) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	}
+	if strings.Contains(userKey, DefaultDeviceDelimiter) || strings.HasPrefix(userKey, SessionIndexPreKey) ||
+		strings.HasPrefix(userKey, TenantPreKey) {
+		return gerror.NewCode(gcode.CodeInvalidParameter, MsgErrUserKeyInvalid)
+	}
+	if strings.Contains(deviceId, DefaultDeviceDelimiter) {
+		return gerror.NewCode(gcode.CodeInvalidParameter, MsgErrDeviceIdInvalid)
+	}
+	return nil
+}
+
+// keyLocks serializes read-modify-write cycles on the same cache key within the process | 在进程内串行化同一缓存 key 的读改写
+// Keys are hashed onto a fixed set of mutexes, so the zero value is ready to use | key 散列到固定数量的互斥锁上，零值即可使用
+type keyLocks struct {
+	stripes [keyLockStripes]sync.Mutex
+}
+
+// lock locks the mutex of key and returns its unlock function | 锁定 key 对应的互斥锁并返回解锁函数
+func (l *keyLocks) lock(key string) (unlock func()) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	mu := &l.stripes[h.Sum32()%keyLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// newSessionEvent builds an event describing the cached session | 根据缓存的会话信息构建事件
+func newSessionEvent(t EventType, userCache g.Map) *Event {
+	return &Event{
+		Type:       t,
+		UserKey:    gconv.String(userCache[KeyUserKey]),
+		DeviceId:   gconv.String(userCache[KeyDeviceId]),
+		CreateTime: gconv.Int64(userCache[KeyCreateTime]),
+	}
+}
+
+// sessionIndexKey builds the cache key of user session index | 构建用户会话索引的缓存 key
+func sessionIndexKey(userKey string) string {
+	return SessionIndexPreKey + userKey
+}
+
+// GenerateWithDevice creates a new token for user on the given device | 为用户在指定设备上生成 Token
+func (m *GTokenV2) GenerateWithDevice(ctx context.Context, userKey, deviceId string, data any) (token string, err error) {
+	ctx, span := m.Telemetry.start(ctx, SpanGenerate)
+	defer func() { m.Telemetry.end(span, err) }()
+	return m.generate(ctx, userKey, deviceId, data, nil, m.options().MultiLogin)
+}
+
+// generate creates a device session, reusing the existing token when reuse is set | 创建设备会话，reuse 为 true 时重用已有 Token
+func (m *GTokenV2) generate(ctx context.Context, userKey, deviceId string, data any, grants *Grants, reuse bool) (token string, err error) {
+	if err = checkSessionKey(userKey, deviceId); err != nil {
+		return "", err
+	}
+	cacheKey := sessionKey(userKey, deviceId)
+
+	// Support multi-login on the same device (reuse existing token) | 同一设备支持重复登录（重用旧 Token）
+	if reuse {
+		userCache, err := m.Cache.Get(ctx, cacheKey)
+		if err == nil && userCache != nil && gconv.String(userCache[KeyToken]) != "" {
+			if grants != nil {
+				// Refresh grants of the reused session | 更新重用会话的权限
+				setGrants(userCache, grants)
+				if err = m.Cache.Set(ctx, cacheKey, userCache); err != nil {
+					return "", gerror.WrapCode(gcode.CodeInternalError, err)
+				}
+			}
+			return gconv.String(userCache[KeyToken]), nil
+		}
+	}
+
+	// Check session limit of the user, holding the index until it is written back | 检查用户会话数上限，索引写回前保持加锁
+	unlock := m.sessionLocks.lock(sessionIndexKey(userKey))
+	defer unlock()
+	index, err := m.loadSessionIndex(ctx, userKey)
+	if err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if _, ok := index[deviceId]; !ok && m.options().MaxSessions > 0 {
+		for len(index) >= m.options().MaxSessions {
+			if m.options().SessionEvictPolicy == SessionEvictReject {
+				return "", gerror.NewCode(CodeSessionLimit, MsgErrSessionLimit)
+			}
+			oldest := oldestDevice(index)
+			if err = m.Cache.Remove(ctx, sessionKey(userKey, oldest)); err != nil {
+				return "", gerror.WrapCode(gcode.CodeInternalError, err)
+			}
+			if err = m.removeRefreshToken(ctx, sessionKey(userKey, oldest)); err != nil {
+				return "", gerror.WrapCode(gcode.CodeInternalError, err)
+			}
+			delete(index, oldest)
+			m.Telemetry.sessionsChanged(ctx, -1)
+			m.emit(ctx, "", &Event{Type: EventDestroyed, UserKey: userKey, DeviceId: oldest, Reason: MsgErrSessionLimit})
+		}
+	}
+
+	// Encode session key into token | 编码会话 key 生成 Token
+	token, err = m.Codec.Encode(ctx, cacheKey)
+	if err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	// Cache structure for user token | 构建用户缓存结构
+	createTime := m.now()
+	userCache := g.Map{
+		KeyUserKey:       userKey,    // 用户唯一标识
+		KeyDeviceId:      deviceId,   // 设备标识
+		KeyToken:         token,      // Token 值
+		KeyData:          data,       // 附加数据
+		KeyRefreshNum:    0,          // 已续期次数
+		KeyCreateTime:    createTime, // 创建时间
+		KeyLastRenewTime: 0,          // 续期时间
+	}
+	if grants != nil {
+		setGrants(userCache, grants) // 角色与权限
+	}
+	if m.TenantId != "" {
+		userCache[KeyTenantId] = m.TenantId // 所属租户
+	}
+
+	// Save token data to cache | 将用户 Token 信息写入缓存
+	if err = m.Cache.Set(ctx, cacheKey, userCache); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	// Register session in user index | 在用户会话索引中登记
+	if _, ok := index[deviceId]; !ok {
+		m.Telemetry.sessionsChanged(ctx, 1)
+	}
+	index[deviceId] = createTime
+	if err = m.Cache.Set(ctx, sessionIndexKey(userKey), index); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	m.emit(ctx, token, newSessionEvent(EventGenerated, userCache))
+	return token, nil
+}
+
+// ListSessions returns all active sessions of user ordered by creation time | 按创建时间返回用户的所有有效会话
+func (m *GTokenV2) ListSessions(ctx context.Context, userKey string) ([]*Session, error) {
+	if err := checkSessionKey(userKey, ""); err != nil {
+		return nil, err
+	}
+	index, err := m.Cache.Get(ctx, sessionIndexKey(userKey))
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	sessions := make([]*Session, 0, len(index))
+	for deviceId := range index {
+		userCache, err := m.Cache.Get(ctx, sessionKey(userKey, deviceId))
+		if err != nil {
+			return nil, gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+		if userCache == nil {
+			continue // Session expired or destroyed | 会话已过期或已销毁
+		}
+		sessions = append(sessions, newSessionFromCache(userCache, m.options().Timeout))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreateTime < sessions[j].CreateTime
+	})
+	return sessions, nil
+}
+
+// DestroySession removes a single device session of user | 销毁用户在指定设备上的会话
+func (m *GTokenV2) DestroySession(ctx context.Context, userKey, deviceId string) (err error) {
+	ctx, span := m.Telemetry.start(ctx, SpanDestroySession)
+	defer func() { m.Telemetry.end(span, err) }()
+
+	if err = checkSessionKey(userKey, deviceId); err != nil {
+		return err
+	}
+	unlock := m.sessionLocks.lock(sessionIndexKey(userKey))
+	defer unlock()
+	index, err := m.loadSessionIndex(ctx, userKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if err = m.Cache.Remove(ctx, sessionKey(userKey, deviceId)); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if err = m.removeRefreshToken(ctx, sessionKey(userKey, deviceId)); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+
+	if _, ok := index[deviceId]; ok {
+		m.Telemetry.sessionsChanged(ctx, -1)
+	}
+	delete(index, deviceId)
+	if err = m.saveSessionIndex(ctx, userKey, index); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	m.emit(ctx, "", &Event{Type: EventDestroyed, UserKey: userKey, DeviceId: deviceId})
+	return nil
+}
+
+// loadSessionIndex loads user session index and drops expired sessions | 加载用户会话索引并剔除已失效的会话
+func (m *GTokenV2) loadSessionIndex(ctx context.Context, userKey string) (g.Map, error) {
+	index, err := m.Cache.Get(ctx, sessionIndexKey(userKey))
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return g.Map{}, nil
+	}
+	for deviceId := range index {
+		userCache, err := m.Cache.Get(ctx, sessionKey(userKey, deviceId))
+		if err != nil {
+			return nil, err
+		}
+		if userCache == nil {
+			delete(index, deviceId)
+		}
+	}
+	return index, nil
+}
+
+// saveSessionIndex writes user session index, removing it when empty | 写入用户会话索引，为空时删除
+func (m *GTokenV2) saveSessionIndex(ctx context.Context, userKey string, index g.Map) error {
+	if len(index) == 0 {
+		return m.Cache.Remove(ctx, sessionIndexKey(userKey))
+	}
+	return m.Cache.Set(ctx, sessionIndexKey(userKey), index)
+}
+
+// touchSessionIndex keeps the index alive as long as its sessions | 续期会话时同步延长索引的有效期
+// Sessions missing from the index were destroyed meanwhile and are not added back | 索引中不存在的会话已被并发销毁，不会重新加入
+func (m *GTokenV2) touchSessionIndex(ctx context.Context, userKey, deviceId string) error {
+	unlock := m.sessionLocks.lock(sessionIndexKey(userKey))
+	defer unlock()
+	index, err := m.Cache.Get(ctx, sessionIndexKey(userKey))
+	if err != nil {
+		return err
+	}
+	if _, ok := index[deviceId]; !ok {
+		return nil
+	}
+	return m.Cache.Set(ctx, sessionIndexKey(userKey), index)
+}
+
+// oldestDevice returns the deviceId with the earliest creation time | 返回创建时间最早的设备标识
+func oldestDevice(index g.Map) string {
+	var (
+		oldest     string
+		oldestTime int64 = -1
+	)
+	for deviceId, createTime := range index {
+		t := gconv.Int64(createTime)
+		if oldestTime < 0 || t < oldestTime || (t == oldestTime && deviceId < oldest) {
+			oldest, oldestTime = deviceId, t
+		}
+	}
+	return oldest
+}
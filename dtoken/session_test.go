@@ -0,0 +1,227 @@
+package dtoken
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestToken(t *testing.T, options Options) *GTokenV2 {
+	if options.CachePreKey == "" {
+		options.CachePreKey = "Test:" + t.Name() + ":"
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.RefreshTimeout == 0 {
+		options.RefreshTimeout = DefaultRefreshTimeout
+	}
+	if options.SessionEvictPolicy == 0 {
+		options.SessionEvictPolicy = SessionEvictOldest
+	}
+	pool, err := NewRenewPoolBuilder().MinSize(2).MaxSize(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Stop)
+	return &GTokenV2{
+		Options:          options,
+		Codec:            NewDefaultCodec(DefaultTokenDelimiter, []byte(DefaultEncryptKey)),
+		Cache:            NewDefaultCache(CacheModeCache, options.CachePreKey, options.Timeout),
+		RefreshCache:     NewDefaultCache(CacheModeCache, options.CachePreKey+RefreshPreKey, options.RefreshTimeout),
+		RevokeCache:      NewDefaultCache(CacheModeCache, options.CachePreKey+RevokePreKey, options.RefreshTimeout),
+		Events:           NewEventBus(),
+		RenewPoolManager: pool,
+	}
+}
+
+func TestSession_MultiDevice(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	webToken, err := token.GenerateWithDevice(ctx, "alice", "web", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	appToken, err := token.GenerateWithDevice(ctx, "alice", "app", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webToken == appToken {
+		t.Fatal("expected independent tokens per device")
+	}
+
+	userKey, data, err := token.ParseToken(ctx, appToken)
+	if err != nil || userKey != "alice" || data != "a" {
+		t.Fatalf("unexpected parse result: %s %v %v", userKey, data, err)
+	}
+
+	sessions, err := token.ListSessions(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].DeviceId != "web" || sessions[1].DeviceId != "app" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	if err = token.DestroySession(ctx, "alice", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, webToken); err == nil {
+		t.Fatal("expected destroyed session to be invalid")
+	}
+	if _, err = token.Validate(ctx, appToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = token.Destroy(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ = token.ListSessions(ctx, "alice"); len(sessions) != 0 {
+		t.Fatalf("expected no sessions, got %d", len(sessions))
+	}
+}
+
+func TestSession_MaxSessions(t *testing.T) {
+	ctx := context.Background()
+
+	evict := newTestToken(t, Options{MaxSessions: 2, SessionEvictPolicy: SessionEvictOldest})
+	first, _ := evict.GenerateWithDevice(ctx, "bob", "d1", nil)
+	time.Sleep(2 * time.Millisecond)
+	_, _ = evict.GenerateWithDevice(ctx, "bob", "d2", nil)
+	time.Sleep(2 * time.Millisecond)
+	if _, err := evict.GenerateWithDevice(ctx, "bob", "d3", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := evict.Validate(ctx, first); err == nil {
+		t.Fatal("expected oldest session to be evicted")
+	}
+
+	reject := newTestToken(t, Options{MaxSessions: 1, SessionEvictPolicy: SessionEvictReject})
+	if _, err := reject.GenerateWithDevice(ctx, "bob", "d1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reject.GenerateWithDevice(ctx, "bob", "d2", nil); err == nil {
+		t.Fatal("expected login to be rejected")
+	}
+	if _, err := reject.GenerateWithDevice(ctx, "bob", "d1", nil); err != nil {
+		t.Fatal("re-login on the same device should be allowed:", err)
+	}
+}
+
+func TestSession_KeyCollision(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	// "alice#phone" would share the cache key of alice on device phone
+	if _, err := token.GenerateWithDevice(ctx, "alice", "phone", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := token.Generate(ctx, "alice#phone", nil); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if _, err := token.GenerateWithDevice(ctx, "alice", "phone#2", nil); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid deviceId, got %v", err)
+	}
+
+	// "sessions:bob" would overwrite the session index of bob
+	if _, err := token.GenerateWithDevice(ctx, "bob", "web", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := token.GenerateWithDevice(ctx, SessionIndexPreKey+"bob", "web", nil); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if _, err := token.ListSessions(ctx, SessionIndexPreKey+"bob"); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if err := token.DestroySession(ctx, "alice#phone", ""); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if sessions, _ := token.ListSessions(ctx, "bob"); len(sessions) != 1 {
+		t.Fatalf("expected index of bob to be intact, got %d sessions", len(sessions))
+	}
+
+	// "tenant:acme:bob" would share the cache key of bob in tenant acme
+	acme, err := token.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = acme.GenerateWithDevice(ctx, "bob", "web", "acme-data"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.GenerateWithDevice(ctx, TenantPreKey+"acme:bob", "web", nil); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if _, _, err = token.Get(ctx, TenantPreKey+"acme:bob#web"); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if err = token.SetGrants(ctx, TenantPreKey+"acme:bob", "web", &Grants{Roles: []string{"admin"}}); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if err = token.DestroySession(ctx, TenantPreKey+"acme:bob", "web"); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("expected invalid userKey, got %v", err)
+	}
+	if sessions, _ := acme.ListSessions(ctx, "bob"); len(sessions) != 1 || sessions[0].Data != "acme-data" {
+		t.Fatalf("expected acme session of bob to be intact, got %+v", sessions)
+	}
+	if sessions, _ := token.ListSessions(ctx, "alice"); len(sessions) != 1 {
+		t.Fatalf("expected session of alice to be intact, got %d sessions", len(sessions))
+	}
+}
+
+// slowCache widens the read-modify-write window of the wrapped cache
+type slowCache struct {
+	Cache
+}
+
+func (c *slowCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	cacheValue, err := c.Cache.Get(ctx, cacheKey)
+	time.Sleep(time.Millisecond)
+	return cacheValue, err
+}
+
+func TestSession_ConcurrentIndex(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{MaxSessions: 3})
+	token.Cache = &slowCache{Cache: token.Cache}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := token.GenerateWithDevice(ctx, "carol", fmt.Sprintf("d%d", i), nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	index, err := token.Cache.Get(ctx, sessionIndexKey("carol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 3 {
+		t.Fatalf("expected 3 indexed sessions, got %d", len(index))
+	}
+	if sessions, _ := token.ListSessions(ctx, "carol"); len(sessions) != 3 {
+		t.Fatalf("expected 3 live sessions, got %d", len(sessions))
+	}
+
+	// Renewal must not bring a destroyed device back into the index
+	sessions, _ := token.ListSessions(ctx, "carol")
+	if err = token.DestroySession(ctx, "carol", sessions[0].DeviceId); err != nil {
+		t.Fatal(err)
+	}
+	if err = token.touchSessionIndex(ctx, "carol", sessions[0].DeviceId); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ = token.Cache.Get(ctx, sessionIndexKey("carol")); len(index) != 2 {
+		t.Fatalf("expected destroyed device to stay out of the index, got %d", len(index))
+	}
+}
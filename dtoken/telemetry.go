@@ -0,0 +1,354 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"time"
+)
+
+// Telemetry names | 遥测名称
+const (
+	TelemetryScope = "github.com/Zany2/dtoken/dtoken" // Instrumentation scope | 埋点作用域
+
+	SpanGenerate       = "dtoken.Generate"       // Span of GenerateWithDevice | 生成 Token 的 Span
+	SpanValidate       = "dtoken.Validate"       // Span of Validate | 校验 Token 的 Span
+	SpanRenew          = "dtoken.Renew"          // Span of the async renewal task | 异步续期任务的 Span
+	SpanDestroy        = "dtoken.Destroy"        // Span of Destroy | 销毁用户会话的 Span
+	SpanDestroySession = "dtoken.DestroySession" // Span of DestroySession | 销毁设备会话的 Span
+	SpanCachePrefix    = "dtoken.Cache."         // Span prefix of cache calls | 缓存调用的 Span 前缀
+	SpanCodecPrefix    = "dtoken.Codec."         // Span prefix of codec calls | 编解码调用的 Span 前缀
+
+	MetricValidations   = "dtoken.validations"         // Validations by outcome | 按结果统计的校验次数
+	MetricRenewals      = "dtoken.renewals"            // Renewals by outcome | 按结果统计的续期次数
+	MetricSessions      = "dtoken.sessions.active"     // Estimated active sessions of this instance | 本实例估算的活跃会话数
+	MetricCacheDuration = "dtoken.cache.duration"      // Cache call latency | 缓存调用耗时
+	MetricPoolRunning   = "dtoken.renew_pool.running"  // Running renew tasks | 正在运行的续期任务数
+	MetricPoolCapacity  = "dtoken.renew_pool.capacity" // Renew pool capacity | 续期协程池容量
+	MetricPoolUsage     = "dtoken.renew_pool.usage"    // Renew pool usage ratio | 续期协程池使用率
+
+	AttrOutcome   = "dtoken.outcome"   // success or one of the fixed failure reasons | success 或固定的失败原因之一
+	AttrCache     = "dtoken.cache"     // Cache name: token refresh revoke | 缓存名称
+	AttrOperation = "dtoken.operation" // Cache operation | 缓存操作
+
+	OutcomeSuccess = "success" // Successful outcome | 成功
+	OutcomeFailure = "failure" // Failed outcome without a more specific reason | 无更具体原因的失败
+	OutcomeMissing = "missing" // No token given | 未提供 Token
+	OutcomeInvalid = "invalid" // Token malformed, tampered or of another tenant | Token 格式错误、被篡改或属于其他租户
+	OutcomeExpired = "expired" // Session expired or destroyed | 会话已过期或已销毁
+	OutcomeKicked  = "kicked"  // Session replaced by a newer login | 会话已被新的登录顶替
+	OutcomeRevoked = "revoked" // Token revoked | Token 已被吊销
+
+	CacheNameToken   = "token"   // Session cache | 会话缓存
+	CacheNameRefresh = "refresh" // Refresh token cache | 刷新令牌缓存
+	CacheNameRevoke  = "revoke"  // Revocation cache | 吊销缓存
+)
+
+// Telemetry holds OpenTelemetry tracer and instruments, nil disables telemetry | 持有 OpenTelemetry 的 Tracer 与指标，为 nil 时不采集
+type Telemetry struct {
+	tracer        trace.Tracer
+	meter         metric.Meter
+	validations   metric.Int64Counter
+	renewals      metric.Int64Counter
+	sessions      metric.Int64UpDownCounter
+	cacheDuration metric.Float64Histogram
+}
+
+// NewTelemetry creates instruments from the providers (nil uses the global ones) | 使用指定 Provider 创建埋点（为 nil 时使用全局 Provider）
+func NewTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (*Telemetry, error) {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+	t := &Telemetry{
+		tracer: tracerProvider.Tracer(TelemetryScope, trace.WithInstrumentationVersion(Version)),
+		meter:  meterProvider.Meter(TelemetryScope, metric.WithInstrumentationVersion(Version)),
+	}
+
+	var err error
+	if t.validations, err = t.meter.Int64Counter(MetricValidations,
+		metric.WithDescription("Token validations by outcome")); err != nil {
+		return nil, err
+	}
+	if t.renewals, err = t.meter.Int64Counter(MetricRenewals,
+		metric.WithDescription("Token renewals by outcome")); err != nil {
+		return nil, err
+	}
+	if t.sessions, err = t.meter.Int64UpDownCounter(MetricSessions,
+		metric.WithDescription("Sessions created minus sessions destroyed by this instance, expirations are not observed")); err != nil {
+		return nil, err
+	}
+	if t.cacheDuration, err = t.meter.Float64Histogram(MetricCacheDuration,
+		metric.WithDescription("Token cache call latency"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// start starts a span, returning a no-op span when telemetry is disabled | 开始一个 Span，未启用遥测时返回空 Span
+func (t *Telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end records the error on span and ends it | 在 Span 上记录错误并结束
+func (t *Telemetry) end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// validated counts a validation, failures are labelled by reason | 统计校验次数，失败按原因区分
+func (t *Telemetry) validated(ctx context.Context, err error) {
+	if t == nil {
+		return
+	}
+	t.validations.Add(ctx, 1, metric.WithAttributes(attribute.String(AttrOutcome, validationOutcome(err))))
+}
+
+// validationOutcome maps a validation error to a fixed outcome, keeping metric cardinality bounded | 将校验错误映射为固定的结果，限制指标基数
+func validationOutcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	switch gerror.Code(err) {
+	case CodeTokenMissing:
+		return OutcomeMissing
+	case CodeTokenInvalid, CodeTenantInvalid:
+		return OutcomeInvalid
+	case CodeTokenExpired:
+		return OutcomeExpired
+	case CodeTokenKicked:
+		return OutcomeKicked
+	case CodeTokenRevoked:
+		return OutcomeRevoked
+	default:
+		return OutcomeFailure
+	}
+}
+
+// renewed counts a renewal | 统计续期次数
+func (t *Telemetry) renewed(ctx context.Context, err error) {
+	if t == nil {
+		return
+	}
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+	t.renewals.Add(ctx, 1, metric.WithAttributes(attribute.String(AttrOutcome, outcome)))
+}
+
+// sessionsChanged adjusts the active sessions estimate | 调整活跃会话估算值
+func (t *Telemetry) sessionsChanged(ctx context.Context, delta int64) {
+	if t == nil || delta == 0 {
+		return
+	}
+	t.sessions.Add(ctx, delta)
+}
+
+// observePool registers renew pool gauges | 注册续期协程池指标
+func (t *Telemetry) observePool(pool *RenewPoolManager) (metric.Registration, error) {
+	running, err := t.meter.Int64ObservableGauge(MetricPoolRunning, metric.WithDescription("Running renew tasks"))
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := t.meter.Int64ObservableGauge(MetricPoolCapacity, metric.WithDescription("Renew pool capacity"))
+	if err != nil {
+		return nil, err
+	}
+	usage, err := t.meter.Float64ObservableGauge(MetricPoolUsage, metric.WithDescription("Renew pool usage ratio"))
+	if err != nil {
+		return nil, err
+	}
+	return t.meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		r, c, u := pool.Stats()
+		observer.ObserveInt64(running, int64(r))
+		observer.ObserveInt64(capacity, int64(c))
+		observer.ObserveFloat64(usage, u)
+		return nil
+	}, running, capacity, usage)
+}
+
+// EnableTelemetry wraps codec and caches with tracing and registers metrics | 为编解码器与缓存添加链路追踪并注册指标
+// Call it before the token is used, it is not safe for concurrent use | 需在使用 Token 之前调用，非并发安全
+func (m *GTokenV2) EnableTelemetry(t *Telemetry) error {
+	if t == nil || m.Telemetry != nil {
+		return nil
+	}
+	if m.RenewPoolManager != nil {
+		registration, err := t.observePool(m.RenewPoolManager)
+		if err != nil {
+			return err
+		}
+		m.poolMetrics = registration
+	}
+	m.Telemetry = t
+	m.Codec = NewTracedCodec(m.Codec, t)
+	m.Cache = NewTracedCache(m.Cache, CacheNameToken, t)
+	if m.RefreshCache != nil {
+		m.RefreshCache = NewTracedCache(m.RefreshCache, CacheNameRefresh, t)
+	}
+	if m.RevokeCache != nil {
+		m.RevokeCache = NewTracedCache(m.RevokeCache, CacheNameRevoke, t)
+	}
+	return nil
+}
+
+// TracedCache decorates a Cache with spans and latency metrics | 为 Cache 添加 Span 与耗时指标的装饰器
+type TracedCache struct {
+	Cache     Cache      // Decorated cache | 被装饰的缓存
+	Name      string     // Cache name used as attribute | 作为属性的缓存名称
+	Telemetry *Telemetry // Telemetry instruments | 遥测埋点
+}
+
+// tracedRenewerCache keeps Renewer available when the decorated cache supports it | 被装饰缓存支持原子续期时保留 Renewer 能力
+type tracedRenewerCache struct {
+	*TracedCache
+}
+
+// NewTracedCache decorates cache, preserving its optional interfaces | 装饰缓存并保留其可选接口
+// Renewer is kept only when cache implements it, the others report CodeNotSupported or do nothing when cache lacks them |
+// 仅当被装饰缓存实现 Renewer 时保留该接口，其余接口在被装饰缓存未实现时返回 CodeNotSupported 或不做任何操作
+func NewTracedCache(cache Cache, name string, t *Telemetry) Cache {
+	traced := &TracedCache{Cache: cache, Name: name, Telemetry: t}
+	if _, ok := cache.(Renewer); ok {
+		return &tracedRenewerCache{traced}
+	}
+	return traced
+}
+
+// Set implements Cache | 实现 Cache 接口
+func (c *TracedCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	return c.observe(ctx, "Set", func(ctx context.Context) error {
+		return c.Cache.Set(ctx, cacheKey, cacheValue)
+	})
+}
+
+// Get implements Cache | 实现 Cache 接口
+func (c *TracedCache) Get(ctx context.Context, cacheKey string) (cacheValue g.Map, err error) {
+	err = c.observe(ctx, "Get", func(ctx context.Context) (err error) {
+		cacheValue, err = c.Cache.Get(ctx, cacheKey)
+		return err
+	})
+	return cacheValue, err
+}
+
+// Remove implements Cache | 实现 Cache 接口
+func (c *TracedCache) Remove(ctx context.Context, cacheKey string) error {
+	return c.observe(ctx, "Remove", func(ctx context.Context) error {
+		return c.Cache.Remove(ctx, cacheKey)
+	})
+}
+
+// Close closes the decorated cache if it holds resources | 关闭持有资源的被装饰缓存
+func (c *TracedCache) Close() error {
+	if closer, ok := c.Cache.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Unwrap returns the decorated cache | 返回被装饰的缓存
+func (c *TracedCache) Unwrap() Cache {
+	return c.Cache
+}
+
+// SetTimeout implements TimeoutSetter when the decorated cache does | 被装饰缓存实现 TimeoutSetter 时实现该接口
+func (c *TracedCache) SetTimeout(timeout int64) {
+	if setter, ok := c.Cache.(TimeoutSetter); ok {
+		setter.SetTimeout(timeout)
+	}
+}
+
+// Scan implements Scanner when the decorated cache does | 被装饰缓存实现 Scanner 时实现该接口
+func (c *TracedCache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	scanner, ok := c.Cache.(Scanner)
+	if !ok {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrScanOff)
+	}
+	return c.observe(ctx, "Scan", func(ctx context.Context) error {
+		return scanner.Scan(ctx, prefix, fn)
+	})
+}
+
+// Renew implements Renewer | 实现 Renewer 接口
+func (c *tracedRenewerCache) Renew(ctx context.Context, cacheKey string, token string, renewTime int64) (renewed bool, err error) {
+	err = c.observe(ctx, "Renew", func(ctx context.Context) (err error) {
+		renewed, err = c.Cache.(Renewer).Renew(ctx, cacheKey, token, renewTime)
+		return err
+	})
+	return renewed, err
+}
+
+// Swap implements Swapper when the decorated cache does | 被装饰缓存实现 Swapper 时实现该接口
+func (c *TracedCache) Swap(ctx context.Context, cacheKey string, token string, cacheValue g.Map) (swapped bool, err error) {
+	swapper, ok := c.Cache.(Swapper)
+	if !ok {
+		return false, gerror.NewCode(gcode.CodeNotSupported, MsgErrSwapOff)
+	}
+	err = c.observe(ctx, "Swap", func(ctx context.Context) (err error) {
+		swapped, err = swapper.Swap(ctx, cacheKey, token, cacheValue)
+		return err
+	})
+	return swapped, err
+}
+
+// observe runs a cache call inside a span and records its latency | 在 Span 中执行缓存调用并记录耗时
+func (c *TracedCache) observe(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	attrs := []attribute.KeyValue{attribute.String(AttrCache, c.Name), attribute.String(AttrOperation, operation)}
+	ctx, span := c.Telemetry.start(ctx, SpanCachePrefix+operation, attrs...)
+	startTime := time.Now()
+	err := fn(ctx)
+	c.Telemetry.cacheDuration.Record(ctx, time.Since(startTime).Seconds(), metric.WithAttributes(attrs...))
+	c.Telemetry.end(span, err)
+	return err
+}
+
+// TracedCodec decorates a Codec with spans | 为 Codec 添加 Span 的装饰器
+type TracedCodec struct {
+	Codec     Codec      // Decorated codec | 被装饰的编解码器
+	Telemetry *Telemetry // Telemetry instruments | 遥测埋点
+}
+
+// NewTracedCodec creates a new TracedCodec instance | 创建一个新的 TracedCodec 实例
+func NewTracedCodec(codec Codec, t *Telemetry) *TracedCodec {
+	return &TracedCodec{Codec: codec, Telemetry: t}
+}
+
+// Encode implements Codec | 实现 Codec 接口
+func (c *TracedCodec) Encode(ctx context.Context, userKey string) (token string, err error) {
+	ctx, span := c.Telemetry.start(ctx, SpanCodecPrefix+"Encode")
+	token, err = c.Codec.Encode(ctx, userKey)
+	c.Telemetry.end(span, err)
+	return token, err
+}
+
+// Decrypt implements Codec | 实现 Codec 接口
+func (c *TracedCodec) Decrypt(ctx context.Context, token string) (userKey string, err error) {
+	ctx, span := c.Telemetry.start(ctx, SpanCodecPrefix+"Decrypt")
+	userKey, err = c.Codec.Decrypt(ctx, token)
+	c.Telemetry.end(span, err)
+	return userKey, err
+}
+
+// TokenId implements TokenIdentifier, empty when the decorated codec has no ids | 实现 TokenIdentifier，被装饰编解码器无标识时返回空
+func (c *TracedCodec) TokenId(ctx context.Context, token string) (string, error) {
+	if identifier, ok := c.Codec.(TokenIdentifier); ok {
+		return identifier.TokenId(ctx, token)
+	}
+	return "", nil
+}
@@ -0,0 +1,104 @@
+package dtoken
+
+import (
+	"context"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTelemetry_SpansAndMetrics(t *testing.T) {
+	ctx := context.Background()
+	spans := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	telemetry, err := NewTelemetry(tracerProvider, meterProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := newTestToken(t, Options{})
+	if err = token.EnableTelemetry(telemetry); err != nil {
+		t.Fatal(err)
+	}
+
+	userToken, err := token.GenerateWithDevice(ctx, "alice", "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, userToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, "bad"); err == nil {
+		t.Fatal("expected invalid token")
+	}
+	if _, err = token.GenerateWithDevice(ctx, "alice", "app", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = token.DestroySession(ctx, "alice", "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spans
+	names := make(map[string]int)
+	for _, span := range spans.GetSpans() {
+		names[span.Name]++
+	}
+	for _, name := range []string{SpanGenerate, SpanValidate, SpanDestroySession, SpanCachePrefix + "Get", SpanCachePrefix + "Set", SpanCodecPrefix + "Encode", SpanCodecPrefix + "Decrypt"} {
+		if names[name] == 0 {
+			t.Fatalf("missing span %s in %v", name, names)
+		}
+	}
+
+	// Metrics
+	var rm metricdata.ResourceMetrics
+	if err = reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	validations, ok := metrics[MetricValidations].(metricdata.Sum[int64])
+	if !ok || len(validations.DataPoints) != 2 {
+		t.Fatalf("expected validations by outcome, got %#v", metrics[MetricValidations])
+	}
+	outcomes := make(map[string]int64)
+	for _, point := range validations.DataPoints {
+		outcome, _ := point.Attributes.Value(AttrOutcome)
+		outcomes[outcome.AsString()] = point.Value
+	}
+	if outcomes[OutcomeSuccess] != 1 || outcomes[OutcomeInvalid] != 1 {
+		t.Fatalf("expected fixed outcome labels, got %v", outcomes)
+	}
+	sessions, ok := metrics[MetricSessions].(metricdata.Sum[int64])
+	if !ok || len(sessions.DataPoints) != 1 || sessions.DataPoints[0].Value != 1 {
+		t.Fatalf("expected one active session, got %#v", metrics[MetricSessions])
+	}
+	if _, ok = metrics[MetricCacheDuration].(metricdata.Histogram[float64]); !ok {
+		t.Fatalf("expected cache latency histogram, got %#v", metrics[MetricCacheDuration])
+	}
+	capacity, ok := metrics[MetricPoolCapacity].(metricdata.Gauge[int64])
+	if !ok || len(capacity.DataPoints) != 1 || capacity.DataPoints[0].Value != 2 {
+		t.Fatalf("expected pool capacity gauge, got %#v", metrics[MetricPoolCapacity])
+	}
+
+	// Pool gauges stop after shutdown
+	token.Shutdown(ctx)
+	rm = metricdata.ResourceMetrics{}
+	if err = reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == MetricPoolCapacity {
+				t.Fatal("expected pool gauges to be unregistered")
+			}
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Tenant lookup types of Options.TenantLookup | Options.TenantLookup 的查找类型
+const (
+	LookupHost = "host" // "host" or "host:<suffix>", leftmost label of the host | 主机名最左侧的标签
+	LookupPath = "path" // "path:<index>", path segment at index starting from 0 | 从 0 开始的第 index 个路径段
+)
+
+// TenantOptions overrides Options for one tenant, zero values inherit | 单个租户的配置覆盖项，零值表示继承
+type TenantOptions struct {
+	Timeout    int64  // Token expiration (ms), builds caches of CacheMode dedicated to the tenant | Token 有效期（毫秒），会为租户单独构建 CacheMode 缓存
+	MaxRefresh int64  // Refresh window (ms) | 续期窗口（毫秒）
+	MultiLogin *bool  // Allow multi-login | 是否允许多端登录
+	EncryptKey []byte // Encryption key, builds a codec of CodecMode dedicated to the tenant | 加密密钥，会为租户单独构建 CodecMode 编解码器
+}
+
+// TenantToken is implemented by tokens serving several tenants | 支持多租户的 Token 实现该接口
+type TenantToken interface {
+	Tenant(tenantId string) (Token, error) // Token scoped to tenantId ("" for the default namespace) | 限定在租户内的 Token（"" 表示默认命名空间）
+}
+
+// TenantResolver finds the tenant of a request | 解析请求所属的租户
+type TenantResolver interface {
+	// Resolve returns the tenantId, "" when the request carries none | 返回租户标识，请求未携带时返回 ""
+	Resolve(c Carrier) (string, error)
+}
+
+// TenantResolverFunc adapts a function to TenantResolver | 将函数适配为 TenantResolver
+type TenantResolverFunc func(c Carrier) (string, error)
+
+// Resolve implements TenantResolver | 实现 TenantResolver 接口
+func (f TenantResolverFunc) Resolve(c Carrier) (string, error) {
+	return f(c)
+}
+
+// HostTenantResolver takes the tenant from the leftmost host label, "acme" for "acme.example.com" | 从主机名最左侧的标签获取租户，如 "acme.example.com" 中的 "acme"
+type HostTenantResolver struct {
+	Suffix string // Required domain suffix such as "example.com", other hosts carry no tenant | 要求的域名后缀，如 "example.com"，其他主机不携带租户
+}
+
+// Resolve implements TenantResolver | 实现 TenantResolver 接口
+func (r HostTenantResolver) Resolve(c Carrier) (string, error) {
+	host := c.Host()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return "", nil
+	}
+	host = strings.ToLower(host)
+	if r.Suffix != "" {
+		prefix, ok := strings.CutSuffix(host, "."+strings.ToLower(strings.TrimPrefix(r.Suffix, ".")))
+		if !ok {
+			return "", nil
+		}
+		host = prefix + "."
+	}
+	label, _, ok := strings.Cut(host, ".")
+	if !ok {
+		return "", nil
+	}
+	return label, nil
+}
+
+// HeaderTenantResolver takes the tenant from a request header | 从请求头获取租户
+type HeaderTenantResolver struct {
+	Name string // Header name such as "X-Tenant-Id" | 请求头名称，如 "X-Tenant-Id"
+}
+
+// Resolve implements TenantResolver | 实现 TenantResolver 接口
+func (r HeaderTenantResolver) Resolve(c Carrier) (string, error) {
+	return strings.TrimSpace(c.Header(r.Name)), nil
+}
+
+// PathTenantResolver takes the tenant from a path segment, index 0 gives "acme" for "/acme/orders" | 从路径段获取租户，index 为 0 时 "/acme/orders" 得到 "acme"
+type PathTenantResolver struct {
+	Index int // Segment index starting from 0 | 从 0 开始的路径段序号
+}
+
+// Resolve implements TenantResolver | 实现 TenantResolver 接口
+func (r PathTenantResolver) Resolve(c Carrier) (string, error) {
+	segments := splitPath(c.Path())
+	if r.Index < 0 || r.Index >= len(segments) {
+		return "", nil
+	}
+	return segments[r.Index], nil
+}
+
+// TenantResolverChain tries resolvers in order and returns the first tenant found | 依次尝试解析器，返回首个解析到的租户
+type TenantResolverChain []TenantResolver
+
+// NewTenantResolverChain creates a chain from resolvers | 使用解析器创建解析链
+func NewTenantResolverChain(resolvers ...TenantResolver) TenantResolverChain {
+	return resolvers
+}
+
+// Resolve implements TenantResolver | 实现 TenantResolver 接口
+func (chain TenantResolverChain) Resolve(c Carrier) (string, error) {
+	for _, resolver := range chain {
+		tenantId, err := resolver.Resolve(c)
+		if err != nil || tenantId != "" {
+			return tenantId, err
+		}
+	}
+	return "", nil
+}
+
+// NewTenantResolverByOptions builds the chain described by TenantLookup, nil when empty | 根据 TenantLookup 构建解析链，为空时返回 nil
+func NewTenantResolverByOptions(options Options) (TenantResolver, error) {
+	if len(options.TenantLookup) == 0 {
+		return nil, nil
+	}
+	chain := make(TenantResolverChain, 0, len(options.TenantLookup))
+	for _, item := range options.TenantLookup {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		switch {
+		case parts[0] == LookupHost && len(parts) == 1:
+			chain = append(chain, HostTenantResolver{})
+		case parts[0] == LookupHost && parts[1] != "":
+			chain = append(chain, HostTenantResolver{Suffix: parts[1]})
+		case parts[0] == LookupHeader && len(parts) == 2 && parts[1] != "":
+			chain = append(chain, HeaderTenantResolver{Name: parts[1]})
+		case parts[0] == LookupPath && len(parts) == 2:
+			index, err := strconv.Atoi(parts[1])
+			if err != nil || index < 0 {
+				return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid TenantLookup item %q", item)
+			}
+			chain = append(chain, PathTenantResolver{Index: index})
+		default:
+			return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "invalid TenantLookup item %q", item)
+		}
+	}
+	return chain, nil
+}
+
+// mustTenantResolverByOptions builds the chain, panicking on invalid config | 构建解析链，配置错误时 panic
+func mustTenantResolverByOptions(options Options) TenantResolver {
+	resolver, err := NewTenantResolverByOptions(options)
+	if err != nil {
+		panic("invalid config: " + err.Error() + " | TenantLookup 配置错误")
+	}
+	return resolver
+}
+
+// ResolveTenant returns the token scoped to the tenant of the request and the tenantId | 返回限定在请求所属租户内的 Token 及租户标识
+// token is returned as is when resolver is nil or the request carries no tenant | resolver 为 nil 或请求未携带租户时原样返回 token
+func ResolveTenant(token Token, resolver TenantResolver, c Carrier) (Token, string, error) {
+	if resolver == nil {
+		return token, "", nil
+	}
+	tenantId, err := resolver.Resolve(c)
+	if err != nil || tenantId == "" {
+		return token, "", err
+	}
+	tenantToken, ok := token.(TenantToken)
+	if !ok {
+		return nil, "", gerror.NewCode(gcode.CodeNotSupported, MsgErrTenantOff)
+	}
+	scoped, err := tenantToken.Tenant(tenantId)
+	if err != nil {
+		return nil, "", err
+	}
+	return scoped, tenantId, nil
+}
+
+// tenantContextKey is the context key of the request tenant | 请求租户的上下文 key
+type tenantContextKey struct{}
+
+// WithTenant stores the tenantId in ctx | 将租户标识存入上下文
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantId)
+}
+
+// TenantFromContext returns the tenantId resolved for the request | 返回为请求解析出的租户标识
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantId, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantId, ok && tenantId != ""
+}
+
+// checkTenantId rejects tenantIds that would break key namespacing | 拒绝会破坏 key 命名空间的租户标识
+func checkTenantId(tenantId string) error {
+	if tenantId == "" || strings.ContainsAny(tenantId, DefaultTenantDelimiter+DefaultDeviceDelimiter+":/ ") {
+		return gerror.NewCodef(CodeTenantInvalid, "%s: %q", MsgErrTenant, tenantId)
+	}
+	return nil
+}
+
+// tenantOptions returns options with the overrides of tenantId applied | 返回应用租户覆盖项后的配置
+func (opt Options) tenantOptions(tenantId string) Options {
+	tenant := opt.Tenants[tenantId]
+	opt.Tenants = nil
+	if tenant.Timeout > 0 {
+		opt.Timeout = tenant.Timeout
+		if opt.RefreshTimeout <= opt.Timeout {
+			opt.RefreshTimeout = opt.Timeout * 2
+		}
+	}
+	if tenant.MaxRefresh > 0 {
+		opt.MaxRefresh = tenant.MaxRefresh
+	}
+	if tenant.MultiLogin != nil {
+		opt.MultiLogin = *tenant.MultiLogin
+	}
+	if len(tenant.EncryptKey) > 0 {
+		opt.EncryptKey, opt.EncryptKeys = tenant.EncryptKey, nil
+		if opt.CodecMode == CodecModeJWT && opt.JwtAlg == JwtAlgHS256 {
+			opt.JwtSecret = tenant.EncryptKey
+		}
+	}
+	return opt
+}
+
+// Tenant implements TenantToken, tenants without TenantOptions share the default caches under their own prefix | 实现 TenantToken 接口，未配置 TenantOptions 的租户以独立前缀共享默认缓存
+// Tenants are built once and reused, so they keep serializing session and refresh updates | 租户只构建一次并复用，从而持续串行化会话与刷新的更新
+func (m *GTokenV2) Tenant(tenantId string) (Token, error) {
+	if tenantId == "" || tenantId == m.TenantId {
+		return m, nil
+	}
+	if m.TenantId != "" {
+		return nil, gerror.NewCode(CodeTenantInvalid, MsgErrTenant)
+	}
+	if tenant, ok := m.tenants[tenantId]; ok {
+		return tenant, nil
+	}
+	if tenant, ok := m.dynamicTenants.Load(tenantId); ok {
+		return tenant.(*GTokenV2), nil
+	}
+	if err := checkTenantId(tenantId); err != nil {
+		return nil, err
+	}
+
+	// Build under reloadMu so the new tenant cannot miss an UpdateOptions | 在 reloadMu 下构建，避免新租户错过 UpdateOptions
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	if tenant, ok := m.dynamicTenants.Load(tenantId); ok {
+		return tenant.(*GTokenV2), nil
+	}
+	tenant, err := m.newTenant(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	m.dynamicTenants.Store(tenantId, tenant)
+	return tenant, nil
+}
+
+// rangeTenants calls f for configured tenants and tenants created on demand | 对已配置的租户与按需创建的租户调用 f
+func (m *GTokenV2) rangeTenants(f func(tenantId string, tenant *GTokenV2)) {
+	for tenantId, tenant := range m.tenants {
+		f(tenantId, tenant)
+	}
+	m.dynamicTenants.Range(func(key, value any) bool {
+		f(key.(string), value.(*GTokenV2))
+		return true
+	})
+}
+
+// newTenant builds the token of tenantId sharing renew pool, events and telemetry | 构建共享续期协程池、事件与遥测的租户 Token
+func (m *GTokenV2) newTenant(tenantId string) (t *GTokenV2, err error) {
+	tenant := m.options().Tenants[tenantId]
+	t = &GTokenV2{
+		Options:          m.options().tenantOptions(tenantId),
+		TenantId:         tenantId,
+		Codec:            &tenantCodec{Codec: m.Codec, tenantId: tenantId},
+		KeyRing:          m.KeyRing,
+		Events:           m.Events,
+		Telemetry:        m.Telemetry,
+		Logger:           m.Logger,
+		Clock:            m.Clock,
+		RenewPoolManager: m.RenewPoolManager,
+	}
+	if len(tenant.EncryptKey) > 0 {
+		codec, keyRing, err := newCodecByOptions(t.Options)
+		if err != nil {
+			return nil, gerror.WrapCode(gcode.CodeInvalidConfiguration, err)
+		}
+		t.Codec, t.KeyRing = &tenantCodec{Codec: codec, tenantId: tenantId}, keyRing
+	}
+
+	// Namespace cache keys, a Timeout override needs caches with their own TTL | 为缓存 key 加命名空间，覆盖 Timeout 时需要独立 TTL 的缓存
+	prefix := TenantPreKey + tenantId + ":"
+	if tenant.Timeout <= 0 {
+		t.Cache = newTenantCache(m.Cache, prefix)
+		t.RefreshCache = newTenantCache(m.RefreshCache, prefix)
+		t.RevokeCache = newTenantCache(m.RevokeCache, prefix)
+		t.useClock()
+		return t, nil
+	}
+	options := t.Options
+	if t.Cache, err = NewCacheByOptions(options, options.CachePreKey+prefix, options.Timeout); err != nil {
+		return nil, err
+	}
+	if t.RefreshCache, err = NewCacheByOptions(options, options.CachePreKey+prefix+RefreshPreKey, options.RefreshTimeout); err != nil {
+		t.closeCaches()
+		return nil, err
+	}
+	if t.RevokeCache, err = NewCacheByOptions(options, options.CachePreKey+prefix+RevokePreKey, options.RefreshTimeout); err != nil {
+		t.closeCaches()
+		return nil, err
+	}
+	t.useClock()
+	return t, nil
+}
+
+// closeCaches releases caches holding resources such as file locks | 释放持有文件锁等资源的缓存
+func (m *GTokenV2) closeCaches() {
+	for _, cache := range []Cache{m.Cache, m.RefreshCache, m.RevokeCache} {
+		if closer, ok := cache.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				m.logger().Error(context.Background(), "Token cache close error", err)
+			}
+		}
+	}
+}
+
+// tenantCodec binds tokens to a tenant, tokens of other tenants fail to decode | 将 Token 绑定到租户，其他租户的 Token 无法解码
+type tenantCodec struct {
+	Codec
+	tenantId string
+}
+
+// Encode implements Encoder | 实现 Encoder 接口
+func (c *tenantCodec) Encode(ctx context.Context, userKey string) (string, error) {
+	return c.Codec.Encode(ctx, c.tenantId+DefaultTenantDelimiter+userKey)
+}
+
+// Decrypt implements Decoder | 实现 Decoder 接口
+func (c *tenantCodec) Decrypt(ctx context.Context, token string) (string, error) {
+	key, err := c.Codec.Decrypt(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	userKey, ok := strings.CutPrefix(key, c.tenantId+DefaultTenantDelimiter)
+	if !ok {
+		return "", gerror.NewCode(CodeTenantInvalid, MsgErrTenant)
+	}
+	return userKey, nil
+}
+
+// decryptError reports a token of another tenant as CodeTenantInvalid and other failures as CodeTokenInvalid | 其他租户的 Token 报告为 CodeTenantInvalid，其余解码失败报告为 CodeTokenInvalid
+func decryptError(err error) error {
+	if gerror.Code(err) == CodeTenantInvalid {
+		return err
+	}
+	return gerror.WrapCode(CodeTokenInvalid, err)
+}
+
+// tenantCache prefixes keys of a shared cache with the tenant namespace | 为共享缓存的 key 加上租户命名空间前缀
+type tenantCache struct {
+	cache  Cache
+	prefix string
+}
+
+// tenantRenewCache is a tenantCache over a Renewer | 基于 Renewer 的 tenantCache
+type tenantRenewCache struct {
+	*tenantCache
+	renewer Renewer
+}
+
+// newTenantCache wraps cache, keeping Renewer support | 包装缓存，并保留 Renewer 能力
+func newTenantCache(cache Cache, prefix string) Cache {
+	if cache == nil {
+		return nil
+	}
+	c := &tenantCache{cache: cache, prefix: prefix}
+	if renewer, ok := cache.(Renewer); ok {
+		return &tenantRenewCache{tenantCache: c, renewer: renewer}
+	}
+	return c
+}
+
+// Set implements Cache | 实现 Cache 接口
+func (c *tenantCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	return c.cache.Set(ctx, c.prefix+cacheKey, cacheValue)
+}
+
+// Get implements Cache | 实现 Cache 接口
+func (c *tenantCache) Get(ctx context.Context, cacheKey string) (g.Map, error) {
+	return c.cache.Get(ctx, c.prefix+cacheKey)
+}
+
+// Remove implements Cache | 实现 Cache 接口
+func (c *tenantCache) Remove(ctx context.Context, cacheKey string) error {
+	return c.cache.Remove(ctx, c.prefix+cacheKey)
+}
+
+// Scan implements Scanner when the shared cache does | 共享缓存实现 Scanner 时实现该接口
+func (c *tenantCache) Scan(ctx context.Context, prefix string, fn func(cacheKey string) bool) error {
+	scanner, ok := c.cache.(Scanner)
+	if !ok {
+		return gerror.NewCode(gcode.CodeNotSupported, MsgErrScanOff)
+	}
+	return scanner.Scan(ctx, c.prefix+prefix, func(cacheKey string) bool {
+		return fn(strings.TrimPrefix(cacheKey, c.prefix))
+	})
+}
+
+// Swap implements Swapper when the shared cache does | 共享缓存实现 Swapper 时实现该接口
+func (c *tenantCache) Swap(ctx context.Context, cacheKey string, token string, cacheValue g.Map) (bool, error) {
+	swapper, ok := c.cache.(Swapper)
+	if !ok {
+		return false, gerror.NewCode(gcode.CodeNotSupported, MsgErrSwapOff)
+	}
+	return swapper.Swap(ctx, c.prefix+cacheKey, token, cacheValue)
+}
+
+// Renew implements Renewer | 实现 Renewer 接口
+func (c *tenantRenewCache) Renew(ctx context.Context, cacheKey string, token string, renewTime int64) (bool, error) {
+	return c.renewer.Renew(ctx, c.prefix+cacheKey, token, renewTime)
+}
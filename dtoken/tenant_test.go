@@ -0,0 +1,256 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTenant_Isolation(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{})
+
+	acme, err := token.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := token.Tenant("globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acmeToken, err := acme.Generate(ctx, "bob", "acme-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globexToken, err := globex.Generate(ctx, "bob", "globex-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same userKey lives in separate namespaces | 相同用户标识位于不同命名空间
+	if data, err := acme.Validate(ctx, acmeToken); err != nil || data != "acme-data" {
+		t.Fatalf("acme validate: %v %v", data, err)
+	}
+	if data, err := globex.Validate(ctx, globexToken); err != nil || data != "globex-data" {
+		t.Fatalf("globex validate: %v %v", data, err)
+	}
+	if sessions, err := acme.ListSessions(ctx, "bob"); err != nil || len(sessions) != 1 || sessions[0].TenantId != "acme" {
+		t.Fatalf("expected one acme session, got %+v %v", sessions, err)
+	}
+
+	// Tokens are bound to their tenant | Token 绑定到所属租户
+	for _, c := range []struct {
+		name  string
+		token Token
+		value string
+	}{
+		{"acme in globex", globex, acmeToken},
+		{"acme in default", token, acmeToken},
+	} {
+		_, err := c.token.Validate(ctx, c.value)
+		if err == nil {
+			t.Fatalf("%s: expected error", c.name)
+		}
+		if c.token != Token(token) && gerror.Code(err) != CodeTenantInvalid {
+			t.Fatalf("%s: expected CodeTenantInvalid, got %v", c.name, gerror.Code(err))
+		}
+	}
+
+	// Destroying a tenant session leaves the other tenant alone | 销毁租户会话不影响其他租户
+	if err = acme.Destroy(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = globex.Validate(ctx, globexToken); err != nil {
+		t.Fatalf("globex session should survive: %v", err)
+	}
+
+	// Tenant instances cannot switch tenant | 租户实例不能切换租户
+	if _, err = acme.(TenantToken).Tenant("globex"); gerror.Code(err) != CodeTenantInvalid {
+		t.Fatalf("expected CodeTenantInvalid, got %v", err)
+	}
+	if _, err = token.Tenant("a|b"); gerror.Code(err) != CodeTenantInvalid {
+		t.Fatalf("expected CodeTenantInvalid for bad tenantId, got %v", err)
+	}
+}
+
+func TestTenant_Dynamic(t *testing.T) {
+	ctx := context.Background()
+	created, err := New(WithOptions(Options{CachePreKey: "Test:" + t.Name() + ":"}), WithBannerDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { created.Shutdown(ctx) })
+	token := created.(*GTokenV2)
+
+	// Tenants created on demand are built once | 按需创建的租户只构建一次
+	tenants := make([]Token, 8)
+	var wg sync.WaitGroup
+	for i := range tenants {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tenants[i], _ = token.Tenant("dyn")
+		}()
+	}
+	wg.Wait()
+	for _, tenant := range tenants {
+		if tenant == nil || tenant != tenants[0] {
+			t.Fatal("dynamic tenant should be reused")
+		}
+	}
+
+	// They follow UpdateOptions like configured tenants | 与已配置的租户一样跟随 UpdateOptions
+	options := token.GetOptions()
+	options.Timeout = 60 * 1000
+	if err = token.UpdateOptions(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+	if timeout := tenants[0].GetOptions().Timeout; timeout != 60*1000 {
+		t.Fatalf("dynamic tenant timeout not updated: %d", timeout)
+	}
+}
+
+func TestTenant_Overrides(t *testing.T) {
+	ctx := context.Background()
+	multiLogin := true
+	token, err := NewTokenByOptions(Options{
+		CachePreKey: "Test:" + t.Name() + ":",
+		Timeout:     10 * 60 * 1000,
+		MaxRefresh:  5 * 60 * 1000,
+		Tenants: map[string]TenantOptions{
+			"acme": {Timeout: 60 * 1000, MultiLogin: &multiLogin, EncryptKey: []byte("acme-acme-acme-a")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { token.Shutdown(ctx) })
+
+	acme, err := token.(TenantToken).Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := acme.GetOptions()
+	if options.Timeout != 60*1000 || !options.MultiLogin || options.MaxRefresh >= options.Timeout {
+		t.Fatalf("overrides not applied: timeout %d, maxRefresh %d, multiLogin %t", options.Timeout, options.MaxRefresh, options.MultiLogin)
+	}
+	if again, _ := token.(TenantToken).Tenant("acme"); again != acme {
+		t.Fatal("configured tenant should be reused")
+	}
+
+	acmeToken, err := acme.Generate(ctx, "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = acme.Validate(ctx, acmeToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.Validate(ctx, acmeToken); err == nil {
+		t.Fatal("token encrypted with tenant key should not validate in default tenant")
+	}
+	if got := token.GetOptions().Timeout; got != 10*60*1000 {
+		t.Fatalf("default tenant timeout changed to %d", got)
+	}
+
+	// Invalid tenant config is reported | 非法租户配置会被报告
+	err = Options{Tenants: map[string]TenantOptions{"a/b": {}, "short": {EncryptKey: []byte("1")}}, TenantLookup: []string{"cookie:x"}}.Validate()
+	if problems := gconv.Strings(err.(*OptionsError).Problems); len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", problems)
+	}
+}
+
+func TestTenantResolverByOptions(t *testing.T) {
+	resolver, err := NewTenantResolverByOptions(Options{TenantLookup: []string{"header:X-Tenant", "host:.example.com", "path:0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		host, path, header string
+		tenant             string
+	}{
+		{"api.other.com", "/orders", "globex", "globex"},
+		{"acme.example.com", "/orders", "", "acme"},
+		{"example.com", "/initech/orders", "", "initech"},
+		{"127.0.0.1:8000", "/", "", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Host = c.host
+		if c.header != "" {
+			req.Header.Set("X-Tenant", c.header)
+		}
+		tenant, err := resolver.Resolve(NewHTTPCarrier(req))
+		if err != nil || tenant != c.tenant {
+			t.Fatalf("%s%s: expected %q, got %q %v", c.host, c.path, c.tenant, tenant, err)
+		}
+	}
+
+	for _, lookup := range []string{"header", "path:x", "query:tenant"} {
+		if _, err = NewTenantResolverByOptions(Options{TenantLookup: []string{lookup}}); err == nil {
+			t.Fatalf("expected error for %q", lookup)
+		}
+	}
+	if resolver, err = NewTenantResolverByOptions(Options{}); resolver != nil || err != nil {
+		t.Fatalf("expected nil resolver, got %v %v", resolver, err)
+	}
+}
+
+func TestHTTPMiddleware_Tenant(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, Options{TenantLookup: []string{"header:X-Tenant"}})
+	middleware := NewHTTPMiddleware(token)
+
+	var gotTenant string
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant, _ = TenantFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	acme, err := token.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeToken, err := acme.Generate(ctx, "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		tenant string
+		status int
+	}{
+		{"acme", http.StatusOK},
+		{"globex", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+		{"a:b", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		gotTenant = ""
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("Authorization", "Bearer "+acmeToken)
+		if c.tenant != "" {
+			req.Header.Set("X-Tenant", c.tenant)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Fatalf("tenant %q: expected %d, got %d", c.tenant, c.status, rec.Code)
+		}
+	}
+	if _, ok := TenantFromContext(ctx); ok {
+		t.Fatal("unexpected tenant in background context")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeToken)
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if gotTenant != "acme" {
+		t.Fatalf("expected tenant in context, got %q", gotTenant)
+	}
+}
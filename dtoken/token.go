@@ -6,243 +6,405 @@ import (
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
-	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/os/gfsnotify"
+	"github.com/gogf/gf/v2/os/glog"
 	"github.com/gogf/gf/v2/util/gconv"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+	"sync/atomic"
 )
 
 // Token defines token interface | Token 接口定义
 type Token interface {
-	Generate(ctx context.Context, userKey string, data any) (token string, err error) // Generate token | 生成 Token
-	Validate(ctx context.Context, token string) (data any, err error)                 // Validate token | 验证 Token
-	Get(ctx context.Context, userKey string) (token string, data any, err error)      // Get token by userKey | 通过 userKey 获取 Token
-	ParseToken(ctx context.Context, token string) (userKey string, data any, err error)
-	Destroy(ctx context.Context, userKey string) error          // Destroy token | 销毁 Token
-	Renew(ctx context.Context, userKey string, userCache g.Map) // Asynchronously renew token | 异步续期 Token
-	Shutdown(ctx context.Context)                               // Gracefully shutdown renew pool | 优雅关闭续期协程池
-	GetOptions() Options                                        // Get config options | 获取配置参数
+	Generate(ctx context.Context, userKey string, data any) (token string, err error)                                     // Generate token | 生成 Token
+	GenerateWithDevice(ctx context.Context, userKey, deviceId string, data any) (token string, err error)                 // Generate token for a device | 为指定设备生成 Token
+	GenerateWithGrants(ctx context.Context, userKey, deviceId string, data any, grants *Grants) (token string, err error) // Generate token with roles and permissions | 生成携带角色与权限的 Token
+	SetGrants(ctx context.Context, userKey, deviceId string, grants *Grants) error                                        // Replace roles and permissions of a session | 替换会话的角色与权限
+	Validate(ctx context.Context, token string) (data any, err error)                                                     // Validate token | 验证 Token
+	ValidateSession(ctx context.Context, token string) (session *Session, err error)                                      // Validate token and return its session | 验证 Token 并返回会话
+	Get(ctx context.Context, userKey string) (token string, data any, err error)                                          // Get token by userKey | 通过 userKey 获取 Token
+	ParseToken(ctx context.Context, token string) (userKey string, data any, err error)                                   // Parse token | 解析 Token
+	GeneratePair(ctx context.Context, userKey, deviceId string, data any) (*TokenPair, error)                             // Generate access and refresh token | 生成访问令牌与刷新令牌
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)                                                 // Exchange refresh token for a new pair | 使用刷新令牌换取新令牌对
+	ListSessions(ctx context.Context, userKey string) ([]*Session, error)                                                 // List sessions of user | 列出用户的所有会话
+	DestroySession(ctx context.Context, userKey, deviceId string) error                                                   // Destroy a device session | 销毁指定设备的会话
+	Destroy(ctx context.Context, userKey string) error                                                                    // Destroy all sessions of user | 销毁用户的所有会话
+	RevokeToken(ctx context.Context, token string) error                                                                  // Revoke a single token | 吊销单个 Token
+	RevokeUser(ctx context.Context, userKey string, before int64) error                                                   // Revoke tokens of user issued before time | 吊销用户在指定时间前签发的 Token
+	RevokeAll(ctx context.Context, before int64) error                                                                    // Revoke all tokens issued before time | 吊销指定时间前签发的所有 Token
+	Subscribe(listener Listener, types ...EventType) (unsubscribe func())                                                 // Subscribe events synchronously | 同步订阅事件
+	SubscribeAsync(listener Listener, types ...EventType) (unsubscribe func())                                            // Subscribe events asynchronously | 异步订阅事件
+	Renew(ctx context.Context, userKey string, userCache g.Map)                                                           // Asynchronously renew token | 异步续期 Token
+	Shutdown(ctx context.Context)                                                                                         // Gracefully shutdown renew pool | 优雅关闭续期协程池
+	GetOptions() Options                                                                                                  // Get config options | 获取配置参数
 }
 
 // GTokenV2 main implementation | gToken 主体结构体
 type GTokenV2 struct {
-	Options          Options
+	Options          Options // Construction-time options, UpdateOptions leaves them untouched, read GetOptions instead | 构建时的配置，UpdateOptions 不会修改，请使用 GetOptions 读取
 	Codec            Codec
 	Cache            Cache
+	RefreshCache     Cache        // Storage of refresh token families (nil disables refresh tokens) | 刷新令牌族存储（为 nil 时禁用刷新令牌）
+	RevokeCache      Cache        // Storage of revocation list (nil disables revocation) | 吊销列表存储（为 nil 时禁用吊销）
+	KeyRing          *KeyRing     // Rotating encryption keys in aead mode (may be nil) | AEAD 模式下可轮换的加密密钥（可为 nil）
+	Events           *EventBus    // Lifecycle event listeners | 生命周期事件监听器
+	Telemetry        *Telemetry   // OpenTelemetry instruments (nil disables telemetry) | OpenTelemetry 埋点（为 nil 时不采集）
+	Logger           *glog.Logger // Logger of token warnings (nil uses g.Log()) | Token 告警日志（为 nil 时使用 g.Log()）
+	Clock            Clock        // Time source of token logic (nil uses SystemClock) | Token 逻辑的时间来源（为 nil 时使用 SystemClock）
+	TenantId         string       // Tenant of this instance ("" for the default namespace) | 实例所属租户（"" 表示默认命名空间）
 	RenewPoolManager *RenewPoolManager
+
+	poolMetrics    metric.Registration       // Renew pool gauges callback | 续期协程池指标回调
+	tenants        map[string]*GTokenV2      // Tenants configured by Options.Tenants | Options.Tenants 配置的租户
+	dynamicTenants sync.Map                  // Tenants created on demand by Tenant, keyed by tenantId | Tenant 按需创建的租户，以 tenantId 为 key
+	current        atomic.Pointer[Options]   // Options applied by UpdateOptions (nil uses Options) | UpdateOptions 应用的配置（为 nil 时使用 Options）
+	pathRules      atomic.Pointer[PathRules] // Compiled rules of the current options | 当前配置编译后的路径规则
+	reloadMu       sync.Mutex                // Serializes UpdateOptions, config watching and tenant creation | 串行化 UpdateOptions、配置监听与租户创建
+	sessionLocks   keyLocks                  // Serializes updates of session indexes | 串行化会话索引的更新
+	refreshLocks   keyLocks                  // Serializes rotation of refresh families | 串行化刷新令牌族的轮换
+	watcher        *gfsnotify.Callback       // Config file watcher (nil when not watching) | 配置文件监听器（未监听时为 nil）
 }
 
-// NewDefaultTokenByConfig creates a token from global config | 从全局配置创建 Token
-func NewDefaultTokenByConfig() Token {
-	var options Options
-	err := g.Cfg().MustGet(gctx.New(), GTokenCfgName).Struct(&options)
+// NewDefaultTokenByConfig creates a token from global config, panicking on error | 从全局配置创建 Token，出错时 panic
+func NewDefaultTokenByConfig(realm ...string) Token {
+	token, err := NewTokenByConfig(realm...)
 	if err != nil {
-		panic("gToken options init failed: " + err.Error())
+		panic(err)
 	}
-	return NewDefaultToken(options)
+	return token
 }
 
-// NewDefaultToken creates token instance with options | 使用配置创建 Token 实例
+// NewDefaultToken creates token instance with options, panicking on error | 使用配置创建 Token 实例，出错时 panic
 func NewDefaultToken(options Options) Token {
-	// Apply defaults | 应用默认配置
-	if options.CacheMode == 0 {
-		options.CacheMode = CacheModeCache
-	}
-	if options.CachePreKey == "" {
-		options.CachePreKey = DefaultCacheKey
-	}
-	if options.Timeout <= 0 {
-		options.Timeout = DefaultTimeout
-	}
-	if options.MaxRefresh <= 0 {
-		options.MaxRefresh = options.Timeout / 2
-	}
-	if len(options.EncryptKey) == 0 {
-		options.EncryptKey = []byte(DefaultEncryptKey)
-	}
-	if options.TokenDelimiter == "" {
-		options.TokenDelimiter = DefaultTokenDelimiter
-	}
-	if options.PoolMinSize <= 0 {
-		options.PoolMinSize = DefaultMinSize
-	}
-	if options.PoolMaxSize <= 0 {
-		options.PoolMaxSize = DefaultMaxSize
+	token, err := NewTokenByOptions(options)
+	if err != nil {
+		panic(err)
 	}
-	if options.PoolScaleUpRate <= 0 {
-		options.PoolScaleUpRate = DefaultScaleUpRate
+	return token
+}
+
+// NewTokenByConfig creates a token from global config, realm selects the "gToken.<realm>" node | 从全局配置创建 Token，realm 指定 "gToken.<realm>" 节点
+// Use NewRegistryByConfig to create every realm with isolated caches | 使用 NewRegistryByConfig 创建缓存相互隔离的全部域
+// With HotReload the token watches its config node, see WatchConfig | 开启 HotReload 时 Token 会监听其配置节点，参见 WatchConfig
+func NewTokenByConfig(realm ...string) (Token, error) {
+	options, err := optionsByConfig(realm...)
+	if err != nil {
+		return nil, err
 	}
-	if options.PoolScaleDownRate <= 0 {
-		options.PoolScaleDownRate = DefaultScaleDownRate
+	token, err := NewTokenByOptions(options)
+	if err != nil || !options.HotReload {
+		return token, err
 	}
-	if options.RenewInterval < 0 {
-		options.RenewInterval = 0
+	if err = token.(*GTokenV2).WatchConfig(realm...); err != nil {
+		token.Shutdown(gctx.New())
+		return nil, err
 	}
+	return token, nil
+}
 
-	// Validate configuration | 校验配置合法性
-	// 1. MaxRefresh should be less than Timeout
-	if options.MaxRefresh >= options.Timeout {
-		g.Log().Warning(gctx.New(), "invalid config: MaxRefresh >= Timeout, reset to Timeout/2 | 已自动修正为 Timeout 的一半")
-		options.MaxRefresh = options.Timeout / 2
-	}
+// NewTokenByOptions creates token instance with options | 使用配置创建 Token 实例
+// Invalid settings are auto-corrected with a warning unless Strict is set, other problems are returned as *OptionsError
+// 非法配置默认自动修正并告警（Strict 模式下不修正），其余问题以 *OptionsError 返回
+func NewTokenByOptions(options Options) (Token, error) {
+	return New(WithOptions(options))
+}
 
-	// 2. RenewInterval should be less than Timeout
-	if options.RenewInterval >= options.Timeout {
-		g.Log().Warning(gctx.New(), "invalid config: RenewInterval >= Timeout, reset to 0 | 已自动修正为 0 (无间隔限制)")
-		options.RenewInterval = 0
+// New creates token instance with functional options | 使用函数式选项创建 Token 实例
+// Injected components replace the ones built from Options, defaults and validation still apply
+// 注入的组件替代按 Options 构建的组件，默认配置与校验依然生效
+func New(opts ...Option) (Token, error) {
+	b := &builder{logger: g.Log()}
+	for _, opt := range opts {
+		opt(b)
 	}
 
-	// 3. PoolMaxSize must not be smaller than PoolMinSize
-	if options.PoolMaxSize < options.PoolMinSize {
-		g.Log().Warningf(gctx.New(), "invalid config: PoolMaxSize < PoolMinSize, reset PoolMaxSize=%d | 已自动修正 PoolMaxSize 为 %d",
-			options.PoolMinSize, options.PoolMinSize)
-		options.PoolMaxSize = options.PoolMinSize
+	// Apply defaults and validate configuration | 应用默认配置并校验配置合法性
+	options := b.options
+	if err := options.normalize(options.Strict, b.logger); err != nil {
+		return nil, err
 	}
 
-	// 4. ScaleDownRate must be smaller than ScaleUpRate
-	if options.PoolScaleDownRate >= options.PoolScaleUpRate {
-		g.Log().Warning(gctx.New(), "invalid config: PoolScaleDownRate >= PoolScaleUpRate, reset to default values | 已自动修正为默认值")
-		options.PoolScaleUpRate = DefaultScaleUpRate
-		options.PoolScaleDownRate = DefaultScaleDownRate
+	// Construct main token instance | 构建主 Token 实例
+	gfToken := &GTokenV2{
+		Options:          options,
+		Codec:            b.codec,
+		Cache:            b.cache,
+		RefreshCache:     b.refreshCache,
+		RevokeCache:      b.revokeCache,
+		Events:           NewEventBus(),
+		Logger:           b.logger,
+		Clock:            b.clock,
+		RenewPoolManager: b.renewPool,
+	}
+	if gfToken.Codec == nil {
+		codec, keyRing, err := newCodecByOptions(options)
+		if err != nil {
+			return nil, gerror.WrapCode(gcode.CodeInvalidConfiguration, err)
+		}
+		gfToken.Codec, gfToken.KeyRing = codec, keyRing
 	}
 
-	// 5. EncryptKey length check (must panic if invalid)
-	if len(options.EncryptKey) != 16 && len(options.EncryptKey) != 24 && len(options.EncryptKey) != 32 {
-		panic("invalid config: EncryptKey length must be 16, 24, or 32 bytes (AES key size) | EncryptKey 长度必须为 16、24 或 32 字节")
+	// Initialize renew pool and caches, releasing them on failure | 初始化续期协程池与缓存，失败时释放资源
+	if err := gfToken.init(); err != nil {
+		gfToken.Shutdown(gctx.New())
+		return nil, err
 	}
 
-	// 6. CacheMode check (must panic if invalid)
-	if options.CacheMode != CacheModeCache && options.CacheMode != CacheModeRedis && options.CacheMode != CacheModeFile {
-		panic("invalid config: CacheMode must be 1 (gcache), 2 (gredis), or 3 (gfile) | CacheMode 必须为 1(gcache)、2(gredis) 或 3(gfile)")
+	if !b.bannerDisabled {
+		PrintWithOptions(&gfToken.Options)
 	}
+	return gfToken, nil
+}
+
+// init builds the renew pool, caches and telemetry not injected into a new token | 构建新 Token 中未注入的续期协程池、缓存与遥测
+func (m *GTokenV2) init() (err error) {
+	options := m.Options
 
 	// Initialize renew pool | 初始化续期协程池
-	renewPoolManager, err := NewRenewPoolBuilder().
-		MinSize(options.PoolMinSize).
-		MaxSize(options.PoolMaxSize).
-		ScaleUpRate(options.PoolScaleUpRate).
-		ScaleDownRate(options.PoolScaleDownRate).
-		Build()
-	if err != nil {
-		panic(err)
+	if m.RenewPoolManager == nil {
+		if m.RenewPoolManager, err = NewRenewPoolBuilder().
+			MinSize(options.PoolMinSize).
+			MaxSize(options.PoolMaxSize).
+			ScaleUpRate(options.PoolScaleUpRate).
+			ScaleDownRate(options.PoolScaleDownRate).
+			Build(); err != nil {
+			return err
+		}
 	}
 
-	// Construct main token instance | 构建主 Token 实例
-	gfToken := &GTokenV2{
-		Options:          options,
-		Codec:            NewDefaultCodec(options.TokenDelimiter, options.EncryptKey),
-		Cache:            NewDefaultCache(options.CacheMode, options.CachePreKey, options.Timeout),
-		RenewPoolManager: renewPoolManager,
+	// Initialize caches | 初始化缓存
+	if m.Cache == nil {
+		if m.Cache, err = NewCacheByOptions(options, options.CachePreKey, options.Timeout); err != nil {
+			return err
+		}
+	}
+	if m.RefreshCache == nil {
+		if m.RefreshCache, err = NewCacheByOptions(options, options.CachePreKey+RefreshPreKey, options.RefreshTimeout); err != nil {
+			return err
+		}
+	}
+	// Revocation entries outlive any token they may match | 吊销记录的保留时间不短于任何可能匹配的 Token
+	if m.RevokeCache == nil {
+		if m.RevokeCache, err = NewCacheByOptions(options, options.CachePreKey+RevokePreKey, options.RefreshTimeout); err != nil {
+			return err
+		}
 	}
 
-	PrintWithOptions(&gfToken.Options)
-	return gfToken
-}
+	// Expire in-memory sessions and JWTs by the token clock | 内存会话与 JWT 按 Token 时钟过期
+	m.useClock()
 
-// Generate creates a new token for user | 生成 Token
-func (m *GTokenV2) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
-	if userKey == "" {
-		return "", gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	// Initialize configured tenants | 初始化已配置的租户
+	if len(options.Tenants) > 0 {
+		m.tenants = make(map[string]*GTokenV2, len(options.Tenants))
+		for tenantId := range options.Tenants {
+			if m.tenants[tenantId], err = m.newTenant(tenantId); err != nil {
+				return err
+			}
+		}
 	}
 
-	// Support multi-login (reuse existing token) | 支持多端重复登录（重用旧 Token）
-	if m.Options.MultiLogin {
-		token, _, err = m.Get(ctx, userKey)
-		if err == nil && token != "" {
-			return token, nil
+	// Enable telemetry with global providers | 使用全局 Provider 启用遥测
+	if options.Telemetry {
+		telemetry, err := NewTelemetry(nil, nil)
+		if err != nil {
+			return err
+		}
+		return m.EnableTelemetry(telemetry)
+	}
+	return nil
+}
+
+// useClock lets in-memory caches and JWT claims follow the token clock | 使内存缓存与 JWT 声明遵循 Token 时钟
+func (m *GTokenV2) useClock() {
+	if m.Clock == nil {
+		return
+	}
+	for _, cache := range []Cache{m.Cache, m.RefreshCache, m.RevokeCache} {
+		if defaultCache, ok := cache.(*DefaultCache); ok && defaultCache.Clock == nil {
+			defaultCache.Clock = m.Clock
 		}
 	}
+	codec := m.Codec
+	if tenant, ok := codec.(*tenantCodec); ok {
+		codec = tenant.Codec
+	}
+	if jwtCodec, ok := codec.(*JWTCodec); ok && jwtCodec.Clock == nil {
+		jwtCodec.Clock = m.Clock
+	}
+}
+
+// Generate creates a new token for user on the default device | 在默认设备上生成 Token
+func (m *GTokenV2) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
+	return m.GenerateWithDevice(ctx, userKey, "", data)
+}
 
-	// Encode userKey into token | 编码用户唯一标识生成 Token
-	token, err = m.Codec.Encode(ctx, userKey)
+// Validate checks token validity and optionally triggers renewal | 验证 Token 并触发续期
+func (m *GTokenV2) Validate(ctx context.Context, token string) (data any, err error) {
+	session, err := m.ValidateSession(ctx, token)
 	if err != nil {
-		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+		return nil, err
 	}
+	return session.Data, nil
+}
 
-	// Cache structure for user token | 构建用户缓存结构
-	userCache := g.Map{
-		KeyUserKey:       userKey,                      // 用户唯一标识
-		KeyToken:         token,                        // Token 值
-		KeyData:          data,                         // 附加数据
-		KeyRefreshNum:    0,                            // 已续期次数
-		KeyCreateTime:    gtime.Now().TimestampMilli(), // 创建时间
-		KeyLastRenewTime: 0,                            // 续期时间
+// ValidateSession validates token like Validate and returns the whole session | 与 Validate 相同地校验 Token，并返回完整会话
+func (m *GTokenV2) ValidateSession(ctx context.Context, token string) (session *Session, err error) {
+	ctx, span := m.Telemetry.start(ctx, SpanValidate)
+	ctx = withTokenIdSlot(ctx) // Reuse the jti verified by Decrypt | 复用 Decrypt 校验过的 jti
+	defer func() {
+		m.Telemetry.validated(ctx, err)
+		m.Telemetry.end(span, err)
+	}()
+
+	cacheKey, userCache, err := m.validate(ctx, token)
+	if err != nil {
+		event := &Event{Type: EventValidationFailed, Err: err}
+		if userCache != nil {
+			event.UserKey, event.DeviceId = gconv.String(userCache[KeyUserKey]), gconv.String(userCache[KeyDeviceId])
+		}
+		m.emit(ctx, token, event)
+		return nil, err
 	}
+	m.emit(ctx, token, newSessionEvent(EventValidated, userCache))
 
-	// Save token data to cache | 将用户 Token 信息写入缓存
-	if err = m.Cache.Set(ctx, userKey, userCache); err != nil {
-		return "", gerror.WrapCode(gcode.CodeInternalError, err)
+	// Check if renewal is needed | 判断是否需要续期
+	if m.shouldRenew(userCache) {
+		m.Renew(gctx.NeverDone(ctx), cacheKey, userCache)
 	}
-	return token, nil
+
+	return newSessionFromCache(userCache, m.options().Timeout), nil
 }
 
-// Validate checks token validity and optionally triggers renewal | 验证 Token 并触发续期
-func (m *GTokenV2) Validate(ctx context.Context, token string) (data any, err error) {
+// validate verifies token and returns its session, userCache may be set on failure | 校验 Token 并返回会话，失败时 userCache 可能非空
+func (m *GTokenV2) validate(ctx context.Context, token string) (cacheKey string, userCache g.Map, err error) {
 	if token == "" {
-		return nil, gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
+		return "", nil, gerror.NewCode(CodeTokenMissing, MsgErrTokenEmpty)
 	}
 
-	// Decode token to get user key | 解码 Token 获取用户标识
-	userKey, err := m.Codec.Decrypt(ctx, token)
+	// Decode token to get session key | 解码 Token 获取会话 key
+	cacheKey, err = m.Codec.Decrypt(ctx, token)
 	if err != nil {
-		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return "", nil, decryptError(err)
 	}
 
-	// Retrieve cache info by user key | 通过用户标识获取缓存信息
-	userCache, err := m.Cache.Get(ctx, userKey)
+	// Retrieve cache info by session key | 通过会话 key 获取缓存信息
+	userCache, err = m.Cache.Get(ctx, cacheKey)
 	if err != nil {
-		return nil, err
+		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
 	if userCache == nil {
-		return nil, gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+		return "", nil, gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
 	}
 
 	// Verify token consistency | 校验 Token 一致性
 	if token != userCache[KeyToken] {
-		return nil, gerror.NewCode(gcode.CodeInvalidParameter, MsgErrValidate)
+		return "", userCache, gerror.NewCode(CodeTokenKicked, MsgErrValidate)
 	}
 
-	// Check if renewal is needed | 判断是否需要续期
-	if m.shouldRenew(userCache) {
-		m.Renew(gctx.NeverDone(ctx), userKey, userCache)
+	// Check revocation list | 检查吊销列表
+	revoked, err := m.IsRevoked(ctx, token, gconv.String(userCache[KeyUserKey]), gconv.Int64(userCache[KeyCreateTime]))
+	if err != nil {
+		return "", userCache, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
-
-	return userCache[KeyData], nil
+	if revoked {
+		return "", userCache, gerror.NewCode(CodeTokenRevoked, MsgErrRevoked)
+	}
+	return cacheKey, userCache, nil
 }
 
-// Renew asynchronously renews a token | 异步续期 Token
+// Renew asynchronously renews a token, userKey is the session cache key | 异步续期 Token，userKey 为会话缓存 key
 func (m *GTokenV2) Renew(ctx context.Context, userKey string, userCache g.Map) {
-	_ = m.RenewPoolManager.Submit(func() {
-		// 再次确认 Token 是否依然有效
-		currentCache, err := m.Cache.Get(ctx, userKey)
-		if err != nil || currentCache == nil {
-			// 用户已登出或被清除
-			return
+	token := gconv.String(userCache[KeyToken])
+	err := m.RenewPoolManager.Submit(func() {
+		ctx, span := m.Telemetry.start(ctx, SpanRenew)
+		err := m.renew(ctx, userKey, token, userCache)
+		if err != nil {
+			m.renewFailed(ctx, token, userCache, err)
 		}
+		m.Telemetry.end(span, err)
+	})
+	if err != nil {
+		m.renewFailed(ctx, token, userCache, err)
+	}
+}
 
-		// 校验 token 一致性，防止被其他 Token 替换
-		if currentCache[KeyToken] != userCache[KeyToken] {
-			return
+// renew extends the session if it still holds token, a vanished or replaced session is not an error | 会话仍持有该 Token 时续期，会话已消失或被替换不视为错误
+func (m *GTokenV2) renew(ctx context.Context, userKey, token string, userCache g.Map) error {
+	// Atomic renew when supported by cache | 缓存支持时使用原子续期
+	if renewer, ok := m.Cache.(Renewer); ok {
+		renewed, err := renewer.Renew(ctx, userKey, token, m.now())
+		if err != nil || !renewed {
+			return err
 		}
+		m.renewed(ctx, token, userCache)
+		return nil
+	}
 
-		newMap := gconv.Map(userCache, gconv.MapOption{Deep: true})
-		if newMap == nil {
-			return
-		}
+	// 再次确认 Token 是否依然有效
+	currentCache, err := m.Cache.Get(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	if currentCache == nil {
+		// 用户已登出或被清除
+		return nil
+	}
 
-		newMap[KeyLastRenewTime] = gtime.Now().TimestampMilli()
-		newMap[KeyRefreshNum] = gconv.Int(newMap[KeyRefreshNum]) + 1
-		_ = m.Cache.Set(ctx, userKey, newMap)
-	})
+	// 校验 token 一致性，防止被其他 Token 替换
+	if currentCache[KeyToken] != userCache[KeyToken] {
+		return nil
+	}
+
+	newMap := gconv.Map(userCache, gconv.MapOption{Deep: true})
+	if newMap == nil {
+		return nil
+	}
+
+	newMap[KeyLastRenewTime] = m.now()
+	newMap[KeyRefreshNum] = gconv.Int(newMap[KeyRefreshNum]) + 1
+	if err = m.Cache.Set(ctx, userKey, newMap); err != nil {
+		return err
+	}
+	m.renewed(ctx, token, newMap)
+	return nil
+}
+
+// renewed keeps the session index alive and publishes the renewal | 延长会话索引有效期并发布续期事件
+func (m *GTokenV2) renewed(ctx context.Context, token string, userCache g.Map) {
+	m.touchRenewedSession(ctx, userCache)
+	m.Telemetry.renewed(ctx, nil)
+	m.emit(ctx, token, newSessionEvent(EventRenewed, userCache))
+}
+
+// renewFailed logs and publishes a renewal failure | 记录并发布续期失败事件
+func (m *GTokenV2) renewFailed(ctx context.Context, token string, userCache g.Map, err error) {
+	m.logger().Warningf(ctx, "Token renew failed: %v", err)
+	m.Telemetry.renewed(ctx, err)
+	event := newSessionEvent(EventRenewFailed, userCache)
+	event.Err = err
+	m.emit(ctx, token, event)
+}
+
+// touchRenewedSession keeps session index alive together with the session | 同步延长会话索引有效期
+func (m *GTokenV2) touchRenewedSession(ctx context.Context, userCache g.Map) {
+	if userKey := gconv.String(userCache[KeyUserKey]); userKey != "" {
+		_ = m.touchSessionIndex(ctx, userKey, gconv.String(userCache[KeyDeviceId]))
+	}
 }
 
 // shouldRenew checks whether the token should be renewed | 判断是否需要续期
 func (m *GTokenV2) shouldRenew(userCache g.Map) bool {
-	now := gtime.Now().TimestampMilli()                       // current time | 当前时间
+	now := m.now()                                            // current time | 当前时间
 	createTime := gconv.Int64(userCache[KeyCreateTime])       // token creation time | Token 创建时间
 	lastRenewTime := gconv.Int64(userCache[KeyLastRenewTime]) // last renewal time (0 if first) | 上次续期时间（第一次为 0）
 	refreshNum := gconv.Int(userCache[KeyRefreshNum])         // number of renewals | 已续期次数
+	return canRenew(m.options(), now, createTime, lastRenewTime, refreshNum)
+}
 
+// canRenew applies the renew rules of options at now (ms), shared by renewal and cookie refresh | 在 now（毫秒）时应用续期规则，续期与 Cookie 刷新共用
+func canRenew(options *Options, now, createTime, lastRenewTime int64, refreshNum int) bool {
 	// 1. skip renew logic if MaxRefresh is disabled | 若未启用续期机制（MaxRefresh=0），则永不续期
-	if m.Options.MaxRefresh == 0 {
+	if options.MaxRefresh <= 0 {
 		return false
 	}
 
@@ -254,20 +416,20 @@ func (m *GTokenV2) shouldRenew(userCache g.Map) bool {
 
 	// calculate elapsed and remaining time | 计算已过时间与剩余寿命
 	elapsed := now - refTime
-	remaining := m.Options.Timeout - elapsed
+	remaining := options.Timeout - elapsed
 
 	// 2. not in the refresh window | 若未进入续期判断窗口（剩余寿命大于 MaxRefresh），则不续期
-	if remaining > m.Options.MaxRefresh {
+	if remaining > options.MaxRefresh {
 		return false
 	}
 
 	// 3. check renew interval limit (skip for first renewal) | 判断续期间隔（首次续期不受限制）
-	if refreshNum > 0 && m.Options.RenewInterval > 0 && elapsed < m.Options.RenewInterval {
+	if refreshNum > 0 && options.RenewInterval > 0 && elapsed < options.RenewInterval {
 		return false
 	}
 
 	// 4. check max renew times | 判断最大续期次数（0 表示无限制）
-	if m.Options.MaxRefreshTimes > 0 && refreshNum >= m.Options.MaxRefreshTimes {
+	if options.MaxRefreshTimes > 0 && refreshNum >= options.MaxRefreshTimes {
 		return false
 	}
 
@@ -277,8 +439,8 @@ func (m *GTokenV2) shouldRenew(userCache g.Map) bool {
 
 // Get retrieves token and data by userKey | 通过 userKey 获取 Token
 func (m *GTokenV2) Get(ctx context.Context, userKey string) (token string, data any, err error) {
-	if userKey == "" {
-		return "", nil, gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	if err = checkSessionKey(userKey, ""); err != nil {
+		return "", nil, err
 	}
 
 	// Retrieve token and data from cache | 从缓存中获取 Token 与附加数据
@@ -287,7 +449,7 @@ func (m *GTokenV2) Get(ctx context.Context, userKey string) (token string, data
 		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
 	if userCache == nil {
-		return "", nil, gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+		return "", nil, gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
 	}
 	return gconv.String(userCache[KeyToken]), userCache[KeyData], nil
 }
@@ -295,47 +457,108 @@ func (m *GTokenV2) Get(ctx context.Context, userKey string) (token string, data
 // ParseToken parses token to retrieve userKey and data | 解析 Token 获取 userKey 和数据
 func (m *GTokenV2) ParseToken(ctx context.Context, token string) (userKey string, data any, err error) {
 	if token == "" {
-		return "", nil, gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+		return "", nil, gerror.NewCode(CodeTokenMissing, MsgErrTokenEmpty)
 	}
 
-	// Decode token | 解密 Token
-	userKey, err = m.Codec.Decrypt(ctx, token)
+	// Decode token to get session key | 解密 Token 获取会话 key
+	cacheKey, err := m.Codec.Decrypt(ctx, token)
 	if err != nil {
-		return "", nil, gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return "", nil, decryptError(err)
 	}
 
 	// Fetch from cache | 从缓存获取数据
-	userCache, err := m.Cache.Get(ctx, userKey)
+	userCache, err := m.Cache.Get(ctx, cacheKey)
 	if err != nil {
 		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
 	if userCache == nil {
-		return "", nil, gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+		return "", nil, gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
+	}
+	userKey = gconv.String(userCache[KeyUserKey])
+	if userKey == "" {
+		userKey = cacheKey
 	}
 	return userKey, userCache[KeyData], nil
 }
 
-// Destroy removes user token from cache | 销毁 Token
-func (m *GTokenV2) Destroy(ctx context.Context, userKey string) error {
-	if userKey == "" {
-		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+// Destroy removes all sessions of user from cache | 销毁用户的所有会话
+func (m *GTokenV2) Destroy(ctx context.Context, userKey string) (err error) {
+	ctx, span := m.Telemetry.start(ctx, SpanDestroy)
+	defer func() { m.Telemetry.end(span, err) }()
+
+	if err = checkSessionKey(userKey, ""); err != nil {
+		return err
 	}
-	// Remove cache entry | 从缓存移除对应 Token 信息
-	if err := m.Cache.Remove(ctx, userKey); err != nil {
+	unlock := m.sessionLocks.lock(sessionIndexKey(userKey))
+	defer unlock()
+	index, err := m.Cache.Get(ctx, sessionIndexKey(userKey))
+	if err != nil {
 		return gerror.WrapCode(gcode.CodeInternalError, err)
 	}
+
+	// Remove every device session and the default one | 移除所有设备会话及默认会话
+	deviceIds := g.SliceStr{""}
+	for deviceId := range index {
+		if deviceId != "" {
+			deviceIds = append(deviceIds, deviceId)
+		}
+	}
+	for _, deviceId := range deviceIds {
+		if err = m.Cache.Remove(ctx, sessionKey(userKey, deviceId)); err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+		if err = m.removeRefreshToken(ctx, sessionKey(userKey, deviceId)); err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	if err = m.Cache.Remove(ctx, sessionIndexKey(userKey)); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	m.Telemetry.sessionsChanged(ctx, -int64(len(index)))
+	m.emit(ctx, "", &Event{Type: EventDestroyed, UserKey: userKey})
 	return nil
 }
 
 // Shutdown gracefully stops renew pool | 优雅关闭续期协程池
+// Tenant instances share resources of the default instance, shut that one down instead | 租户实例共享默认实例的资源，应关闭默认实例
 func (m *GTokenV2) Shutdown(ctx context.Context) {
+	if m.TenantId != "" {
+		return
+	}
+	m.stopWatch()
+	if m.poolMetrics != nil {
+		_ = m.poolMetrics.Unregister()
+		m.poolMetrics = nil
+	}
 	if m.RenewPoolManager != nil {
-		g.Log().Info(ctx, "Token RenewPoolManager closed")
+		m.logger().Info(ctx, "Token RenewPoolManager closed")
 		m.RenewPoolManager.Stop()
 	}
+	// Release caches holding resources such as file locks | 释放持有文件锁等资源的缓存
+	m.closeCaches()
+	m.rangeTenants(func(_ string, tenant *GTokenV2) {
+		tenant.closeCaches()
+	})
+}
+
+// logger returns the token logger | 返回 Token 日志器
+func (m *GTokenV2) logger() *glog.Logger {
+	if m.Logger == nil {
+		return g.Log()
+	}
+	return m.Logger
 }
 
 // GetOptions 获取Options配置 | 返回当前配置项
+// Options keeps the construction-time config, options applied by UpdateOptions are only visible here | Options 字段保留构建时的配置，UpdateOptions 应用的配置仅在此处可见
 func (m *GTokenV2) GetOptions() Options {
-	return m.Options
+	return *m.options()
+}
+
+// options returns the current options, see UpdateOptions | 返回当前配置，参见 UpdateOptions
+func (m *GTokenV2) options() *Options {
+	if options := m.current.Load(); options != nil {
+		return options
+	}
+	return &m.Options
 }
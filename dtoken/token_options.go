@@ -2,8 +2,16 @@ package dtoken
 
 import (
 	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/text/gstr"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 // Version version number | 版本号
@@ -26,21 +34,351 @@ const (
 
 // Options defines all configuration for gToken | gToken 全局配置参数
 type Options struct {
-	CacheMode        int8       // Cache mode: 1-gcache 2-gredis 3-gfile | 缓存模式：1 gcache 2 gredis 3 gfile
+	CacheMode        int8       // Cache mode: 1-gcache 2-gredis 3-gfile 4-redis hash | 缓存模式：1 gcache 2 gredis 3 gfile 4 redis 哈希
+	RedisGroup       string     // Redis group name for redis modes ("" = default) | Redis 模式使用的分组名（空为默认分组）
+	CacheFileDir     string     // Storage directory for file mode ("" = temp dir) | 文件模式的存储目录（空为临时目录）
+	CacheFileSync    int8       // File mode fsync policy: 1-always 2-every second 3-none | 文件模式刷盘策略：1 每次 2 每秒 3 不主动
 	CachePreKey      string     // Cache key prefix | 缓存 key 前缀
 	Timeout          int64      // Token expiration time (ms) | Token 超时时间（毫秒）
 	MaxRefresh       int64      // Max auto-refresh interval (ms) | 最大自动刷新间隔（毫秒）
 	MaxRefreshTimes  int        // Maximum number of refresh times (0 = unlimited) | 最大刷新次数（0 表示不限制）
+	RefreshTimeout   int64      // Refresh token lifetime (ms) | 刷新令牌有效期（毫秒）
 	TokenDelimiter   string     // Token delimiter | Token 分隔符
 	EncryptKey       []byte     // Token encryption key | Token 加密密钥
+	CodecMode        int8       // Codec mode: 1-default 2-jwt 3-aead | 编解码模式：1 默认 2 JWT 3 AEAD
+	AcceptLegacy     bool       // Accept default-codec tokens in aead mode during migration | AEAD 模式下迁移期间兼容默认编解码的 Token
+	EncryptKeys      []RingKey  // Key ring for aead mode, exactly one active | AEAD 模式的密钥环，必须有且仅有一个激活密钥
+	JwtAlg           string     // JWT algorithm: HS256 RS256 ES256 EdDSA | JWT 签名算法
+	JwtSecret        []byte     // JWT HMAC secret (HS256) | JWT HMAC 密钥（HS256）
+	JwtPrivateKey    string     // JWT PEM private key (RS256/ES256/EdDSA) | JWT PEM 私钥
+	JwtPublicKey     string     // JWT PEM public key, verify only when no private key | JWT PEM 公钥，无私钥时仅用于验签
+	JwtIssuer        string     // JWT "iss" claim | JWT 签发者
+	JwtExpire        int64      // JWT "exp" lifetime (ms, default Timeout, or Timeout*(MaxRefreshTimes+1) with limited renewals) | JWT 有效期（毫秒，默认 Timeout，限制续期次数时为 Timeout*(MaxRefreshTimes+1)）
 	MultiLogin       bool       // Allow multi-login | 是否允许多端登录
-	AuthExcludePaths g.SliceStr // Paths excluded from authentication | 免认证路径列表
+	AuthExcludePaths g.SliceStr // Path rules excluded from authentication, e.g. "GET /articles/*" | 免认证路径规则列表，如 "GET /articles/*"
+	AuthIncludePaths g.SliceStr // Path rules requiring authentication inside excluded ones | 在免认证规则内仍需认证的路径规则
+	AuthExcludeRpcs  g.SliceStr // gRPC full method names excluded from authentication | 免认证的 gRPC 完整方法名列表
+	TokenLookup      g.SliceStr // Ordered token sources: "header:<name>[:<scheme>]", "cookie:<name>", "param:<name>" | 按顺序查找 Token 的来源
+
+	CookieName     string // Session cookie name, enables cookie mode ("" = disabled) | 会话 Cookie 名称，设置后启用 Cookie 模式（空为不启用）
+	CookieDomain   string // Session cookie domain | 会话 Cookie 域名
+	CookiePath     string // Session cookie path (default "/") | 会话 Cookie 路径（默认 "/"）
+	CookieSecure   bool   // Send cookies over HTTPS only | 仅通过 HTTPS 发送 Cookie
+	CookieSameSite string // SameSite policy: lax strict none (default lax) | SameSite 策略：lax strict none（默认 lax）
+	CsrfCookieName string // Readable cookie holding the CSRF token (default "csrf_token") | 存放 CSRF Token 的可读 Cookie（默认 "csrf_token"）
+	CsrfHeaderName string // Header echoing the CSRF token on unsafe methods (default "X-CSRF-Token") | 非安全方法回传 CSRF Token 的请求头（默认 "X-CSRF-Token"）
+
+	AuthRules       []AuthRule          // Route rules requiring roles or permissions | 需要角色或权限的路由规则
+	RolePermissions map[string][]string // Permissions granted by each role | 各角色拥有的权限
+
+	MaxSessions        int  // Maximum concurrent sessions per user (0 = unlimited) | 每个用户的最大并发会话数（0 表示不限制）
+	SessionEvictPolicy int8 // Policy when MaxSessions is reached: 1-evict oldest 2-reject | 达到会话上限时的策略：1 踢出最早 2 拒绝登录
+
+	Tenants      map[string]TenantOptions // Per-tenant overrides, unlisted tenants use these options | 各租户的覆盖配置，未列出的租户使用当前配置
+	TenantLookup g.SliceStr               // Ordered tenant sources of middleware: "host[:<suffix>]", "header:<name>", "path:<index>" | 中间件按顺序查找租户的来源
 
 	PoolMinSize       int     // Minimum pool size | 最小协程数
 	PoolMaxSize       int     // Maximum pool size | 最大协程数
 	PoolScaleUpRate   float64 // Scale-up threshold (expand when usage exceeds this ratio) | 扩容阈值，当使用率超过此比例时扩容
 	PoolScaleDownRate float64 // Scale-down threshold (shrink when usage below this ratio) | 缩容阈值，当使用率低于此比例时缩容
 	RenewInterval     int64   // Minimum renewal interval (ms) | 最小续期间隔（毫秒）
+
+	Telemetry bool // Enable OpenTelemetry tracing and metrics with global providers | 使用全局 Provider 启用 OpenTelemetry 链路追踪与指标
+	Strict    bool // Reject invalid settings instead of auto-correcting them | 拒绝非法配置而不是自动修正
+	HotReload bool // Watch the config file and apply safe changes at runtime, see UpdateOptions | 监听配置文件并在运行时应用可热更新的配置，参见 UpdateOptions
+}
+
+// OptionsError lists every invalid setting found in Options | 列出 Options 中的所有非法配置
+type OptionsError struct {
+	Problems []string // One entry per invalid setting | 每个非法配置一条
+}
+
+// Error implements error | 实现 error 接口
+func (e *OptionsError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Code implements gerror.ICode | 实现 gerror.ICode 接口
+func (e *OptionsError) Code() gcode.Code {
+	return gcode.CodeInvalidConfiguration
+}
+
+// Validate reports every invalid setting after applying defaults, options are left unchanged | 应用默认值后报告所有非法配置，不修改原配置
+// Settings auto-corrected by non-strict construction are reported too | 非严格模式下会被自动修正的配置同样会被报告
+func (opt Options) Validate() error {
+	opt.TokenLookup = append(g.SliceStr{}, opt.TokenLookup...)
+	return opt.normalize(true, g.Log())
+}
+
+// setDefaults fills unset options with default values | 为未设置的配置项填充默认值
+func (opt *Options) setDefaults() {
+	if opt.CacheMode == 0 {
+		opt.CacheMode = CacheModeCache
+	}
+	if opt.CodecMode == 0 {
+		opt.CodecMode = CodecModeDefault
+	}
+	if opt.CachePreKey == "" {
+		opt.CachePreKey = DefaultCacheKey
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = DefaultTimeout
+	}
+	if opt.MaxRefresh <= 0 {
+		opt.MaxRefresh = opt.Timeout / 2
+	}
+	if len(opt.EncryptKey) == 0 {
+		opt.EncryptKey = []byte(DefaultEncryptKey)
+	}
+	if opt.TokenDelimiter == "" {
+		opt.TokenDelimiter = DefaultTokenDelimiter
+	}
+	if opt.PoolMinSize <= 0 {
+		opt.PoolMinSize = DefaultMinSize
+	}
+	if opt.PoolMaxSize <= 0 {
+		opt.PoolMaxSize = DefaultMaxSize
+	}
+	if opt.PoolScaleUpRate <= 0 {
+		opt.PoolScaleUpRate = DefaultScaleUpRate
+	}
+	if opt.PoolScaleDownRate <= 0 {
+		opt.PoolScaleDownRate = DefaultScaleDownRate
+	}
+	if opt.RenewInterval < 0 {
+		opt.RenewInterval = 0
+	}
+	if opt.CodecMode == CodecModeJWT && opt.JwtAlg == "" {
+		opt.JwtAlg = JwtAlgHS256
+	}
+	if opt.CodecMode == CodecModeJWT && opt.JwtAlg == JwtAlgHS256 && len(opt.JwtSecret) == 0 {
+		opt.JwtSecret = opt.EncryptKey
+	}
+	if opt.CacheMode == CacheModeFile && opt.CacheFileDir == "" {
+		opt.CacheFileDir = gfile.Temp()
+	}
+	if opt.CacheMode == CacheModeFile && opt.CacheFileSync == 0 {
+		opt.CacheFileSync = FileSyncEverySecond
+	}
+	if opt.RefreshTimeout <= 0 {
+		opt.RefreshTimeout = DefaultRefreshTimeout
+	}
+	if opt.MaxSessions < 0 {
+		opt.MaxSessions = 0
+	}
+	if opt.SessionEvictPolicy == 0 {
+		opt.SessionEvictPolicy = SessionEvictOldest
+	}
+	if len(opt.TokenLookup) == 0 {
+		opt.TokenLookup = append(g.SliceStr{}, DefaultTokenLookup...)
+	}
+	if len(opt.Tenants) > 0 {
+		tenants := make(map[string]TenantOptions, len(opt.Tenants))
+		for tenantId, tenant := range opt.Tenants {
+			tenants[tenantId] = tenant
+		}
+		opt.Tenants = tenants
+	}
+	if opt.CookieName != "" {
+		if !gstr.InArray(opt.TokenLookup, LookupCookie+":"+opt.CookieName) {
+			opt.TokenLookup = append(append(g.SliceStr{}, opt.TokenLookup...), LookupCookie+":"+opt.CookieName)
+		}
+		if opt.CookiePath == "" {
+			opt.CookiePath = DefaultCookiePath
+		}
+		if opt.CookieSameSite == "" {
+			opt.CookieSameSite = CookieSameSiteLax
+		}
+		if opt.CsrfCookieName == "" {
+			opt.CsrfCookieName = DefaultCsrfCookieName
+		}
+		if opt.CsrfHeaderName == "" {
+			opt.CsrfHeaderName = DefaultCsrfHeaderName
+		}
+	}
+}
+
+// normalize applies defaults and validates options | 应用默认配置并校验配置合法性
+// Correctable settings are fixed with a warning to logger unless strict, all other problems are returned together
+// 可修正的配置在非严格模式下自动修正并向 logger 告警，其余问题汇总返回
+func (opt *Options) normalize(strict bool, logger *glog.Logger) error {
+	opt.setDefaults()
+
+	var problems []string
+	correct := func(problem, fix string, apply func()) {
+		if strict {
+			problems = append(problems, problem)
+			return
+		}
+		logger.Warning(gctx.New(), "invalid config: "+problem+", "+fix)
+		apply()
+	}
+
+	// 1. MaxRefresh should be less than Timeout
+	if opt.MaxRefresh >= opt.Timeout {
+		correct("MaxRefresh >= Timeout", "reset to Timeout/2 | 已自动修正为 Timeout 的一半", func() {
+			opt.MaxRefresh = opt.Timeout / 2
+		})
+	}
+
+	// 2. RenewInterval should be less than Timeout
+	if opt.RenewInterval >= opt.Timeout {
+		correct("RenewInterval >= Timeout", "reset to 0 | 已自动修正为 0 (无间隔限制)", func() {
+			opt.RenewInterval = 0
+		})
+	}
+
+	// 3. RefreshTimeout should be greater than Timeout
+	if opt.RefreshTimeout <= opt.Timeout {
+		correct("RefreshTimeout <= Timeout", "reset to Timeout*2 | 已自动修正为 Timeout 的两倍", func() {
+			opt.RefreshTimeout = opt.Timeout * 2
+		})
+	}
+
+	// 4. PoolMaxSize must not be smaller than PoolMinSize
+	if opt.PoolMaxSize < opt.PoolMinSize {
+		correct("PoolMaxSize < PoolMinSize", fmt.Sprintf("reset PoolMaxSize=%d | 已自动修正 PoolMaxSize 为 %d", opt.PoolMinSize, opt.PoolMinSize), func() {
+			opt.PoolMaxSize = opt.PoolMinSize
+		})
+	}
+
+	// 5. ScaleDownRate must be smaller than ScaleUpRate
+	if opt.PoolScaleDownRate >= opt.PoolScaleUpRate {
+		correct("PoolScaleDownRate >= PoolScaleUpRate", "reset to default values | 已自动修正为默认值", func() {
+			opt.PoolScaleUpRate = DefaultScaleUpRate
+			opt.PoolScaleDownRate = DefaultScaleDownRate
+		})
+	}
+
+	// 6. SessionEvictPolicy must be a known policy
+	if opt.SessionEvictPolicy != SessionEvictOldest && opt.SessionEvictPolicy != SessionEvictReject {
+		correct("SessionEvictPolicy must be 1 or 2", "reset to 1 | 已自动修正为 1 (踢出最早的会话)", func() {
+			opt.SessionEvictPolicy = SessionEvictOldest
+		})
+	}
+
+	// 7. CookieSameSite must be a known policy, None requires Secure
+	if opt.CookieName != "" {
+		opt.CookieSameSite = gstr.ToLower(opt.CookieSameSite)
+		if opt.CookieSameSite != CookieSameSiteLax && opt.CookieSameSite != CookieSameSiteStrict && opt.CookieSameSite != CookieSameSiteNone {
+			correct("CookieSameSite must be lax, strict or none", "reset to lax | 已自动修正为 lax", func() {
+				opt.CookieSameSite = CookieSameSiteLax
+			})
+		}
+		if opt.CookieSameSite == CookieSameSiteNone && !opt.CookieSecure {
+			correct("CookieSameSite=none requires CookieSecure", "reset CookieSecure to true | 已自动开启 CookieSecure", func() {
+				opt.CookieSecure = true
+			})
+		}
+	}
+
+	// 8. EncryptKey length check
+	if len(opt.EncryptKey) != 16 && len(opt.EncryptKey) != 24 && len(opt.EncryptKey) != 32 {
+		problems = append(problems, "EncryptKey length must be 16, 24, or 32 bytes (AES key size) | EncryptKey 长度必须为 16、24 或 32 字节")
+	}
+
+	// 9. CacheMode check
+	if opt.CacheMode != CacheModeCache && opt.CacheMode != CacheModeRedis && opt.CacheMode != CacheModeFile && opt.CacheMode != CacheModeRedisHash {
+		problems = append(problems, "CacheMode must be 1 (gcache), 2 (gredis), 3 (gfile) or 4 (redis hash) | CacheMode 必须为 1(gcache)、2(gredis)、3(gfile) 或 4(redis hash)")
+	}
+
+	// 10. CodecMode check, HS256 must not sign with the public default key
+	if _, _, err := newCodecByOptions(*opt); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if opt.CodecMode == CodecModeJWT && opt.JwtAlg == JwtAlgHS256 && string(opt.JwtSecret) == DefaultEncryptKey {
+		problems = append(problems, "JwtSecret or EncryptKey must be set for HS256, the default key is public | HS256 必须配置 JwtSecret 或 EncryptKey，默认密钥是公开的")
+	}
+
+	// 11. TokenLookup check
+	if _, err := NewExtractorByOptions(*opt); err != nil {
+		problems = append(problems, err.Error()+" | TokenLookup 配置错误")
+	}
+
+	// 12. Path rules check
+	if _, err := NewPathRulesByOptions(*opt); err != nil {
+		problems = append(problems, err.Error()+" | AuthExcludePaths 或 AuthIncludePaths 配置错误")
+	}
+	if _, err := NewPathRules(opt.AuthExcludeRpcs, nil); err != nil {
+		problems = append(problems, err.Error()+" | AuthExcludeRpcs 配置错误")
+	}
+	for _, rule := range opt.AuthRules {
+		if _, err := newPathRuleSet([]string{rule.Path}); err != nil {
+			problems = append(problems, err.Error()+" | AuthRules 配置错误")
+		}
+	}
+
+	// 13. Tenants check
+	tenantIds := make([]string, 0, len(opt.Tenants))
+	for tenantId := range opt.Tenants {
+		tenantIds = append(tenantIds, tenantId)
+	}
+	sort.Strings(tenantIds)
+	for _, tenantId := range tenantIds {
+		tenant := opt.Tenants[tenantId]
+		if err := checkTenantId(tenantId); err != nil {
+			problems = append(problems, err.Error()+" | Tenants 配置错误")
+			continue
+		}
+		if n := len(tenant.EncryptKey); n > 0 && n != 16 && n != 24 && n != 32 {
+			problems = append(problems, fmt.Sprintf("Tenants[%s].EncryptKey length must be 16, 24, or 32 bytes | 租户 %s 的 EncryptKey 长度必须为 16、24 或 32 字节", tenantId, tenantId))
+		}
+		if options := opt.tenantOptions(tenantId); options.MaxRefresh >= options.Timeout {
+			correct(fmt.Sprintf("Tenants[%s].MaxRefresh >= Timeout", tenantId), "reset to Timeout/2 | 已自动修正为 Timeout 的一半", func() {
+				tenant.MaxRefresh = options.Timeout / 2
+				opt.Tenants[tenantId] = tenant
+			})
+		}
+	}
+	if _, err := NewTenantResolverByOptions(*opt); err != nil {
+		problems = append(problems, err.Error()+" | TenantLookup 配置错误")
+	}
+
+	if len(problems) > 0 {
+		return &OptionsError{Problems: problems}
+	}
+	return nil
+}
+
+// jwtExpire returns JwtExpire, by default Timeout or the bounded sliding renewal of a session | 返回 JwtExpire，默认为 Timeout 或有限次滑动续期可维持会话的时间
+// Unlimited renewal keeps Timeout so externally verified JWTs expire soon after logout | 不限续期次数时保持 Timeout，使外部校验的 JWT 在登出后尽快失效
+func (opt Options) jwtExpire() int64 {
+	switch {
+	case opt.JwtExpire > 0:
+		return opt.JwtExpire
+	case opt.MaxRefreshTimes > 0:
+		return opt.Timeout * int64(opt.MaxRefreshTimes+1)
+	default:
+		return opt.Timeout
+	}
+}
+
+// newCodecByOptions creates the codec selected by CodecMode | 创建 CodecMode 指定的编解码器
+func newCodecByOptions(options Options) (codec Codec, keyRing *KeyRing, err error) {
+	switch options.CodecMode {
+	case CodecModeDefault:
+		return NewDefaultCodec(options.TokenDelimiter, options.EncryptKey), nil, nil
+	case CodecModeJWT:
+		jwtCodec, err := NewJWTCodecByOptions(options)
+		if err != nil {
+			return nil, nil, gerror.New("JWT codec init failed: " + err.Error() + " | JWT 编解码器初始化失败")
+		}
+		return jwtCodec, nil, nil
+	case CodecModeAEAD:
+		aeadCodec := NewAEADCodec(options.EncryptKey)
+		if len(options.EncryptKeys) > 0 {
+			if keyRing, err = NewKeyRing(options.EncryptKeys...); err != nil {
+				return nil, nil, gerror.New("EncryptKeys " + err.Error() + " | EncryptKeys 配置错误")
+			}
+			aeadCodec.KeyRing = keyRing
+		}
+		if options.AcceptLegacy {
+			aeadCodec.Legacy = NewDefaultCodec(options.TokenDelimiter, options.EncryptKey)
+		}
+		return aeadCodec, keyRing, nil
+	default:
+		return nil, nil, gerror.New("CodecMode must be 1 (default), 2 (jwt) or 3 (aead) | CodecMode 必须为 1(default)、2(jwt) 或 3(aead)")
+	}
 }
 
 // PrintBanner prints startup banner only | 打印启动横幅
@@ -67,18 +405,52 @@ func PrintWithOptions(opt *Options) {
 	fmt.Println("├──────────────────────────────────────────────────────────────┤")
 
 	// Cache and storage | 缓存与存储配置
-	fmt.Print(formatLine("Cache Mode", fmt.Sprintf("%d (1-gcache 2-gredis 3-gfile)", opt.CacheMode)))
+	fmt.Print(formatLine("Cache Mode", fmt.Sprintf("%d (1-gcache 2-gredis 3-gfile 4-hash)", opt.CacheMode)))
+	if opt.RedisGroup != "" {
+		fmt.Print(formatLine("Redis Group", opt.RedisGroup))
+	}
+	if opt.CacheMode == CacheModeFile {
+		fmt.Print(formatLine("Cache File Dir", opt.CacheFileDir))
+		fmt.Print(formatLine("Cache File Sync", fmt.Sprintf("%d (1-always 2-second 3-none)", opt.CacheFileSync)))
+	}
 	fmt.Print(formatLine("Cache PreKey", opt.CachePreKey))
 	fmt.Print(formatLine("Timeout", fmt.Sprintf("%d ms", opt.Timeout)))
 	fmt.Print(formatLine("Max Refresh", fmt.Sprintf("%d ms", opt.MaxRefresh)))
 	fmt.Print(formatLine("Max Refresh Times", fmt.Sprintf("%d", opt.MaxRefreshTimes)))
 	fmt.Print(formatLine("Renew Interval", fmt.Sprintf("%d ms", opt.RenewInterval)))
+	fmt.Print(formatLine("Refresh Timeout", fmt.Sprintf("%d ms", opt.RefreshTimeout)))
 
 	// Token settings | Token 配置
 	fmt.Println("├──────────────────────────────────────────────────────────────┤")
 	fmt.Print(formatLine("Token Delimiter", opt.TokenDelimiter))
 	fmt.Print(formatLine("Multi Login", fmt.Sprintf("%t", opt.MultiLogin)))
+	fmt.Print(formatLine("Token Lookup", strings.Join(opt.TokenLookup, ",")))
+	if opt.CookieName != "" {
+		fmt.Print(formatLine("Cookie Name", opt.CookieName))
+		fmt.Print(formatLine("Cookie Domain", opt.CookieDomain))
+		fmt.Print(formatLine("Cookie Path", opt.CookiePath))
+		fmt.Print(formatLine("Cookie Secure", fmt.Sprintf("%t", opt.CookieSecure)))
+		fmt.Print(formatLine("Cookie SameSite", opt.CookieSameSite))
+		fmt.Print(formatLine("Csrf Cookie Name", opt.CsrfCookieName))
+		fmt.Print(formatLine("Csrf Header Name", opt.CsrfHeaderName))
+	}
 	fmt.Print(formatLine("Encrypt Key", maskKey(string(opt.EncryptKey))))
+	fmt.Print(formatLine("Codec Mode", fmt.Sprintf("%d (1-default 2-jwt 3-aead)", opt.CodecMode)))
+	if opt.CodecMode == CodecModeAEAD {
+		fmt.Print(formatLine("Accept Legacy", fmt.Sprintf("%t", opt.AcceptLegacy)))
+		for _, key := range opt.EncryptKeys {
+			fmt.Print(formatLine("Encrypt Key "+key.Id, fmt.Sprintf("%s (status %d)", maskKey(string(key.Key)), key.Status)))
+		}
+	}
+	if opt.CodecMode == CodecModeJWT {
+		fmt.Print(formatLine("JWT Alg", opt.JwtAlg))
+		fmt.Print(formatLine("JWT Expire", fmt.Sprintf("%d ms", opt.jwtExpire())))
+	}
+	fmt.Print(formatLine("Max Sessions", fmt.Sprintf("%d", opt.MaxSessions)))
+	fmt.Print(formatLine("Session Evict Policy", fmt.Sprintf("%d (1-oldest 2-reject)", opt.SessionEvictPolicy)))
+	if len(opt.TenantLookup) > 0 {
+		fmt.Print(formatLine("Tenant Lookup", strings.Join(opt.TenantLookup, ",")))
+	}
 
 	// Pool settings | 协程池配置
 	fmt.Println("├──────────────────────────────────────────────────────────────┤")
@@ -86,6 +458,9 @@ func PrintWithOptions(opt *Options) {
 	fmt.Print(formatLine("Pool Max Size", opt.PoolMaxSize))
 	fmt.Print(formatLine("Scale Up Rate", fmt.Sprintf("%.2f", opt.PoolScaleUpRate)))
 	fmt.Print(formatLine("Scale Down Rate", fmt.Sprintf("%.2f", opt.PoolScaleDownRate)))
+	fmt.Print(formatLine("Telemetry", fmt.Sprintf("%t", opt.Telemetry)))
+	fmt.Print(formatLine("Strict", fmt.Sprintf("%t", opt.Strict)))
+	fmt.Print(formatLine("Hot Reload", fmt.Sprintf("%t", opt.HotReload)))
 
 	// Auth excluded paths | 免认证路径
 	if len(opt.AuthExcludePaths) > 0 {
@@ -94,6 +469,40 @@ func PrintWithOptions(opt *Options) {
 			fmt.Print(formatLine("Auth Exclude Path", path))
 		}
 	}
+	if len(opt.AuthIncludePaths) > 0 {
+		fmt.Println("├──────────────────────────────────────────────────────────────┤")
+		for _, path := range opt.AuthIncludePaths {
+			fmt.Print(formatLine("Auth Include Path", path))
+		}
+	}
+	if len(opt.AuthExcludeRpcs) > 0 {
+		fmt.Println("├──────────────────────────────────────────────────────────────┤")
+		for _, method := range opt.AuthExcludeRpcs {
+			fmt.Print(formatLine("Auth Exclude Rpc", method))
+		}
+	}
+
+	// Tenant overrides | 租户覆盖配置
+	if len(opt.Tenants) > 0 {
+		fmt.Println("├──────────────────────────────────────────────────────────────┤")
+		tenantIds := make([]string, 0, len(opt.Tenants))
+		for tenantId := range opt.Tenants {
+			tenantIds = append(tenantIds, tenantId)
+		}
+		sort.Strings(tenantIds)
+		for _, tenantId := range tenantIds {
+			tenant := opt.tenantOptions(tenantId)
+			fmt.Print(formatLine("Tenant "+tenantId, fmt.Sprintf("%d/%d ms, multi %t", tenant.Timeout, tenant.MaxRefresh, tenant.MultiLogin)))
+		}
+	}
+
+	// Authorization rules | 授权规则
+	if len(opt.AuthRules) > 0 {
+		fmt.Println("├──────────────────────────────────────────────────────────────┤")
+		for _, rule := range opt.AuthRules {
+			fmt.Print(formatLine("Auth Rule", strings.TrimSpace(rule.Method+" "+rule.Path)))
+		}
+	}
 
 	fmt.Println("└──────────────────────────────────────────────────────────────┘")
 	fmt.Println()
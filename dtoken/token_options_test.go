@@ -0,0 +1,87 @@
+package dtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"testing"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	if err := (Options{}).Validate(); err != nil {
+		t.Fatalf("expected defaults to be valid, got %v", err)
+	}
+
+	options := Options{
+		Timeout:     1000,
+		MaxRefresh:  2000,
+		EncryptKey:  []byte("short"),
+		CacheMode:   9,
+		TokenLookup: g.SliceStr{"query:token"},
+	}
+	err := options.Validate()
+	if !gerror.HasCode(err, gcode.CodeInvalidConfiguration) {
+		t.Fatalf("expected invalid configuration, got %v", err)
+	}
+	optionsErr, ok := err.(*OptionsError)
+	if !ok {
+		t.Fatalf("expected *OptionsError, got %T", err)
+	}
+	if len(optionsErr.Problems) != 4 {
+		t.Fatalf("expected 4 problems, got %d: %v", len(optionsErr.Problems), optionsErr.Problems)
+	}
+	if options.MaxRefresh != 2000 || len(options.TokenLookup) != 1 {
+		t.Fatal("expected Validate to leave options unchanged")
+	}
+}
+
+func TestNewTokenByOptions(t *testing.T) {
+	// Correctable settings are fixed unless strict | 可修正的配置在非严格模式下自动修正
+	token, err := NewTokenByOptions(Options{Timeout: 10000, MaxRefresh: 20000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.Shutdown(context.Background())
+	if got := token.GetOptions().MaxRefresh; got != 5000 {
+		t.Fatalf("expected MaxRefresh corrected to 5000, got %d", got)
+	}
+
+	if _, err = NewTokenByOptions(Options{Timeout: 10000, MaxRefresh: 20000, Strict: true}); err == nil {
+		t.Fatal("expected strict mode to reject MaxRefresh >= Timeout")
+	}
+	if _, err = NewTokenByOptions(Options{EncryptKey: []byte("short")}); err == nil {
+		t.Fatal("expected error for invalid EncryptKey")
+	}
+}
+
+func TestOptions_JwtDefaultSecret(t *testing.T) {
+	if err := (Options{CodecMode: CodecModeJWT}).Validate(); !gerror.HasCode(err, gcode.CodeInvalidConfiguration) {
+		t.Fatalf("expected HS256 with the default key to be rejected, got %v", err)
+	}
+	if err := (Options{CodecMode: CodecModeJWT, JwtSecret: []byte(DefaultEncryptKey)}).Validate(); err == nil {
+		t.Fatal("expected HS256 with the default key as JwtSecret to be rejected")
+	}
+	if err := (Options{CodecMode: CodecModeJWT, JwtSecret: []byte("jwt-secret")}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Options{CodecMode: CodecModeJWT, EncryptKey: []byte("0123456789abcdef")}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOptions_JwtExpire(t *testing.T) {
+	cases := []struct {
+		options Options
+		want    int64
+	}{
+		{Options{Timeout: 60 * 1000, RefreshTimeout: 3600 * 1000}, 60 * 1000},
+		{Options{Timeout: 60 * 1000, RefreshTimeout: 3600 * 1000, MaxRefreshTimes: 2}, 180 * 1000},
+		{Options{Timeout: 60 * 1000, MaxRefreshTimes: 2, JwtExpire: 30 * 1000}, 30 * 1000},
+	}
+	for _, c := range cases {
+		if got := c.options.jwtExpire(); got != c.want {
+			t.Fatalf("jwtExpire of %+v = %d, want %d", c.options, got, c.want)
+		}
+	}
+}